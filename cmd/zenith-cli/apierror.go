@@ -0,0 +1,26 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ErrorResponse is the JSON body the server sends with every non-2xx
+// status.
+type ErrorResponse struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// serverError describes a non-2xx response, using the message from the
+// server's error envelope when the body is one and the raw body otherwise.
+func serverError(status int, body []byte) string {
+	var e ErrorResponse
+	if json.Unmarshal(body, &e) == nil && e.Error.Message != "" {
+		return fmt.Sprintf("Server returned error (Status %d, %s): %s", status, e.Error.Code, e.Error.Message)
+	}
+	return fmt.Sprintf("Server returned error (Status %d): %s", status, strings.TrimSpace(string(body)))
+}
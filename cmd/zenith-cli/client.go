@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// defaultTimeout matches the server's Ollama backend, which can legitimately
+// take minutes to answer.
+const defaultTimeout = 5 * time.Minute
+
+// httpClient makes every request to the server; --timeout sets its
+// Timeout, which covers connecting and reading the whole response.
+var httpClient = &http.Client{Timeout: defaultTimeout}
+
+// isTimeout reports whether err is httpClient giving up on a slow server,
+// as opposed to failing to reach it.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// contactError describes a request to serverAddr that failed, telling a
+// timeout apart from a server that couldn't be reached.
+func contactError(serverAddr string, err error) string {
+	if isTimeout(err) {
+		return timeoutError(serverAddr)
+	}
+	return fmt.Sprintf("Error contacting server at %s: %v\nIs the zenith-server running?", serverAddr, err)
+}
+
+// readError describes a failure reading a response from serverAddr.
+func readError(serverAddr string, err error) string {
+	if isTimeout(err) {
+		return timeoutError(serverAddr)
+	}
+	return fmt.Sprintf("Error reading response: %v", err)
+}
+
+func timeoutError(serverAddr string) string {
+	return fmt.Sprintf("Timed out after %s waiting for the server at %s to answer. A slow model can take minutes; retry with a longer --timeout.", httpClient.Timeout, serverAddr)
+}
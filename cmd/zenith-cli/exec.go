@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+type RawQueryRequest struct {
+	Type  string `json:"type"`
+	Query string `json:"query"`
+}
+
+type RawQueryResponse struct {
+	Results *RawQueryResults `json:"results,omitempty"`
+}
+
+type RawQueryResults struct {
+	Type    string         `json:"type"`
+	Query   string         `json:"query"`
+	Metrics []MetricResult `json:"metrics,omitempty"`
+	Logs    []LogResult    `json:"logs,omitempty"`
+}
+
+type MetricResult struct {
+	Name      string            `json:"name"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+	Value     *float64          `json:"value"`
+}
+
+type LogResult struct {
+	Time   string            `json:"time"`
+	Fields map[string]string `json:"fields"`
+}
+
+// execQuery runs a MetricsQL or LogsQL query through /query/raw, bypassing
+// the LLM, and prints the rows as a table.
+func execQuery(serverAddr, queryType, query string, asJSON bool) {
+	reqBody, err := json.Marshal(RawQueryRequest{Type: queryType, Query: query})
+	if err != nil {
+		fmt.Printf("Error creating request: %v\n", err)
+		os.Exit(1)
+	}
+
+	resp, err := httpClient.Post(fmt.Sprintf("%s/query/raw", serverAddr), "application/json", bytes.NewBuffer(reqBody))
+	if err != nil {
+		fmt.Println(contactError(serverAddr, err))
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		fmt.Println(readError(serverAddr, err))
+		os.Exit(1)
+	}
+	if resp.StatusCode != http.StatusOK {
+		fmt.Println(serverError(resp.StatusCode, body))
+		os.Exit(1)
+	}
+
+	var rResp RawQueryResponse
+	if err := json.Unmarshal(body, &rResp); err != nil {
+		fmt.Printf("Error parsing response: %v\n", err)
+		os.Exit(1)
+	}
+
+	if asJSON {
+		printJSON(rResp.Results)
+		return
+	}
+	if rResp.Results == nil || (len(rResp.Results.Metrics) == 0 && len(rResp.Results.Logs) == 0) {
+		fmt.Println("No data found.")
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if rResp.Results.Type == "log" {
+		printLogTable(tw, rResp.Results.Logs)
+	} else {
+		printMetricTable(tw, rResp.Results.Metrics)
+	}
+	tw.Flush()
+}
+
+func printMetricTable(w io.Writer, metrics []MetricResult) {
+	fmt.Fprintln(w, "NAME\tLABELS\tVALUE\tTIMESTAMP")
+	for _, m := range metrics {
+		name := m.Name
+		if name == "" {
+			name = "result"
+		}
+		value := "NaN"
+		if m.Value != nil {
+			value = fmt.Sprintf("%g", *m.Value)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, formatLabels(m.Labels), value, m.Timestamp.Local().Format("2006-01-02 15:04:05"))
+	}
+}
+
+func printLogTable(w io.Writer, logs []LogResult) {
+	fmt.Fprintln(w, "TIME\tMESSAGE\tFIELDS")
+	for _, l := range logs {
+		fields := make(map[string]string, len(l.Fields))
+		for k, v := range l.Fields {
+			// _time and _msg have their own columns; the stream fields are noise.
+			if k != "_time" && k != "_msg" && k != "_stream" && k != "_stream_id" {
+				fields[k] = v
+			}
+		}
+		msg := strings.ReplaceAll(l.Fields["_msg"], "\n", " ")
+		fmt.Fprintf(w, "%s\t%s\t%s\n", l.Time, msg, formatLabels(fields))
+	}
+}
+
+// formatLabels renders labels as sorted key=value pairs.
+func formatLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%q", k, labels[k]))
+	}
+	return strings.Join(parts, " ")
+}
@@ -1,38 +1,94 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"time"
 	"zenith/pkg/config"
 )
 
 type QueryRequest struct {
-	Query string `json:"query"`
+	Query string     `json:"query"`
+	SQL   string     `json:"sql,omitempty"`
+	Hint  *QueryHint `json:"hint,omitempty"`
+}
+
+type QueryHint struct {
+	Metric string `json:"metric,omitempty"`
+	Label  string `json:"label,omitempty"`
 }
 
 type QueryResponse struct {
 	InteractionID int64  `json:"interaction_id,omitempty"`
 	Answer        string `json:"answer"`
 	Error         string `json:"error,omitempty"`
+
+	RequiresConfirmation bool   `json:"requires_confirmation,omitempty"`
+	Query                string `json:"query,omitempty"`
+
+	// FeedbackCommand is filled in by the CLI for --json output, in place of
+	// the feedback instructions printed after a text answer.
+	FeedbackCommand string `json:"feedback_command,omitempty"`
+
+	Trace *QueryTrace `json:"trace,omitempty"`
+}
+
+// QueryTrace is how the server derived an answer, returned for --trace.
+type QueryTrace struct {
+	Question string `json:"question"`
+	Attempts []struct {
+		Source string `json:"source"`
+		Query  string `json:"query,omitempty"`
+		Error  string `json:"error,omitempty"`
+	} `json:"attempts"`
+	Query            string `json:"query"`
+	QueryType        string `json:"query_type"`
+	Results          string `json:"results"`
+	ResultsTruncated bool   `json:"results_truncated,omitempty"`
+	Explanation      string `json:"explanation"`
 }
 
 func main() {
-	cfg, err := config.LoadConfig("config.json")
+	cfg, err := config.Load()
 	if err != nil {
-		fmt.Printf("Warning: Failed to load config.json, using defaults: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Warning: Failed to load config, using defaults: %v\n", err)
 		cfg = &config.Config{ServerHost: "localhost", ServerPort: 8080}
 	}
 
 	serverAddr := flag.String("server", fmt.Sprintf("http://%s:%d", cfg.ServerHost, cfg.ServerPort), "Zenith server address")
 	feedbackPtr := flag.String("feedback", "", "Provide feedback on a previous interaction ('good' or 'bad')")
 	idPtr := flag.Int64("id", 0, "The Interaction ID to provide feedback for")
+	outputPtr := flag.String("output", "text", "Output format: 'text' or 'json' (the full server response, for scripting)")
+	jsonPtr := flag.Bool("json", false, "Shorthand for --output json: print the server response as JSON, exiting non-zero on errors")
+	langPtr := flag.String("lang", "", "Language for the answer (e.g. 'German'); defaults to the server's response_language")
+	metricPtr := flag.String("metric", "", "Metric the generated query must use (e.g. 'process_memory_mb')")
+	labelPtr := flag.String("label", "", "Label the generated query should filter or group by (e.g. 'process_name')")
+	outPtr := flag.String("out", "zenith_rl.tar.gz", "File to write for export-db")
+	tracePtr := flag.Bool("trace", false, "Show how the answer was derived: each query tried, the results the LLM saw, and its explanation")
+	timeoutPtr := flag.Duration("timeout", defaultTimeout, "How long to wait for the server to answer (e.g. '10m'); 0 waits forever")
 	flag.Parse()
+	httpClient.Timeout = *timeoutPtr
+
+	if *jsonPtr {
+		*outputPtr = "json"
+	}
+	if *outputPtr != "text" && *outputPtr != "json" {
+		fmt.Println("Error: --output must be 'text' or 'json'")
+		os.Exit(1)
+	}
+	jsonOutput = *outputPtr == "json"
 
 	args := flag.Args()
 
@@ -42,7 +98,7 @@ func main() {
 	if len(args) > 0 {
 		firstArg := args[0]
 		isServerAddr := strings.HasPrefix(firstArg, "http://") || strings.HasPrefix(firstArg, "https://") ||
-			(strings.Contains(firstArg, ":") && firstArg != "recommend")
+			(strings.Contains(firstArg, ":") && firstArg != "recommend" && firstArg != "history" && firstArg != "export-db" && firstArg != "exec")
 
 		if isServerAddr {
 			// Ensure it has a scheme for the http client
@@ -75,34 +131,74 @@ func main() {
 	}
 
 	if len(args) == 0 {
-		fmt.Println("Please provide a query (e.g., 'How many errors in the last hour?'), 'recommend', or use --feedback")
+		fmt.Println("Please provide a query (e.g., 'How many errors in the last hour?'), 'recommend', 'history [n]', 'export-db', 'exec --type metric|log <query>', or use --feedback")
 		os.Exit(1)
 	}
 
+	if args[0] == "exec" {
+		execFlags := flag.NewFlagSet("exec", flag.ExitOnError)
+		typePtr := execFlags.String("type", "metric", "Query language: 'metric' (MetricsQL) or 'log' (LogsQL)")
+		execOutput := execFlags.String("output", *outputPtr, "Output format: 'text' or 'json'")
+		execFlags.Parse(args[1:])
+		if *typePtr != "metric" && *typePtr != "log" {
+			fmt.Println("Error: --type must be 'metric' or 'log'")
+			os.Exit(1)
+		}
+		if *execOutput != "text" && *execOutput != "json" {
+			fmt.Println("Error: --output must be 'text' or 'json'")
+			os.Exit(1)
+		}
+		if execFlags.NArg() == 0 {
+			fmt.Println("Error: exec needs a query, e.g. zenith-cli exec --type metric \"avg(cpu_usage_pct)\"")
+			os.Exit(1)
+		}
+		execQuery(*serverAddr, *typePtr, strings.Join(execFlags.Args(), " "), *execOutput == "json")
+		return
+	}
+
+	if args[0] == "export-db" {
+		exportDB(*serverAddr, *outPtr, cfg.AdminToken)
+		return
+	}
+
+	if args[0] == "history" {
+		n := 10
+		if len(args) > 1 {
+			v, err := strconv.Atoi(args[1])
+			if err != nil || v < 1 {
+				fmt.Println("Error: history takes a positive number of answers to show")
+				os.Exit(1)
+			}
+			n = v
+		}
+		showHistory(*serverAddr, n, jsonOutput, cfg.AdminToken)
+		return
+	}
+
 	if args[0] == "recommend" {
-		resp, err := http.Get(fmt.Sprintf("%s/recommend", *serverAddr))
+		resp, err := httpClient.Get(withLang(fmt.Sprintf("%s/recommend", *serverAddr), *langPtr))
 		if err != nil {
-			fmt.Printf("Error contacting server at %s: %v\n", *serverAddr, err)
-			fmt.Println("Is the zenith-server running?")
-			os.Exit(1)
+			fail("%s", contactError(*serverAddr, err))
 		}
 		defer resp.Body.Close()
 
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
-			fmt.Printf("Error reading response: %v\n", err)
-			os.Exit(1)
+			fail("%s", readError(*serverAddr, err))
 		}
 
 		if resp.StatusCode != http.StatusOK {
-			fmt.Printf("Server returned error (Status %d): %s\n", resp.StatusCode, string(body))
-			os.Exit(1)
+			fail("%s", serverError(resp.StatusCode, body))
 		}
 
 		var qResp QueryResponse
 		if err := json.Unmarshal(body, &qResp); err != nil {
-			fmt.Printf("Error parsing response: %v\n", err)
-			os.Exit(1)
+			fail("Error parsing response: %v", err)
+		}
+
+		if jsonOutput {
+			printQueryJSON(qResp)
+			return
 		}
 
 		if qResp.Error != "" {
@@ -119,64 +215,395 @@ func main() {
 	}
 
 	query := strings.Join(args, " ")
-	reqBody, err := json.Marshal(QueryRequest{Query: query})
+	queryURL := withLang(fmt.Sprintf("%s/query", *serverAddr), *langPtr)
+	if *tracePtr {
+		queryURL = withParam(queryURL, "trace", "1")
+	}
+	var hint *QueryHint
+	if *metricPtr != "" || *labelPtr != "" {
+		hint = &QueryHint{Metric: *metricPtr, Label: *labelPtr}
+	}
+	// Print the analysis as it is generated, unless the output is JSON.
+	var onToken func(string)
+	streamed := false
+	if !jsonOutput {
+		onToken = func(token string) {
+			if !streamed {
+				fmt.Println("\n--- Zenith Analysis ---")
+				streamed = true
+			}
+			fmt.Print(token)
+		}
+	}
+	qResp := postQuery(*serverAddr, queryURL, QueryRequest{Query: query, Hint: hint}, onToken)
+
+	// JSON output never prompts: a query that needs confirmation comes back
+	// with requires_confirmation set and the query to review.
+	if jsonOutput {
+		printQueryJSON(qResp)
+		return
+	}
+	exitOnServerError(qResp, streamed)
+
+	if qResp.RequiresConfirmation {
+		fmt.Println(qResp.Answer)
+		fmt.Printf("Query: %s\n", qResp.Query)
+		fmt.Print("Run it anyway? [y/N]: ")
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			fmt.Println("Query cancelled.")
+			return
+		}
+		qResp = postQuery(*serverAddr, withParam(queryURL, "confirmed", "1"), QueryRequest{Query: query, SQL: qResp.Query}, onToken)
+		exitOnServerError(qResp, streamed)
+	}
+
+	if streamed {
+		fmt.Println()
+	} else {
+		fmt.Println("\n--- Zenith Analysis ---")
+		fmt.Println(qResp.Answer)
+	}
+	if qResp.Trace != nil {
+		printTrace(qResp.Trace)
+	}
+	if qResp.InteractionID != 0 {
+		fmt.Printf("\n[Interaction ID: %d] To provide feedback, use: zenith-cli --id %d --feedback good|bad\n", qResp.InteractionID, qResp.InteractionID)
+	}
+}
+
+// printTrace prints each stage of how an answer was derived. A trace from a
+// failed query stops at the stage that failed.
+func printTrace(t *QueryTrace) {
+	fmt.Println("\n--- Trace ---")
+	fmt.Printf("Question: %s\n", t.Question)
+	for i, a := range t.Attempts {
+		fmt.Printf("Attempt %d (%s): %s\n", i+1, a.Source, a.Query)
+		if a.Error != "" {
+			fmt.Printf("  failed: %s\n", a.Error)
+		}
+	}
+	if t.Query == "" {
+		return
+	}
+	fmt.Printf("Explained query (%s): %s\n", t.QueryType, t.Query)
+	truncated := ""
+	if t.ResultsTruncated {
+		truncated = " (truncated)"
+	}
+	fmt.Printf("Results%s:\n%s\n", truncated, strings.TrimSpace(t.Results))
+	if t.Explanation != "" {
+		fmt.Printf("Explanation:\n%s\n", strings.TrimSpace(t.Explanation))
+	}
+}
+
+// jsonOutput is set by --json (or --output json): responses and errors are
+// printed as JSON on stdout, without banners or instructions.
+var jsonOutput bool
+
+// fail reports an error and exits non-zero; with JSON output the error is
+// printed as {"error": ...}.
+func fail(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if jsonOutput {
+		printJSON(QueryResponse{Error: msg})
+	} else {
+		fmt.Println(msg)
+	}
+	os.Exit(1)
+}
+
+// printQueryJSON prints a /query or /recommend response for --json, with the
+// feedback command as a field, and exits non-zero if it carries an error.
+func printQueryJSON(qResp QueryResponse) {
+	if qResp.InteractionID != 0 {
+		qResp.FeedbackCommand = fmt.Sprintf("zenith-cli --id %d --feedback good|bad", qResp.InteractionID)
+	}
+	printJSON(qResp)
+	if qResp.Error != "" {
+		os.Exit(1)
+	}
+}
+
+// exitOnServerError prints the error a text-mode response carries, if any,
+// and exits. After a streamed answer it starts on a new line.
+func exitOnServerError(qResp QueryResponse, streamed bool) {
+	if qResp.Error == "" {
+		return
+	}
+	if streamed {
+		fmt.Println()
+	}
+	fmt.Printf("Server Error: %s\n", qResp.Error)
+	if qResp.Trace != nil {
+		printTrace(qResp.Trace)
+	}
+	os.Exit(1)
+}
+
+// printJSON writes v to stdout as indented JSON for piping into other tools.
+func printJSON(v interface{}) {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Printf("Error encoding response: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(out))
+}
+
+// withLang appends a ?lang= override to endpoint when one was requested.
+func withLang(endpoint, lang string) string {
+	if lang == "" {
+		return endpoint
+	}
+	return withParam(endpoint, "lang", lang)
+}
+
+// withParam appends a single query parameter to endpoint.
+func withParam(endpoint, key, value string) string {
+	sep := "?"
+	if strings.Contains(endpoint, "?") {
+		sep = "&"
+	}
+	return endpoint + sep + key + "=" + url.QueryEscape(value)
+}
+
+// postQuery sends req to the /query endpoint and returns the decoded
+// response, exiting on transport or HTTP errors. An error the response
+// carries is left to the caller. With onToken set, the
+// explanation is requested as a stream and passed to it as it arrives.
+func postQuery(serverAddr, endpoint string, req QueryRequest, onToken func(string)) QueryResponse {
+	reqBody, err := json.Marshal(req)
 	if err != nil {
 		fmt.Printf("Error creating request: %v\n", err)
 		os.Exit(1)
 	}
 
-	resp, err := http.Post(fmt.Sprintf("%s/query", *serverAddr), "application/json", bytes.NewBuffer(reqBody))
+	httpReq, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewBuffer(reqBody))
 	if err != nil {
-		fmt.Printf("Error contacting server at %s: %v\n", *serverAddr, err)
-		fmt.Println("Is the zenith-server running?")
+		fmt.Printf("Error creating request: %v\n", err)
 		os.Exit(1)
 	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	id := newQueryID()
+	httpReq.Header.Set("X-Query-ID", id)
+	if onToken != nil {
+		httpReq.Header.Set("Accept", "text/event-stream")
+	}
+
+	stop := cancelOnInterrupt(serverAddr, id)
+	// Giving up on a slow server must also stop the query there, or it keeps
+	// generating and querying for nobody.
+	cancelIfTimedOut := func(err error) {
+		stop()
+		if isTimeout(err) {
+			cancelQuery(serverAddr, id)
+		}
+	}
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		cancelIfTimedOut(err)
+		fail("%s", contactError(serverAddr, err))
+	}
 	defer resp.Body.Close()
 
+	if onToken != nil && strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		qResp, err := readEventStream(resp.Body, onToken)
+		cancelIfTimedOut(err)
+		if err != nil {
+			if isTimeout(err) {
+				fmt.Printf("\n%s\n", timeoutError(serverAddr))
+				os.Exit(1)
+			}
+			fmt.Printf("\nError reading response stream: %v\n", err)
+			os.Exit(1)
+		}
+		return qResp
+	}
+
 	body, err := io.ReadAll(resp.Body)
+	cancelIfTimedOut(err)
 	if err != nil {
-		fmt.Printf("Error reading response: %v\n", err)
-		os.Exit(1)
+		fail("%s", readError(serverAddr, err))
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("Server returned error (Status %d): %s\n", resp.StatusCode, string(body))
-		os.Exit(1)
+		fail("%s", serverError(resp.StatusCode, body))
 	}
 
 	var qResp QueryResponse
 	if err := json.Unmarshal(body, &qResp); err != nil {
-		fmt.Printf("Error parsing response: %v\n", err)
-		os.Exit(1)
+		fail("Error parsing response: %v", err)
 	}
+	return qResp
+}
 
-	if qResp.Error != "" {
-		fmt.Printf("Server Error: %s\n", qResp.Error)
-		os.Exit(1)
+func newQueryID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// cancelOnInterrupt cancels query id on the server if the user presses
+// Ctrl-C before it answers, so it stops generating and querying. The
+// returned function restores the default Ctrl-C behaviour.
+func cancelOnInterrupt(serverAddr, id string) func() {
+	sigs := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sigs, os.Interrupt)
+	go func() {
+		select {
+		case <-sigs:
+			fmt.Println("\nCancelling query...")
+			cancelQuery(serverAddr, id)
+			os.Exit(130)
+		case <-done:
+		}
+	}()
+	return func() {
+		signal.Stop(sigs)
+		close(done)
 	}
+}
 
-	fmt.Println("\n--- Zenith Analysis ---")
-	fmt.Println(qResp.Answer)
-	if qResp.InteractionID != 0 {
-		fmt.Printf("\n[Interaction ID: %d] To provide feedback, use: zenith-cli --id %d --feedback good|bad\n", qResp.InteractionID, qResp.InteractionID)
+// cancelQuery asks the server to stop working on query id, giving up
+// quickly if it doesn't answer.
+func cancelQuery(serverAddr, id string) {
+	req, err := http.NewRequest(http.MethodDelete, serverAddr+"/query/"+id, nil)
+	if err != nil {
+		return
+	}
+	client := &http.Client{Timeout: 5 * time.Second}
+	if resp, err := client.Do(req); err == nil {
+		resp.Body.Close()
 	}
 }
 
 func sendFeedback(serverAddr string, id int64, feedback int) {
 	reqBody := fmt.Sprintf(`{"interaction_id": %d, "feedback": %d}`, id, feedback)
 
-	resp, err := http.Post(fmt.Sprintf("%s/feedback", serverAddr), "application/json", bytes.NewBuffer([]byte(reqBody)))
+	resp, err := httpClient.Post(fmt.Sprintf("%s/feedback", serverAddr), "application/json", bytes.NewBuffer([]byte(reqBody)))
 	if err != nil {
-		fmt.Printf("Error sending feedback: %v\n", err)
+		fmt.Println(contactError(serverAddr, err))
 		os.Exit(1)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		fmt.Printf("Server returned error (Status %d): %s\n", resp.StatusCode, string(body))
+		fmt.Println(serverError(resp.StatusCode, body))
 		os.Exit(1)
 	}
 
 	fmt.Printf("Feedback recorded for Interaction ID: %d\n", id)
 }
+
+type HistoryEntry struct {
+	Time     time.Time     `json:"time"`
+	Kind     string        `json:"kind"`
+	Question string        `json:"question,omitempty"`
+	Response QueryResponse `json:"response"`
+}
+
+// showHistory prints the server's last n answers, oldest first,
+// authenticating with the admin token.
+func showHistory(serverAddr string, n int, asJSON bool, token string) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/history?n=%d", serverAddr, n), nil)
+	if err != nil {
+		fmt.Printf("Error creating request: %v\n", err)
+		os.Exit(1)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		fmt.Println(contactError(serverAddr, err))
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		fmt.Println(readError(serverAddr, err))
+		os.Exit(1)
+	}
+	if resp.StatusCode != http.StatusOK {
+		fmt.Println(serverError(resp.StatusCode, body))
+		os.Exit(1)
+	}
+
+	var entries []HistoryEntry
+	if err := json.Unmarshal(body, &entries); err != nil {
+		fmt.Printf("Error parsing response: %v\n", err)
+		os.Exit(1)
+	}
+
+	if asJSON {
+		printJSON(entries)
+		return
+	}
+	if len(entries) == 0 {
+		fmt.Println("No answers yet.")
+		return
+	}
+	for _, e := range entries {
+		question := e.Question
+		if e.Kind == "recommend" {
+			question = "(recommendations)"
+		}
+		fmt.Printf("\n--- %s  %s ---\n", e.Time.Local().Format("2006-01-02 15:04:05"), question)
+		fmt.Println(e.Response.Answer)
+		if e.Response.InteractionID != 0 {
+			fmt.Printf("[Interaction ID: %d]\n", e.Response.InteractionID)
+		}
+	}
+}
+
+// exportDB downloads a gzipped snapshot of the server's RL database to out,
+// authenticating with the admin token.
+func exportDB(serverAddr, out, token string) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/export/rl-db", serverAddr), nil)
+	if err != nil {
+		fmt.Printf("Error creating request: %v\n", err)
+		os.Exit(1)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		fmt.Println(contactError(serverAddr, err))
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		fmt.Println(serverError(resp.StatusCode, body))
+		os.Exit(1)
+	}
+
+	// Write to a temporary name so an interrupted download never leaves a
+	// truncated archive under the requested name.
+	tmp := out + ".partial"
+	f, err := os.Create(tmp)
+	if err != nil {
+		fmt.Printf("Error creating %s: %v\n", out, err)
+		os.Exit(1)
+	}
+	n, err := io.Copy(f, resp.Body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		fmt.Printf("Error downloading export: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.Rename(tmp, out); err != nil {
+		fmt.Printf("Error writing %s: %v\n", out, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Exported RL database to %s (%d bytes)\n", out, n)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// readEventStream reads the server-sent events of a streamed /query
+// response, passing each "token" event to onToken, and returns the response
+// carried by the final "done" or "error" event.
+func readEventStream(body io.Reader, onToken func(string)) (QueryResponse, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var event, data string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line != "" {
+			if v, ok := strings.CutPrefix(line, "event: "); ok {
+				event = v
+			} else if v, ok := strings.CutPrefix(line, "data: "); ok {
+				data = v
+			}
+			continue
+		}
+
+		switch event {
+		case "token":
+			var token string
+			if err := json.Unmarshal([]byte(data), &token); err != nil {
+				return QueryResponse{}, err
+			}
+			onToken(token)
+		case "done", "error":
+			var qResp QueryResponse
+			err := json.Unmarshal([]byte(data), &qResp)
+			return qResp, err
+		}
+		event, data = "", ""
+	}
+	if err := scanner.Err(); err != nil {
+		return QueryResponse{}, err
+	}
+	return QueryResponse{}, fmt.Errorf("stream ended without a result")
+}
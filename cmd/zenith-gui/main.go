@@ -21,9 +21,9 @@ func init() {
 }
 
 func main() {
-	cfg, err := config.LoadConfig("config.json")
+	cfg, err := config.Load()
 	if err != nil {
-		fmt.Printf("Warning: Failed to load config.json, using defaults: %v\n", err)
+		fmt.Printf("Warning: Failed to load config, using defaults: %v\n", err)
 		cfg = &config.Config{
 			ServerHost:  "localhost",
 			ServerPort:  8080,
@@ -185,15 +185,7 @@ func postJSON(url string, payload interface{}) map[string]interface{} {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		// Try to parse error from body if it's JSON
-		var errData map[string]interface{}
-		if json.Unmarshal(respBody, &errData) == nil {
-			if errMsg, ok := errData["error"].(string); ok {
-				result["error"] = errMsg
-				return result
-			}
-		}
-		result["error"] = fmt.Sprintf("Server returned status %d: %s", resp.StatusCode, string(respBody))
+		result["error"] = errorMessage(resp.StatusCode, respBody)
 		return result
 	}
 
@@ -219,14 +211,7 @@ func getJSON(url string) map[string]interface{} {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		var errData map[string]interface{}
-		if json.Unmarshal(body, &errData) == nil {
-			if errMsg, ok := errData["error"].(string); ok {
-				result["error"] = errMsg
-				return result
-			}
-		}
-		result["error"] = fmt.Sprintf("Server returned status %d: %s", resp.StatusCode, string(body))
+		result["error"] = errorMessage(resp.StatusCode, body)
 		return result
 	}
 
@@ -235,3 +220,17 @@ func getJSON(url string) map[string]interface{} {
 	}
 	return result
 }
+
+// errorMessage extracts the message from a non-2xx response: the server's
+// {"error":{"code":...,"message":...}} envelope, or the raw body.
+func errorMessage(status int, body []byte) string {
+	var errData struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &errData) == nil && errData.Error.Message != "" {
+		return errData.Error.Message
+	}
+	return fmt.Sprintf("Server returned status %d: %s", status, string(body))
+}
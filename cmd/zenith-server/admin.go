@@ -0,0 +1,122 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"zenith/pkg/db"
+)
+
+// AdminDeleteRequest selects collected data to delete. Match is a MetricsQL
+// series selector for "metric" or a LogsQL filter for "log". Start and End
+// are RFC3339 timestamps or Unix seconds and may be left empty.
+type AdminDeleteRequest struct {
+	Type  string `json:"type"`
+	Match string `json:"match"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+type AdminDeleteResponse struct {
+	Type    string `json:"type"`
+	Match   string `json:"match"`
+	Deleted bool   `json:"deleted"`
+	Note    string `json:"note,omitempty"`
+}
+
+// requireAdminToken only lets requests through that carry
+// "Authorization: Bearer <token>". With no token configured every request
+// is refused, so enabling the admin endpoints alone never opens them up.
+func requireAdminToken(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			writeError(w, http.StatusForbidden, "Admin endpoints require admin_token to be configured")
+			return
+		}
+		if !hasAdminToken(r, token) {
+			log.Printf("Rejected admin request to %s from %s", r.URL.Path, r.RemoteAddr)
+			writeError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// hasAdminToken reports whether r carries "Authorization: Bearer <token>"
+// for a configured, non-empty token.
+func hasAdminToken(r *http.Request, token string) bool {
+	got, bearer := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return token != "" && bearer && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// parseAdminTime parses an RFC3339 timestamp or Unix seconds. An empty
+// string yields the zero time.
+func parseAdminTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: expected RFC3339 or Unix seconds", s)
+	}
+	return t, nil
+}
+
+func handleAdminDelete(w http.ResponseWriter, r *http.Request, database *db.VictoriaDB) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req AdminDeleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	resp := AdminDeleteResponse{Type: req.Type, Match: req.Match}
+
+	// Bad input is the caller's to fix (400); anything after that is the
+	// backend failing (502).
+	start, err := parseAdminTime(req.Start)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	end, err := parseAdminTime(req.End)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Type != "metric" && req.Type != "log" {
+		writeError(w, http.StatusBadRequest, `type must be "metric" or "log"`)
+		return
+	}
+	if strings.TrimSpace(req.Match) == "" {
+		writeError(w, http.StatusBadRequest, "match is required")
+		return
+	}
+
+	log.Printf("Admin delete of %s data matching %s (start=%q end=%q)", req.Type, req.Match, req.Start, req.End)
+	if req.Type == "metric" {
+		err = database.DeleteMetricsContext(r.Context(), req.Match, start, end)
+		resp.Note = "VictoriaMetrics deletes entire matching series; start and end do not limit it."
+	} else {
+		err = database.DeleteLogsContext(r.Context(), req.Match, start, end)
+	}
+	if err != nil {
+		log.Printf("Admin delete failed: %v", err)
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	resp.Deleted = true
+	respondJSON(w, resp)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"zenith/pkg/db"
+)
+
+func TestRequireAdminToken(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+	tests := []struct {
+		token, header string
+		want          int
+	}{
+		{"", "", http.StatusForbidden},
+		{"", "Bearer ", http.StatusForbidden},
+		{"s3cret", "", http.StatusUnauthorized},
+		{"s3cret", "Bearer wrong", http.StatusUnauthorized},
+		{"s3cret", "s3cret", http.StatusUnauthorized},
+		{"s3cret", "Bearer s3cret", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/export/rl-db", nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		rec := httptest.NewRecorder()
+		requireAdminToken(tt.token, ok)(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("token %q, Authorization %q: got %d, want %d", tt.token, tt.header, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestHandleAdminDelete_Status(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "storage unavailable", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	working := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer working.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	tests := []struct {
+		body    string
+		backend string
+		want    int
+	}{
+		{`{"type":"metric","match":"{job=\"x\"}"}`, working.URL, http.StatusOK},
+		{`{"type":"log","match":"processName:x","start":"1700000000"}`, working.URL, http.StatusOK},
+		{`{"type":"metric","match":"{job=\"x\"}","start":"yesterday"}`, working.URL, http.StatusBadRequest},
+		{`{"type":"log","match":"processName:x","end":"soon"}`, working.URL, http.StatusBadRequest},
+		{`{"type":"trace","match":"x"}`, working.URL, http.StatusBadRequest},
+		{`{"type":"metric","match":"  "}`, working.URL, http.StatusBadRequest},
+		{`{"type":"metric","match":"{job=\"x\"}"}`, failing.URL, http.StatusBadGateway},
+		{`{"type":"log","match":"processName:x"}`, down.URL, http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/admin/delete", strings.NewReader(tt.body))
+		handleAdminDelete(rec, req, db.NewVictoriaDB(tt.backend, tt.backend))
+		if rec.Code != tt.want {
+			t.Errorf("%s against %s: got %d, want %d: %s", tt.body, tt.backend, rec.Code, tt.want, rec.Body.String())
+		}
+	}
+}
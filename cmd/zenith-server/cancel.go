@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"sync"
+)
+
+// queryIDHeader carries a /query request's ID. Clients may choose the ID
+// themselves so they know it before the answer arrives; otherwise the
+// server assigns one. Either way it is echoed in the response.
+const queryIDHeader = "X-Query-ID"
+
+// queryIDPattern is what a client-chosen ID must look like: at least 32
+// characters, as long as the random IDs newQueryID makes, so an ID can't be
+// guessed.
+var queryIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{32,64}$`)
+
+// queryRegistry tracks in-flight queries so DELETE /query/{id} can cancel
+// them.
+type queryRegistry struct {
+	mu      sync.Mutex
+	running map[string]runningQuery
+}
+
+// runningQuery is one in-flight query and the host that sent it, the only
+// one besides an admin allowed to cancel it.
+type runningQuery struct {
+	cancel context.CancelFunc
+	owner  string
+}
+
+var queries = &queryRegistry{running: make(map[string]runningQuery)}
+
+func newQueryID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// requestHost returns the host r came from, without the port, which
+// changes between a query and its cancellation.
+func requestHost(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// cancellable runs next with a request context that DELETE /query/{id}
+// can cancel.
+func (q *queryRegistry) cancellable(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		id := r.Header.Get(queryIDHeader)
+		q.mu.Lock()
+		if _, taken := q.running[id]; taken || !queryIDPattern.MatchString(id) {
+			id = newQueryID()
+		}
+		q.running[id] = runningQuery{cancel: cancel, owner: requestHost(r)}
+		q.mu.Unlock()
+		defer func() {
+			q.mu.Lock()
+			delete(q.running, id)
+			q.mu.Unlock()
+		}()
+
+		w.Header().Set(queryIDHeader, id)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// cancel cancels the query with the given ID on behalf of host, reporting
+// whether it was still running and host was allowed to cancel it: host
+// sent it, or admin is set.
+func (q *queryRegistry) cancel(id, host string, admin bool) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	query, ok := q.running[id]
+	if !ok || (query.owner != host && !admin) {
+		return false
+	}
+	query.cancel()
+	return true
+}
+
+// handleCancelQuery serves DELETE /query/{id}. The method is checked here
+// rather than in the route pattern, which would conflict with /query/raw.
+// Only the host that sent the query, or a request with the admin token,
+// may cancel it; anyone else is told there is no such query, so IDs can't
+// be probed.
+func handleCancelQuery(w http.ResponseWriter, r *http.Request, adminToken string) {
+	if r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	id := r.PathValue("id")
+	if !queries.cancel(id, requestHost(r), hasAdminToken(r, adminToken)) {
+		writeError(w, http.StatusNotFound, "No running query with that ID")
+		return
+	}
+	log.Printf("Cancelled query %s", id)
+	respondJSON(w, map[string]interface{}{"id": id, "cancelled": true})
+}
+
+// await runs fn in the background and returns its result, or ctx's error as
+// soon as ctx is done. The LLM providers don't take a context, so a
+// cancelled call still finishes on the backend, but the handler stops
+// waiting for it. A panic in fn is re-raised in the caller, where
+// recoverPanics turns it into a 500, instead of killing the server.
+func await(ctx context.Context, fn func() (string, error)) (string, error) {
+	type result struct {
+		s        string
+		err      error
+		panicked any
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				log.Printf("Panic in background LLM call: %v\n%s", p, debug.Stack())
+				done <- result{panicked: p}
+			}
+		}()
+		s, err := fn()
+		done <- result{s: s, err: err}
+	}()
+	select {
+	case res := <-done:
+		if res.panicked != nil {
+			panic(res.panicked)
+		}
+		return res.s, res.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// startQuery runs a /query from host through queries.cancellable that
+// blocks until cancelled, and returns its ID and a channel closed when it
+// ends.
+func startQuery(t *testing.T, host, id string) (string, chan struct{}) {
+	t.Helper()
+	started, done := make(chan string), make(chan struct{})
+	handler := queries.cancellable(func(w http.ResponseWriter, r *http.Request) {
+		started <- w.Header().Get(queryIDHeader)
+		<-r.Context().Done()
+	})
+	req := httptest.NewRequest(http.MethodPost, "/query", nil)
+	req.RemoteAddr = host + ":50000"
+	req.Header.Set(queryIDHeader, id)
+	go func() {
+		defer close(done)
+		handler(httptest.NewRecorder(), req)
+	}()
+	return <-started, done
+}
+
+func cancelFrom(host, id, auth string) int {
+	req := httptest.NewRequest(http.MethodDelete, "/query/"+id, nil)
+	req.SetPathValue("id", id)
+	req.RemoteAddr = host + ":50001"
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	rec := httptest.NewRecorder()
+	handleCancelQuery(rec, req, "s3cret")
+	return rec.Code
+}
+
+func TestHandleCancelQuery_OwnerOrAdmin(t *testing.T) {
+	id, done := startQuery(t, "192.0.2.1", "1")
+	if len(id) < 32 {
+		t.Errorf("Expected a guessable client ID to be replaced, got %q", id)
+	}
+	cancelFrom("192.0.2.1", id, "")
+	<-done
+
+	id, done = startQuery(t, "192.0.2.1", "")
+	if code := cancelFrom("192.0.2.99", id, ""); code != http.StatusNotFound {
+		t.Errorf("Expected another host's cancel to get 404, got %d", code)
+	}
+	if code := cancelFrom("192.0.2.1", id, ""); code != http.StatusOK {
+		t.Errorf("Expected the owner's cancel to succeed, got %d", code)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the query to stop after being cancelled")
+	}
+
+	id, done = startQuery(t, "192.0.2.1", "")
+	if code := cancelFrom("192.0.2.99", id, "Bearer s3cret"); code != http.StatusOK {
+		t.Errorf("Expected an admin to cancel any query, got %d", code)
+	}
+	<-done
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"io/fs"
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"zenith/pkg/db"
+	"zenith/pkg/telemetry"
+)
+
+// dataDiskInterval is how often the database data directories are walked.
+// A recursive walk of a large VictoriaMetrics store is not free, and disk
+// usage changes slowly, so this runs far less often than collection.
+const dataDiskInterval = 15 * time.Minute
+
+// DataDiskUsage holds the most recently measured size of the embedded
+// databases' data directories.
+type DataDiskUsage struct {
+	mu           sync.Mutex
+	MetricsBytes int64
+	LogsBytes    int64
+	UpdatedAt    time.Time
+}
+
+func (u *DataDiskUsage) snapshot() (metricsBytes, logsBytes int64) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.MetricsBytes, u.LogsBytes
+}
+
+// dirSize returns the total size in bytes of all regular files under path.
+func dirSize(path string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Files can vanish mid-walk while the database compacts parts.
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total, err
+}
+
+// watchDataDirs periodically measures the metrics and logs data directories,
+// records the sizes as metrics, and keeps usage current for /healthz.
+func watchDataDirs(database *db.VictoriaDB, metricsDir, logsDir string, usage *DataDiskUsage) {
+	vmGauge := telemetry.Default.Gauge("zenith_vm_data_bytes", "Size of the VictoriaMetrics data directory in bytes.")
+	vlGauge := telemetry.Default.Gauge("zenith_vlogs_data_bytes", "Size of the VictoriaLogs data directory in bytes.")
+
+	measure := func() {
+		metricsBytes, err := dirSize(metricsDir)
+		if err != nil {
+			log.Printf("Error measuring %s: %v", metricsDir, err)
+		}
+		logsBytes, err := dirSize(logsDir)
+		if err != nil {
+			log.Printf("Error measuring %s: %v", logsDir, err)
+		}
+
+		usage.mu.Lock()
+		usage.MetricsBytes = metricsBytes
+		usage.LogsBytes = logsBytes
+		usage.UpdatedAt = time.Now()
+		usage.mu.Unlock()
+
+		vmGauge.Set(nil, float64(metricsBytes))
+		vlGauge.Set(nil, float64(logsBytes))
+
+		labels := map[string]string{"host": "localhost"}
+		database.InsertMetric("zenith_vm_data_bytes", float64(metricsBytes), labels)
+		database.InsertMetric("zenith_vlogs_data_bytes", float64(logsBytes), labels)
+	}
+
+	measure()
+	ticker := time.NewTicker(dataDiskInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		measure()
+	}
+}
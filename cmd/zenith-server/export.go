@@ -0,0 +1,85 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"zenith/pkg/rl"
+)
+
+// handleExportDB streams a gzipped tar holding a consistent snapshot of the
+// RL database, taken with VACUUM INTO so it never captures a half-written
+// page while the server keeps logging interactions.
+func handleExportDB(w http.ResponseWriter, r *http.Request, rlDB *rl.DB) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	dir, err := os.MkdirTemp("", "zenith-export-")
+	if err != nil {
+		log.Printf("Error creating export directory: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to export database")
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	snapshot := filepath.Join(dir, filepath.Base(rlDBPath))
+	if err := rlDB.Snapshot(snapshot); err != nil {
+		log.Printf("Error exporting RL database: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to export database")
+		return
+	}
+
+	f, err := os.Open(snapshot)
+	if err != nil {
+		log.Printf("Error opening RL database snapshot: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to export database")
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		log.Printf("Error opening RL database snapshot: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to export database")
+		return
+	}
+
+	name := fmt.Sprintf("zenith_rl-%s.tar.gz", time.Now().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	hdr := &tar.Header{
+		Name:    filepath.Base(rlDBPath),
+		Mode:    0644,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+	// Headers are already sent, so a failure from here on can only be logged.
+	if err := tw.WriteHeader(hdr); err != nil {
+		log.Printf("Error writing export archive: %v", err)
+		return
+	}
+	if _, err := io.Copy(tw, f); err != nil {
+		log.Printf("Error writing export archive: %v", err)
+		return
+	}
+	if err := tw.Close(); err != nil {
+		log.Printf("Error writing export archive: %v", err)
+		return
+	}
+	if err := gz.Close(); err != nil {
+		log.Printf("Error writing export archive: %v", err)
+		return
+	}
+	log.Printf("Exported RL database snapshot (%d bytes)", info.Size())
+}
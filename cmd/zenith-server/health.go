@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"zenith/pkg/db"
+	"zenith/pkg/llm"
+	"zenith/pkg/rl"
+)
+
+// healthCheckTimeout bounds each dependency check so a hung backend makes
+// /health report it down instead of hanging the probe.
+const healthCheckTimeout = 5 * time.Second
+
+// DependencyStatus is the result of checking one dependency.
+type DependencyStatus struct {
+	Status    string `json:"status"` // "up", "down", or "disabled"
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latency_ms"`
+}
+
+// HealthResponse reports whether every dependency zenith-server needs to
+// answer queries is reachable. Unlike /healthz it actively probes them.
+type HealthResponse struct {
+	Status       string                      `json:"status"` // "ok" or "unavailable"
+	Dependencies map[string]DependencyStatus `json:"dependencies"`
+}
+
+// handleHealth probes VictoriaMetrics, VictoriaLogs, the LLM provider, and
+// the RL database concurrently, answering 503 if any of them is down so it
+// can back a Kubernetes readiness probe. An LLM provider that was never
+// configured (collection-only mode) is reported as disabled, not down.
+func handleHealth(w http.ResponseWriter, r *http.Request, database *db.VictoriaDB, breaker *llm.Breaker, rlDB *rl.DB) {
+	ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+	defer cancel()
+
+	checks := map[string]func(context.Context) error{
+		"victoria_metrics": func(ctx context.Context) error {
+			_, err := database.QueryMetricsResultsContext(ctx, "1")
+			return err
+		},
+		"victoria_logs": func(ctx context.Context) error {
+			_, err := database.QueryLogsResultsContext(ctx, "* | limit 1")
+			return err
+		},
+		"rl_db": rlDB.Ping,
+	}
+	if breaker != nil {
+		checks["llm"] = func(ctx context.Context) error {
+			if state := breaker.State(); state == llm.BreakerOpen {
+				return fmt.Errorf("circuit breaker is %s", state)
+			}
+			// Pingers don't take a context; give up on them at the deadline.
+			done := make(chan error, 1)
+			go func() { done <- breaker.Ping() }()
+			select {
+			case err := <-done:
+				return err
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+
+	resp := HealthResponse{Status: "ok", Dependencies: make(map[string]DependencyStatus)}
+	if breaker == nil {
+		resp.Dependencies["llm"] = DependencyStatus{Status: "disabled", Error: llm.ErrProviderNotConfigured.Error()}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check func(context.Context) error) {
+			defer wg.Done()
+			start := time.Now()
+			err := check(ctx)
+			status := DependencyStatus{Status: "up", LatencyMs: time.Since(start).Milliseconds()}
+			if err != nil {
+				status.Status = "down"
+				status.Error = err.Error()
+			}
+			mu.Lock()
+			resp.Dependencies[name] = status
+			if err != nil {
+				resp.Status = "unavailable"
+			}
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	w.Header().Set("Content-Type", "application/json")
+	if resp.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
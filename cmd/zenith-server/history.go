@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"zenith/pkg/rl"
+)
+
+// maxHistorySize caps history_size so a typo can't hold every answer the
+// server ever gives in memory.
+const maxHistorySize = 1000
+
+// HistoryEntry is one answer kept in the recent-answers ring buffer.
+type HistoryEntry struct {
+	Time     time.Time     `json:"time"`
+	Kind     string        `json:"kind"` // "query" or "recommend"
+	Question string        `json:"question,omitempty"`
+	Response QueryResponse `json:"response"`
+}
+
+// answerHistory is a fixed-size ring buffer of the most recent answers,
+// shared by all clients. It is lighter than reading the RL database and is
+// lost on restart.
+type answerHistory struct {
+	mu      sync.Mutex
+	entries []HistoryEntry
+	next    int
+	full    bool
+}
+
+func newAnswerHistory(size int) *answerHistory {
+	if size < 1 {
+		size = 1
+	}
+	if size > maxHistorySize {
+		size = maxHistorySize
+	}
+	return &answerHistory{entries: make([]HistoryEntry, size)}
+}
+
+var answers = newAnswerHistory(20)
+
+func (h *answerHistory) add(kind, question string, resp QueryResponse) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entries[h.next] = HistoryEntry{Time: time.Now(), Kind: kind, Question: question, Response: resp}
+	h.next = (h.next + 1) % len(h.entries)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// recent returns up to n entries, oldest first.
+func (h *answerHistory) recent(n int) []HistoryEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	count := h.next
+	if h.full {
+		count = len(h.entries)
+	}
+	if n > count {
+		n = count
+	}
+	out := make([]HistoryEntry, 0, n)
+	for i := n; i > 0; i-- {
+		out = append(out, h.entries[(h.next-i+len(h.entries))%len(h.entries)])
+	}
+	return out
+}
+
+func handleHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	n := 10
+	if s := r.URL.Query().Get("n"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 1 {
+			writeError(w, http.StatusBadRequest, "n must be a positive integer")
+			return
+		}
+		n = v
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(answers.recent(n))
+}
+
+// handleExperiences serves GET /history/experiences: the interactions
+// recorded in the RL database, newest first, paginated with ?limit=
+// (default 20, max 1000) and ?offset=. Unlike /history it survives
+// restarts and includes each interaction's generated query, result, and
+// feedback, so earlier answers can be found and re-scored by ID.
+func handleExperiences(w http.ResponseWriter, r *http.Request, rlDB *rl.DB) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	limit := 20
+	if s := r.URL.Query().Get("limit"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 1 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = min(v, maxHistorySize)
+	}
+	offset := 0
+	if s := r.URL.Query().Get("offset"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 0 {
+			writeError(w, http.StatusBadRequest, "offset must be a non-negative integer")
+			return
+		}
+		offset = v
+	}
+
+	experiences, err := rlDB.ListExperiences(limit, offset)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list interactions: %v", err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(experiences)
+}
+
+// handleStats serves GET /stats: interaction, success, failure, and
+// feedback counts from the RL database, overall and per source.
+func handleStats(w http.ResponseWriter, r *http.Request, rlDB *rl.DB) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	stats, err := rlDB.Stats()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to compute stats: %v", err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
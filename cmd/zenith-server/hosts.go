@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"zenith/pkg/db"
+)
+
+// HostsResponse is the body of GET /hosts.
+type HostsResponse struct {
+	Hosts []string `json:"hosts"`
+}
+
+// handleHosts serves GET /hosts: every value of the host label in
+// VictoriaMetrics, i.e. the machines reporting into it.
+func handleHosts(w http.ResponseWriter, r *http.Request, database *db.VictoriaDB) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	hosts, err := database.LabelValuesContext(r.Context(), "host")
+	if err != nil {
+		log.Printf("Error listing hosts: %v", err)
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	if hosts == nil {
+		hosts = []string{}
+	}
+	respondJSON(w, HostsResponse{Hosts: hosts})
+}
+
+var (
+	// whichHostPattern matches "which host is busiest?" and the like.
+	whichHostPattern = regexp.MustCompile(`(?i)\bwhich\s+(host|machine|server)s?\b`)
+	// compareHostsPattern matches "compare CPU across all hosts", "memory
+	// per host", "CPU by host".
+	compareHostsPattern = regexp.MustCompile(`(?i)\b(?:across|between|per|by|for\s+(?:all|each|every))\s+(?:the\s+|all\s+|each\s+|every\s+)*(?:hosts?|machines?|servers?)\b|\bcompare\b.*\b(?:hosts|machines|servers)\b`)
+	// leastPattern flips the ranking to the least loaded host first.
+	leastPattern = regexp.MustCompile(`(?i)\b(?:least|lowest|idlest|quietest|most idle)\b`)
+	// memoryWordPattern picks memory over CPU as the metric compared.
+	memoryWordPattern = regexp.MustCompile(`(?i)\b(?:memory|ram|mem)\b`)
+	// loadSubjectPattern matches questions that are about CPU or memory
+	// load at all; "which server runs postgres?" is not.
+	loadSubjectPattern = regexp.MustCompile(`(?i)\b(?:cpu|processor|load(?:ed)?|busy|busier|busiest|idle|idlest|quietest|memory|ram|mem|utili[sz]ation)\b`)
+	// otherSubjectPattern matches questions about something other than CPU
+	// or memory, which are left to the LLM.
+	otherSubjectPattern = regexp.MustCompile(`(?i)\b(?:disk|network|bytes|traffic|logs?|errors?|process(?:es)?|apps?|applications?|temperature|fans?|services?|fds?|files?)\b`)
+)
+
+// cannedHostQuery answers fleet questions about CPU or memory ("compare CPU
+// across all hosts", "which host is busiest?") deterministically, grouping
+// by the host label. "Which host" picks the top one; comparisons list every
+// host, busiest first (or least busy, for "least" and "lowest"). Models
+// tend to drop the grouping and average the whole fleet into one number.
+// Questions that name neither CPU, memory nor load, or name anything else,
+// go to the LLM.
+//
+// The collectors label everything host="localhost", so the grouping only
+// tells machines apart once each one relabels host to its own name.
+func cannedHostQuery(question string) (string, bool) {
+	which := whichHostPattern.FindStringSubmatch(question)
+	if which == nil && !compareHostsPattern.MatchString(question) {
+		return "", false
+	}
+	if !loadSubjectPattern.MatchString(question) || otherSubjectPattern.MatchString(question) {
+		return "", false
+	}
+
+	window, ok := questionWindow(question)
+	if !ok {
+		window = "1h"
+	}
+	metric := "cpu_usage_pct"
+	if memoryWordPattern.MatchString(question) {
+		metric = "memory_used_mb"
+	}
+
+	top, sort := "topk", "sort_desc"
+	if leastPattern.MatchString(question) {
+		top, sort = "bottomk", "sort"
+	}
+	byHost := fmt.Sprintf("avg by (host) (avg_over_time(%s[%s]))", metric, window)
+	if which != nil && !strings.HasSuffix(strings.ToLower(which[0]), "s") && !compareHostsPattern.MatchString(question) {
+		return fmt.Sprintf("METRIC:%s(1, %s)", top, byHost), true
+	}
+	return fmt.Sprintf("METRIC:%s(%s)", sort, byHost), true
+}
@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestCannedHostQuery(t *testing.T) {
+	tests := []struct {
+		question string
+		want     string
+	}{
+		{"which host is busiest?", "METRIC:topk(1, avg by (host) (avg_over_time(cpu_usage_pct[1h])))"},
+		{"which machine uses the most memory in the last 3 hours", "METRIC:topk(1, avg by (host) (avg_over_time(memory_used_mb[3h])))"},
+		{"compare CPU across all hosts", "METRIC:sort_desc(avg by (host) (avg_over_time(cpu_usage_pct[1h])))"},
+		{"memory per host", "METRIC:sort_desc(avg by (host) (avg_over_time(memory_used_mb[1h])))"},
+		{"which servers are least loaded", "METRIC:sort(avg by (host) (avg_over_time(cpu_usage_pct[1h])))"},
+	}
+	for _, tt := range tests {
+		if got, ok := cannedHostQuery(tt.question); !ok || got != tt.want {
+			t.Errorf("cannedHostQuery(%q) = %q, %v; want %q", tt.question, got, ok, tt.want)
+		}
+	}
+
+	noMatch := []string{
+		"which server runs postgres?",
+		"which machine rebooted last",
+		"which host has the most disk usage",
+		"which host sent the most network traffic",
+		"compare error logs across hosts",
+		"which process uses the most CPU",
+		"how busy is the cpu",
+		"which hosts are reporting",
+	}
+	for _, q := range noMatch {
+		if got, ok := cannedHostQuery(q); ok {
+			t.Errorf("Expected %q to go to the LLM, got %s", q, got)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"zenith/pkg/collector"
+	"zenith/pkg/config"
+	"zenith/pkg/db"
+	"zenith/pkg/gemini"
+)
+
+// StartupInfo is a structured summary of what this Zenith instance is
+// monitoring and where it keeps the results. It is logged at startup and
+// served from /info.
+type StartupInfo struct {
+	Collection collector.Info `json:"collection"`
+
+	CollectInterval string `json:"collect_interval"`
+	SRUMInterval    string `json:"srum_interval"`
+
+	Provider      string `json:"provider"`
+	Model         string `json:"model,omitempty"`
+	ProviderError string `json:"provider_error,omitempty"`
+
+	// SchemaDriftMissing are collected metrics the LLM prompt never
+	// mentions; SchemaDriftUnknown are prompt metrics no collector emits.
+	SchemaDriftMissing []string `json:"schema_drift_missing,omitempty"`
+	SchemaDriftUnknown []string `json:"schema_drift_unknown,omitempty"`
+
+	MetricsURLs []string `json:"metrics_urls"`
+	LogsURLs    []string `json:"logs_urls"`
+	MetricsData string   `json:"metrics_data"`
+	LogsData    string   `json:"logs_data"`
+	RLDatabase  string   `json:"rl_database"`
+}
+
+func buildStartupInfo(cfg *config.Config, database *db.VictoriaDB, providerErr error, driftMissing, driftUnknown []string) StartupInfo {
+	info := StartupInfo{
+		Collection:         collector.Describe(),
+		CollectInterval:    cfg.CollectInterval,
+		SRUMInterval:       srumInterval.String(),
+		Provider:           cfg.LLMProvider,
+		SchemaDriftMissing: driftMissing,
+		SchemaDriftUnknown: driftUnknown,
+		MetricsURLs:        database.MetricsURLs,
+		LogsURLs:           database.LogsURLs,
+		MetricsData:        cfg.MetricsData,
+		LogsData:           cfg.LogsData,
+		RLDatabase:         rlDBPath,
+	}
+	switch cfg.LLMProvider {
+	case "gemini":
+		info.Model = gemini.ModelName
+	case "ollama":
+		info.Model = cfg.OllamaModel
+	case "llamacpp":
+		info.Model = cfg.LlamaCppModel
+	}
+	if providerErr != nil {
+		info.ProviderError = providerErr.Error()
+	}
+	return info
+}
+
+func logStartupInfo(info StartupInfo) {
+	c := info.Collection
+	log.Printf("Monitoring on %s: collectors [%s]", c.Platform, strings.Join(c.Collectors, ", "))
+	log.Printf("Metrics produced: %s", strings.Join(c.Metrics, ", "))
+	log.Printf("Log sources: %s", strings.Join(c.LogSources, ", "))
+	log.Printf("Collection every %s (SRUM every %s), %d CPU sample(s) per cycle", info.CollectInterval, info.SRUMInterval, c.CPUSubSamples)
+	provider := info.Provider
+	if info.Model != "" {
+		provider += " (" + info.Model + ")"
+	}
+	if info.ProviderError != "" {
+		provider += ", unavailable: " + info.ProviderError
+	}
+	log.Printf("LLM provider: %s", provider)
+	log.Printf("Data: metrics %s, logs %s, interactions %s", info.MetricsData, info.LogsData, info.RLDatabase)
+}
+
+func handleInfo(w http.ResponseWriter, r *http.Request, info StartupInfo) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
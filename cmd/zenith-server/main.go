@@ -3,45 +3,77 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
 	"syscall"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"zenith/pkg/collector"
 	"zenith/pkg/config"
 	"zenith/pkg/db"
-	"zenith/pkg/gemini"
-	"zenith/pkg/llamacpp"
 	"zenith/pkg/llm"
-	"zenith/pkg/ollama"
 	"zenith/pkg/rl"
+	"zenith/pkg/telemetry"
 )
 
 type QueryRequest struct {
 	Query string `json:"query"`
+	// SQL is the previously generated query being confirmed with
+	// ?confirmed=1. It is executed as-is instead of asking the LLM again.
+	SQL string `json:"sql,omitempty"`
+	// Hint optionally constrains query generation to a metric and label.
+	Hint *llm.QueryHint `json:"hint,omitempty"`
 }
 
 type QueryResponse struct {
-	InteractionID int64  `json:"interaction_id,omitempty"`
-	Answer        string `json:"answer"`
-	Error         string `json:"error,omitempty"`
+	InteractionID int64         `json:"interaction_id,omitempty"`
+	Answer        string        `json:"answer"`
+	Error         string        `json:"error,omitempty"`
+	Results       *QueryResults `json:"results,omitempty"`
+
+	// RequiresConfirmation is set instead of executing when the generated
+	// query (in Query) would scan more than the configured window.
+	RequiresConfirmation bool   `json:"requires_confirmation,omitempty"`
+	Query                string `json:"query,omitempty"`
+
+	// Attempts is how many generate/execute rounds the query took. It is
+	// only reported with ?verbose=1.
+	Attempts int `json:"attempts,omitempty"`
+
+	// Trace shows how the answer was derived. It is only included with
+	// ?trace=1.
+	Trace *QueryTrace `json:"trace,omitempty"`
+}
+
+// QueryResults carries the typed rows behind an answer. It is only included
+// in a /query response when the client asks for it with ?include_results=1.
+type QueryResults struct {
+	Type    string            `json:"type"` // "metric", "range", or "log"
+	Query   string            `json:"query"`
+	Metrics []db.MetricResult `json:"metrics,omitempty"`
+	Series  []db.TimeSeries   `json:"series,omitempty"`
+	Logs    []db.LogResult    `json:"logs,omitempty"`
 }
 
 var DefaultAPIKey string
 
 func main() {
 	// Load config first
-	cfg, err := config.LoadConfig("config.json")
+	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("failed to load config: %v", err)
 	}
@@ -76,6 +108,9 @@ func main() {
 
 	envKey := os.Getenv("GEMINI_API_KEY")
 	defaultKey := envKey
+	if defaultKey == "" && cfg.GeminiAPIKeyFile != "" {
+		defaultKey = "file://" + cfg.GeminiAPIKeyFile
+	}
 	if defaultKey == "" {
 		defaultKey = cfg.GeminiAPIKey
 		if defaultKey == "" {
@@ -86,92 +121,341 @@ func main() {
 	provider := flag.String("provider", cfg.LLMProvider, "LLM Provider (gemini, ollama, llamacpp)")
 	modelName := flag.String("model", cfg.OllamaModel, "Model name for ollama local provider")
 	apiKey := flag.String("key", defaultKey, "Gemini API Key")
+	dumpConfig := flag.Bool("dump-config", false, "Print the effective configuration as JSON (secrets redacted) and exit")
 	flag.Parse()
 
-	if *provider == "gemini" && *apiKey == "" {
-		log.Fatal("Gemini API key is required")
+	// Fold flag and env overrides back into cfg so it reflects what is actually in effect
+	cfg.ServerPort = *port
+	cfg.CollectInterval = *collectInterval
+	cfg.MetricsHost, cfg.MetricsPort = splitHostPort(*metricsURL, cfg.MetricsHost, cfg.MetricsPort)
+	cfg.LogsHost, cfg.LogsPort = splitHostPort(*logsURL, cfg.LogsHost, cfg.LogsPort)
+	cfg.MetricsBin = *metricsBin
+	cfg.LogsBin = *logsBin
+	cfg.MetricsData = *metricsData
+	cfg.LogsData = *logsData
+	cfg.LlamaCppBin = *llamaBin
+	cfg.LlamaCppModel = *llamaModel
+	cfg.LLMProvider = *provider
+	cfg.OllamaModel = *modelName
+	cfg.GeminiAPIKey = *apiKey
+
+	// Resolve file:// and env:// references so the key itself never has to
+	// appear in config.json or on the command line.
+	if key, err := config.ResolveSecret(cfg.GeminiAPIKey); err != nil {
+		log.Printf("Error resolving Gemini API key: %v", err)
+		cfg.GeminiAPIKey = ""
+	} else {
+		cfg.GeminiAPIKey = key
+	}
+
+	if *dumpConfig {
+		out, err := json.MarshalIndent(cfg.Redacted(), "", "    ")
+		if err != nil {
+			log.Fatalf("failed to encode config: %v", err)
+		}
+		fmt.Println(string(out))
+		return
+	}
+
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(err)
 	}
 
 	// Extract ports from URLs to start databases on the correct ports
 	metricsPort := extractPort(*metricsURL, cfg.MetricsPort)
 	logsPort := extractPort(*logsURL, cfg.LogsPort)
 
-	// Start VictoriaMetrics and VictoriaLogs
-	metricsCmd := startProcess(*metricsBin, "-storageDataPath", *metricsData, "-httpListenAddr", fmt.Sprintf(":%d", metricsPort))
-	defer stopProcess(metricsCmd)
+	if grace, err := time.ParseDuration(cfg.SubprocessShutdownGrace); err == nil {
+		subprocessShutdownGrace = grace
+	} else {
+		log.Printf("Invalid subprocess_shutdown_grace '%s', defaulting to %s: %v", cfg.SubprocessShutdownGrace, subprocessShutdownGrace, err)
+	}
 
-	logsCmd := startProcess(*logsBin, "-storageDataPath", *logsData, "-httpListenAddr", fmt.Sprintf(":%d", logsPort))
-	defer stopProcess(logsCmd)
+	// Start VictoriaMetrics and VictoriaLogs, unless they run elsewhere
+	if cfg.ManagesMetrics() {
+		metricsProc := superviseProcess(*metricsBin, "-storageDataPath", *metricsData, "-httpListenAddr", fmt.Sprintf(":%d", metricsPort))
+		defer metricsProc.Stop()
+	} else {
+		log.Printf("Using external VictoriaMetrics at %s", *metricsURL)
+	}
+
+	if cfg.ManagesLogs() {
+		logsProc := superviseProcess(*logsBin, "-storageDataPath", *logsData, "-httpListenAddr", fmt.Sprintf(":%d", logsPort))
+		defer logsProc.Stop()
+	} else {
+		log.Printf("Using external VictoriaLogs at %s", *logsURL)
+	}
 
 	// Wait a moment for databases to start
 	time.Sleep(2 * time.Second)
 
-	database := db.NewVictoriaDB(*metricsURL, *logsURL)
-	log.Printf("Using VictoriaMetrics at %s", *metricsURL)
-	log.Printf("Using VictoriaLogs at %s", *logsURL)
+	dbPass, err := config.ResolveSecret(cfg.DBBasicAuthPass)
+	if err != nil {
+		log.Fatalf("invalid db_basic_auth_pass: %v", err)
+	}
+	dbToken, err := config.ResolveSecret(cfg.DBBearerToken)
+	if err != nil {
+		log.Fatalf("invalid db_bearer_token: %v", err)
+	}
+	database := db.NewVictoriaDBWithAuth(
+		strings.Join(append([]string{*metricsURL}, cfg.MetricsMirrorURLs...), ","),
+		strings.Join(append([]string{*logsURL}, cfg.LogsMirrorURLs...), ","),
+		cfg.DBBasicAuthUser, dbPass, dbToken,
+	)
+	database.LogLimit = cfg.DefaultLogLimit
+	database.LogTimeField = cfg.LogsTimeField
+	database.LogMsgField = cfg.LogsMsgField
+	database.LogStreamFields = cfg.LogsStreamFields
+	if database.Relabeler, err = db.NewRelabeler(cfg.Relabel); err != nil {
+		log.Fatalf("invalid relabel config: %v", err)
+	}
+	if cfg.ExposeCollectedMetrics {
+		database.Latest = db.NewLatestSamples(collector.CounterNames)
+		// Long enough to span the hourly SRUM cycle.
+		database.Latest.MaxAge = 2 * time.Hour
+		if cfg.TagRunID {
+			database.Latest.DropLabels = []string{"run_id"}
+		}
+	}
+	var examples []llm.Example
+	if cfg.ExamplesFile != "" {
+		if examples, err = llm.LoadExamples(cfg.ExamplesFile); err != nil {
+			log.Fatalf("invalid examples file: %v", err)
+		}
+		log.Printf("Loaded %d query examples from %s", len(examples), cfg.ExamplesFile)
+	}
+	if cfg.DBQueryTimeout != "" {
+		if database.QueryTimeout, err = time.ParseDuration(cfg.DBQueryTimeout); err != nil {
+			log.Printf("Invalid db_query_timeout '%s', using the VictoriaMetrics default: %v", cfg.DBQueryTimeout, err)
+		}
+	}
+
+	var confirmWindow time.Duration
+	if cfg.ConfirmQueryWindow != "" {
+		confirmWindow, err = db.ParseDuration(cfg.ConfirmQueryWindow)
+		if err != nil {
+			log.Printf("Invalid confirm_query_window '%s', disabling query confirmation: %v", cfg.ConfirmQueryWindow, err)
+		}
+	}
+	switch db.WriteMode(cfg.BackendWriteMode) {
+	case db.WriteAny, db.WriteAll:
+		database.WriteMode = db.WriteMode(cfg.BackendWriteMode)
+	default:
+		log.Printf("Invalid backend_write_mode '%s', defaulting to any", cfg.BackendWriteMode)
+	}
+	log.Printf("Using VictoriaMetrics at %s", strings.Join(database.MetricsURLs, ", "))
+	log.Printf("Using VictoriaLogs at %s", strings.Join(database.LogsURLs, ", "))
 
 	// Initialize LLM Provider
-	var llmProvider llm.Provider
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	log.Printf("Initializing LLM Provider: %s", *provider)
-	switch *provider {
-	case "gemini":
-		client, err := gemini.NewClient(ctx, *apiKey)
+	llmProvider, llamaCmd, providerErr := initProvider(ctx, cfg, providerOptions{
+		Name:        *provider,
+		APIKey:      *apiKey,
+		OllamaModel: *modelName,
+		LlamaBin:    *llamaBin,
+		LlamaModel:  *llamaModel,
+	})
+	defer stopProcess(llamaCmd)
+
+	var breaker *llm.Breaker
+	var driftMissing, driftUnknown []string
+	if providerErr != nil {
+		if !cfg.AllowProviderless {
+			log.Fatalf("LLM provider %s unavailable: %v", *provider, providerErr)
+		}
+		log.Printf("Warning: LLM provider %s unavailable, running in collection-only mode: %v", *provider, providerErr)
+	} else {
+		driftMissing, driftUnknown = checkSchemaDrift(llmProvider)
+
+		// Wrap the provider in a circuit breaker so a dead backend fast-fails
+		// instead of making every request walk the full retry chain.
+		breakerCooldown, err := time.ParseDuration(cfg.LLMBreakerCooldown)
 		if err != nil {
-			log.Fatalf("failed to create gemini client: %v", err)
-		}
-		llmProvider = client
-		log.Println("Using Gemini Provider")
-	case "ollama":
-		ollamaURL := fmt.Sprintf("http://%s:%d", cfg.OllamaHost, cfg.OllamaPort)
-		llmProvider = ollama.NewClient(ollamaURL, *modelName)
-		log.Printf("Using Ollama Provider at %s (Model: %s)", ollamaURL, *modelName)
-	case "llamacpp":
-		log.Printf("Configured Llama.cpp Model: %s", *llamaModel)
-		// Auto-download model if missing
-		if err := llamacpp.EnsureModel(*llamaModel); err != nil {
-			log.Fatalf("failed to ensure llama model: %v", err)
-		}
-
-		// Start llama-server process
-		llamaURL := fmt.Sprintf("http://%s:%d", cfg.LlamaCppHost, cfg.LlamaCppPort)
-		log.Printf("Starting llama-server at %s with binary %s", llamaURL, *llamaBin)
-		llamaCmd := startProcess(*llamaBin, "-m", *llamaModel, "--host", cfg.LlamaCppHost, "--port", fmt.Sprintf("%d", cfg.LlamaCppPort))
-		defer stopProcess(llamaCmd)
-
-		// Wait a moment for server to start
-		time.Sleep(2 * time.Second)
-
-		llmProvider = llamacpp.NewClient(llamaURL)
-		log.Printf("Using Llama.cpp Provider at %s", llamaURL)
-	default:
-		log.Fatalf("Unknown provider: %s", *provider)
+			log.Printf("Invalid llm_breaker_cooldown '%s', defaulting to 30s: %v", cfg.LLMBreakerCooldown, err)
+			breakerCooldown = 30 * time.Second
+		}
+		redactor, err := llm.NewRedactor(cfg.RedactSensitiveData, cfg.RedactPatterns)
+		if err != nil {
+			log.Fatalf("invalid redact_patterns: %v", err)
+		}
+		if redactor != nil {
+			log.Printf("Redacting sensitive data from query results and system data sent to the LLM (built-ins: %v, %d custom pattern(s))", cfg.RedactSensitiveData, len(cfg.RedactPatterns))
+			llmProvider = redactingProvider{llmProvider, redactor}
+		}
+		breaker = llm.NewBreaker(timedProvider{llmProvider}, cfg.LLMBreakerThreshold, breakerCooldown)
+		llmProvider = breaker
+		telemetry.Default.GaugeFunc("zenith_llm_breaker_state", "LLM circuit breaker state (0=closed, 1=open, 2=half-open).", func() float64 {
+			return float64(breaker.State())
+		})
+		telemetry.Default.GaugeFunc("zenith_llm_consecutive_failures", "Consecutive LLM provider failures since the last success.", func() float64 {
+			return float64(breaker.ConsecutiveFailures())
+		})
 	}
 
 	// Initialize RL Database
-	rlDB, err := rl.InitDB("zenith_rl.db")
+	rlDB, err := rl.InitDB(rlDBPath)
 	if err != nil {
 		log.Fatalf("failed to init RL database: %v", err)
 	}
 	defer rlDB.Close()
 
+	var rlRetention time.Duration
+	if cfg.RLRetention != "" {
+		if rlRetention, err = db.ParseDuration(cfg.RLRetention); err != nil {
+			log.Printf("Invalid rl_retention '%s', not pruning old interactions: %v", cfg.RLRetention, err)
+			rlRetention = 0
+		}
+	}
+	if rlRetention > 0 || cfg.RLMaxRows > 0 {
+		go pruneExperiences(rlDB, rlRetention, cfg.RLMaxRows)
+	}
+
 	// Start Background Collection
-	go startScheduler(database, *collectInterval)
+	collector.SetCollectionNice(cfg.CollectionNice)
+	cpuSampleInterval, err := time.ParseDuration(cfg.CPUSubSampleInterval)
+	if err != nil {
+		log.Printf("Invalid cpu_sub_sample_interval '%s', defaulting to 1s: %v", cfg.CPUSubSampleInterval, err)
+		cpuSampleInterval = time.Second
+	}
+	collector.SetCPUSampling(cfg.CPUSubSamples, cpuSampleInterval)
+	if cfg.MaxLogAge != "" {
+		maxLogAge, err := db.ParseDuration(cfg.MaxLogAge)
+		if err != nil {
+			log.Printf("Invalid max_log_age '%s', not limiting log age: %v", cfg.MaxLogAge, err)
+		} else {
+			collector.SetMaxLogAge(maxLogAge)
+		}
+	}
+	collector.SetProcessThresholds(cfg.ProcessCPUThreshold, cfg.ProcessMemThresholdMB)
+	tagRunID = cfg.TagRunID
+	autoFeedback = cfg.AutoFeedbackOnSuccess
+	if tagRunID && cfg.EmitStaleMarkers {
+		log.Println("tag_run_id gives every collection run its own series, so emit_stale_markers is ignored")
+		cfg.EmitStaleMarkers = false
+	}
+	collector.SetEmitStaleMarkers(cfg.EmitStaleMarkers)
+	if err := collector.SetMacOSLogScope(cfg.MacOSLogScope, cfg.MacOSLogSubsystems); err != nil {
+		log.Fatalf("invalid macos_log_scope: %v", err)
+	}
+	collector.SetEventLogChannels(cfg.EventLogChannels)
+	collector.SetEnableThermal(cfg.EnableThermal)
+	collector.SetSrumCursorFile(cfg.SrumCursorFile)
+	scheduler := newSchedulerWatchdog(database, *collectInterval)
+	go scheduler.run()
+
+	answers = newAnswerHistory(cfg.HistorySize)
+	if !cfg.DisableSQLCache {
+		ttl, err := db.ParseDuration(cfg.SQLCacheTTL)
+		if err != nil {
+			log.Printf("Invalid sql_cache_ttl '%s', defaulting to 1h: %v", cfg.SQLCacheTTL, err)
+			ttl = time.Hour
+		}
+		translations = newSQLCache(cfg.SQLCacheSize, ttl)
+	}
+
+	if cfg.ReportInterval != "" {
+		reportInterval, err := db.ParseDuration(cfg.ReportInterval)
+		switch {
+		case err != nil || reportInterval <= 0:
+			log.Printf("Invalid report_interval '%s', not generating scheduled reports: %v", cfg.ReportInterval, err)
+		case providerErr != nil:
+			log.Printf("LLM provider unavailable, not generating scheduled reports")
+		default:
+			log.Printf("Writing a report to %s every %s", cfg.ReportDir, cfg.ReportInterval)
+			go startReportJob(database, llmProvider, rlDB, reportInterval, cfg.ReportDir, cfg.ResponseLanguage)
+		}
+	}
+
+	info := buildStartupInfo(cfg, database, providerErr, driftMissing, driftUnknown)
+	logStartupInfo(info)
+
+	// Track how much disk the embedded databases are using
+	diskUsage := &DataDiskUsage{}
+	go watchDataDirs(database, *metricsData, *logsData, diskUsage)
+
+	recommendExtra := recommendExtraQueries(cfg.RecommendExtraMetrics)
 
 	// Start HTTP Server
-	server := &http.Server{Addr: fmt.Sprintf(":%d", *port)}
-	http.HandleFunc("/query", func(w http.ResponseWriter, r *http.Request) {
-		handleQuery(w, r, database, llmProvider, rlDB)
+	server := &http.Server{Addr: fmt.Sprintf(":%d", *port), Handler: recoverPanics(http.DefaultServeMux)}
+	http.HandleFunc("/query", trackInFlight(requireProvider(llmProvider, providerErr, queries.cancellable(func(w http.ResponseWriter, r *http.Request) {
+		handleQuery(w, r, database, llmProvider, rlDB, cfg.ResponseLanguage, confirmWindow, examples, cfg.RLExamples)
+	}))))
+	http.HandleFunc("/query/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handleCancelQuery(w, r, cfg.AdminToken)
 	})
-	http.HandleFunc("/recommend", func(w http.ResponseWriter, r *http.Request) {
-		handleRecommend(w, r, database, llmProvider, rlDB)
-	})
-	http.HandleFunc("/feedback", func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/query/raw", trackInFlight(func(w http.ResponseWriter, r *http.Request) {
+		handleRawQuery(w, r, database)
+	}))
+	http.HandleFunc("/query_range", trackInFlight(func(w http.ResponseWriter, r *http.Request) {
+		handleQueryRange(w, r, database)
+	}))
+	http.HandleFunc("/recommend", trackInFlight(requireProvider(llmProvider, providerErr, func(w http.ResponseWriter, r *http.Request) {
+		handleRecommend(w, r, database, llmProvider, rlDB, cfg.ResponseLanguage, recommendExtra, cfg.RecommendFallback)
+	})))
+	http.HandleFunc("/report", trackInFlight(requireProvider(llmProvider, providerErr, func(w http.ResponseWriter, r *http.Request) {
+		handleReport(w, r, database, llmProvider, rlDB, cfg.ResponseLanguage)
+	})))
+	http.HandleFunc("/feedback", trackInFlight(func(w http.ResponseWriter, r *http.Request) {
 		handleFeedback(w, r, rlDB)
+	}))
+	http.HandleFunc("/metrics", handleMetrics)
+	http.HandleFunc("/internal/metrics", handleMetrics)
+	// Past prompts and answers, the collection schedule and the RL database
+	// are the operator's, so these take the admin token even without
+	// enable_admin_endpoints.
+	http.HandleFunc("/history", requireAdminToken(cfg.AdminToken, handleHistory))
+	http.HandleFunc("/history/experiences", requireAdminToken(cfg.AdminToken, func(w http.ResponseWriter, r *http.Request) {
+		handleExperiences(w, r, rlDB)
+	}))
+	http.HandleFunc("/config/interval", requireAdminToken(cfg.AdminToken, func(w http.ResponseWriter, r *http.Request) {
+		handleCollectInterval(w, r, scheduler)
+	}))
+	http.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		handleStats(w, r, rlDB)
+	})
+	http.HandleFunc("/export/rl-db", trackInFlight(requireAdminToken(cfg.AdminToken, func(w http.ResponseWriter, r *http.Request) {
+		handleExportDB(w, r, rlDB)
+	})))
+	http.HandleFunc("/info", func(w http.ResponseWriter, r *http.Request) {
+		live := info
+		live.CollectInterval, _ = scheduler.currentInterval()
+		handleInfo(w, r, live)
+	})
+	http.HandleFunc("/schema/reconcile", func(w http.ResponseWriter, r *http.Request) {
+		handleSchemaReconcile(w, r, database)
+	})
+	http.HandleFunc("/hosts", func(w http.ResponseWriter, r *http.Request) {
+		handleHosts(w, r, database)
+	})
+	if database.Latest != nil {
+		http.HandleFunc("/collect/metrics", func(w http.ResponseWriter, r *http.Request) {
+			handleCollectedMetrics(w, r, database.Latest)
+		})
+	}
+	if cfg.EnableAdminEndpoints {
+		log.Println("Admin endpoints enabled.")
+		http.HandleFunc("/admin/delete", trackInFlight(requireAdminToken(cfg.AdminToken, func(w http.ResponseWriter, r *http.Request) {
+			handleAdminDelete(w, r, database)
+		})))
+	}
+	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		handleHealth(w, r, database, breaker, rlDB)
+	})
+	http.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		handleHealthz(w, r, breaker, diskUsage, cfg.DataDiskWarnBytes, scheduler)
 	})
 
+	// Dump runtime state on SIGUSR1 (unix only)
+	dumpChan := make(chan os.Signal, 1)
+	notifyDumpSignal(dumpChan)
+	go func() {
+		for range dumpChan {
+			dumpState(*provider, breaker)
+		}
+	}()
+
 	// Handle Graceful Shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
@@ -211,7 +495,27 @@ func extractPort(urlStr string, defaultPort int) int {
 	return defaultPort
 }
 
+// splitHostPort returns the host and port of a URL such as http://localhost:8428,
+// falling back to the provided defaults for any part that cannot be parsed.
+func splitHostPort(rawURL, defaultHost string, defaultPort int) (string, int) {
+	host := defaultHost
+	if u, err := url.Parse(rawURL); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	return host, extractPort(rawURL, defaultPort)
+}
+
 func startProcess(bin string, args ...string) *exec.Cmd {
+	cmd, err := tryStartProcess(bin, args...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return cmd
+}
+
+// tryStartProcess is startProcess for optional subprocesses whose failure to
+// start should be reported rather than abort the server.
+func tryStartProcess(bin string, args ...string) (*exec.Cmd, error) {
 	// Security fix for Windows: Go 1.19+ doesn't allow running executables
 	// relative to current directory without an explicit path separator.
 	if !filepath.IsAbs(bin) && !strings.Contains(bin, string(filepath.Separator)) {
@@ -220,7 +524,10 @@ func startProcess(bin string, args ...string) *exec.Cmd {
 		}
 	}
 
+	reapStaleChild(bin)
+
 	cmd := exec.Command(bin, args...)
+	configureChild(cmd)
 	// Set stdout/stderr to files or just discard if they are too chatty
 	// For debugging, we can redirect to files
 	logFile, err := os.OpenFile(fmt.Sprintf("%s.log", strings.TrimSuffix(filepath.Base(bin), filepath.Ext(bin))), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
@@ -231,173 +538,653 @@ func startProcess(bin string, args ...string) *exec.Cmd {
 
 	log.Printf("Starting %s...", bin)
 	if err := cmd.Start(); err != nil {
-		log.Fatalf("Failed to start %s: %v", bin, err)
+		return nil, fmt.Errorf("failed to start %s: %v", bin, err)
 	}
-	return cmd
+	if err := adoptChild(cmd); err != nil {
+		log.Printf("Warning: %s may outlive the server if it crashes: %v", bin, err)
+	}
+	recordChildPID(bin, cmd)
+	return cmd, nil
 }
 
+// subprocessShutdownGrace is how long a child gets to exit after the
+// graceful stop request before it is killed. VictoriaMetrics may need a while
+// to flush to a slow disk.
+var subprocessShutdownGrace = 10 * time.Second
+
+// subprocessKillWait bounds the final wait after a kill.
+const subprocessKillWait = 5 * time.Second
+
 func stopProcess(cmd *exec.Cmd) {
 	if cmd != nil && cmd.Process != nil {
-		log.Printf("Stopping process %d...", cmd.Process.Pid)
+		done := make(chan struct{})
+		go func() {
+			cmd.Wait()
+			close(done)
+		}()
+		stopStartedProcess(cmd, done)
+	}
+}
 
-		// Windows doesn't support SIGTERM for child processes in the same way.
-		// We'll try to be gentle but fall back to Kill quickly on Windows.
-		if runtime.GOOS == "windows" {
-			cmd.Process.Kill()
-		} else {
-			cmd.Process.Signal(syscall.SIGTERM)
-		}
+// stopStartedProcess is stopProcess for a process that something else is
+// already waiting for; done is closed when it exits.
+func stopStartedProcess(cmd *exec.Cmd, done <-chan struct{}) {
+	log.Printf("Stopping process %d...", cmd.Process.Pid)
+	defer forgetChildPID(cmd)
 
-		// Wait for it to exit
-		done := make(chan error, 1)
-		go func() { done <- cmd.Wait() }()
+	// SIGTERM on unix, CTRL_BREAK to the child's process group on Windows
+	if err := interruptProcess(cmd.Process); err != nil {
+		log.Printf("Graceful stop of process %d failed, killing: %v", cmd.Process.Pid, err)
+		cmd.Process.Kill()
+	}
 
-		select {
-		case <-done:
-			log.Println("Process exited.")
-		case <-time.After(3 * time.Second):
-			log.Println("Process timed out, killing...")
-			cmd.Process.Kill()
+	select {
+	case <-done:
+		log.Println("Process exited.")
+		return
+	case <-time.After(subprocessShutdownGrace):
+		log.Printf("Process %d did not exit within %s, killing...", cmd.Process.Pid, subprocessShutdownGrace)
+		cmd.Process.Kill()
+	}
+
+	select {
+	case <-done:
+		log.Println("Process killed.")
+	case <-time.After(subprocessKillWait):
+		log.Printf("Process %d still running %s after kill", cmd.Process.Pid, subprocessKillWait)
+	}
+}
+
+// srumInterval is how often SRUM is read. Windows only flushes SRUM every
+// hour, so there is no point polling faster.
+const srumInterval = 60 * time.Minute
+
+// rlDBPath is where interactions and feedback are recorded.
+const rlDBPath = "zenith_rl.db"
+
+// rlPruneInterval is how often old interactions are pruned from the RL
+// database.
+const rlPruneInterval = 24 * time.Hour
+
+// pruneExperiences deletes interactions older than retention and beyond the
+// newest maxRows (either may be 0 to skip it) at startup and then daily.
+// Interactions with feedback are always kept.
+func pruneExperiences(rlDB *rl.DB, retention time.Duration, maxRows int) {
+	for {
+		if retention > 0 {
+			if n, err := rlDB.PruneOlderThan(retention); err != nil {
+				log.Printf("Failed to prune old interactions: %v", err)
+			} else if n > 0 {
+				log.Printf("Pruned %d interactions older than %s from the RL database", n, formatPeriod(retention))
+			}
+		}
+		if maxRows > 0 {
+			if n, err := rlDB.PruneToMaxRows(maxRows); err != nil {
+				log.Printf("Failed to prune interactions: %v", err)
+			} else if n > 0 {
+				log.Printf("Pruned %d interactions beyond the newest %d from the RL database", n, maxRows)
+			}
 		}
+		time.Sleep(rlPruneInterval)
 	}
 }
 
-func startScheduler(database *db.VictoriaDB, intervalStr string) {
-	interval, err := time.ParseDuration(intervalStr)
+// parseCollectInterval parses the collection interval, defaulting to 5m.
+func parseCollectInterval(intervalStr string) time.Duration {
+	interval, err := db.ParseDuration(intervalStr)
 	if err != nil {
 		log.Printf("Invalid interval format '%s', defaulting to 5m: %v", intervalStr, err)
 		interval = 5 * time.Minute
 	}
+	return interval
+}
+
+// startScheduler runs collection cycles for generation gen of the
+// watchdog's scheduler until a newer generation replaces it.
+func startScheduler(s *schedulerWatchdog, gen int64) {
+	database := s.database
+	intervalStr, interval := s.currentInterval()
 
 	// Regular 5-minute ticker: logs, CPU, memory, process, network
 	regularTicker := time.NewTicker(interval)
 	defer regularTicker.Stop()
 
-	// SRUM ticker: Windows only flushes SRUM every hour, so no point polling faster
-	srumTicker := time.NewTicker(60 * time.Minute)
+	srumTicker := time.NewTicker(srumInterval)
 	defer srumTicker.Stop()
 
+	// Beat while idle too, so a long interval doesn't look like a stall.
+	heartbeatTicker := time.NewTicker(min(interval, time.Minute))
+	defer heartbeatTicker.Stop()
+
 	// Run both immediately on startup
 	log.Println("Running initial collection...")
-	runCollection(database, intervalStr)
-	go runSRUMCollection(database)
+	recoverCollection("regular", func() { runCollection(database, intervalStr) })
+	go recoverCollection("srum", func() { runSRUMCollection(database) })
 
-	for {
+	for s.beat(gen) {
 		select {
 		case <-regularTicker.C:
 			log.Println("Running scheduled collection...")
-			runCollection(database, intervalStr)
+			recoverCollection("regular", func() { runCollection(database, intervalStr) })
 		case <-srumTicker.C:
 			log.Println("Running scheduled SRUM collection...")
-			runSRUMCollection(database)
+			recoverCollection("srum", func() { runSRUMCollection(database) })
+		case <-heartbeatTicker.C:
+		case <-s.reset:
+			intervalStr, interval = s.currentInterval()
+			regularTicker.Reset(interval)
+			heartbeatTicker.Reset(min(interval, time.Minute))
+			log.Printf("Collection scheduler now runs every %s", intervalStr)
 		}
 	}
+	log.Printf("Collection scheduler %d replaced by a newer one, exiting", gen)
+}
+
+// collectionErrors logs collection failures, collapsing a failure that
+// repeats every cycle (a missing binary, a database that is down) into one
+// line per hour.
+var collectionErrors = telemetry.NewDeduper(time.Hour, func(s string) { log.Print(s) })
+
+// tagRunID adds each collection run's ID as a run_id label on the gauge
+// samples it writes. Off by default: every run then creates new series.
+var tagRunID bool
+
+// newRunID names a collection run of kind ("regular" or "srum") after when
+// it started, e.g. "regular-20240501T101500Z".
+func newRunID(kind string, start time.Time) string {
+	return kind + "-" + start.UTC().Format("20060102T150405Z")
+}
+
+// runSink is where a run with runID writes its samples: database, with a
+// run_id label on gauges when tag_run_id is on. Counters stay untagged so
+// increase() and rate() keep working across runs.
+func runSink(database *db.VictoriaDB, runID string) collector.MetricSink {
+	if !tagRunID {
+		return database
+	}
+	return collector.WithGaugeLabels(database, map[string]string{"run_id": runID})
 }
 
 func runCollection(database *db.VictoriaDB, duration string) {
+	start := time.Now()
+	defer timeCollection("regular", start)
+	runID := newRunID("regular", start)
+	sink := runSink(database, runID)
+	log.Printf("Starting collection run %s", runID)
+
 	if err := collector.CollectLogs(database, duration); err != nil {
-		log.Printf("Error collecting logs: %v", err)
+		collectionErrors.Printf("Error collecting logs: %v", err)
+	}
+	if err := collector.CollectLogCounts(database, sink, duration); err != nil {
+		collectionErrors.Printf("Error counting logs: %v", err)
 	}
-	if err := collector.CollectMetrics(database); err != nil {
-		log.Printf("Error collecting metrics: %v", err)
+	if err := collector.CollectMetrics(sink); err != nil {
+		collectionErrors.Printf("Error collecting metrics: %v", err)
 	}
-	if err := collector.CollectProcessMetrics(database); err != nil {
-		log.Printf("Error collecting process metrics: %v", err)
+	if err := collector.CollectProcessMetrics(sink); err != nil {
+		collectionErrors.Printf("Error collecting process metrics: %v", err)
 	}
-	log.Println("Finished collection.")
+	state.markCollection()
+	log.Printf("Finished collection run %s.", runID)
 }
 
 func runSRUMCollection(database *db.VictoriaDB) {
-	if err := collector.CollectSrumHistoricalMetrics(database); err != nil {
-		log.Printf("Error collecting SRUM historical metrics: %v", err)
+	start := time.Now()
+	defer timeCollection("srum", start)
+	runID := newRunID("srum", start)
+	log.Printf("Starting SRUM collection run %s", runID)
+
+	if err := collector.CollectSrumHistoricalMetrics(runSink(database, runID)); err != nil {
+		collectionErrors.Printf("Error collecting SRUM historical metrics: %v", err)
 	}
-	log.Println("Finished SRUM collection.")
+	state.markSRUMCollection()
+	log.Printf("Finished SRUM collection run %s.", runID)
 }
 
-func handleQuery(w http.ResponseWriter, r *http.Request, database *db.VictoriaDB, client llm.Provider, rlDB *rl.DB) {
+// learnedExamples returns up to n past questions this machine translated
+// successfully, as few-shot examples.
+func learnedExamples(rlDB *rl.DB, n int) []llm.Example {
+	experiences, err := rlDB.GetSuccessfulExamples("query", n)
+	if err != nil {
+		log.Printf("Failed to load past successful queries: %v", err)
+		return nil
+	}
+	var examples []llm.Example
+	for _, e := range experiences {
+		if ex, ok := llm.ParseExample(e.Prompt, e.GeneratedQuery); ok {
+			examples = append(examples, ex)
+		}
+	}
+	return examples
+}
+
+func handleQuery(w http.ResponseWriter, r *http.Request, database *db.VictoriaDB, client llm.Provider, rlDB *rl.DB, defaultLang string, confirmWindow time.Duration, examples []llm.Example, rlExamples int) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	var req QueryRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	lang, err := responseLanguage(r, defaultLang)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	log.Printf("Analyzing query: %s", req.Query)
+	countQuery("query")
+	confirmed := r.URL.Query().Get("confirmed") == "1"
+	var trace *QueryTrace
+	if r.URL.Query().Get("trace") == "1" {
+		trace = newQueryTrace(req.Query)
+	}
 
 	var sqlQuery string
 	var results string
-	var err error
+	var structured *QueryResults
 
 	// Retry loop for SQL generation and execution (up to 3 attempts)
+	// What worked on this machine before is the most relevant guidance, so
+	// it goes ahead of the configured examples.
+	examples = append(learnedExamples(rlDB, rlExamples), examples...)
+
 	maxRetries := 3
+	attempts := 0
+	fromCache := false
+	// source is where the query being run came from; see TraceAttempt.Source.
+	var source string
 	for attempt := 1; attempt <= maxRetries; attempt++ {
-		sqlQuery, err = client.GenerateSQL(req.Query)
+		fromCache = false
+		source = rl.OriginLLM
+		if attempt == 1 && confirmed && req.SQL != "" {
+			sqlQuery, err, source = req.SQL, nil, "confirmed"
+		} else if canned, ok := cannedCounterQuery(req.Query); ok && attempt == 1 && req.Hint == nil {
+			log.Printf("Using canned counter query: %s", canned)
+			sqlQuery, err, source = canned, nil, "canned"
+		} else if canned, ok := cannedHostQuery(req.Query); ok && attempt == 1 && req.Hint == nil {
+			log.Printf("Using canned host comparison query: %s", canned)
+			sqlQuery, err, source = canned, nil, "canned"
+		} else if cached, ok := translations.get(req.Query); ok && attempt == 1 && req.Hint == nil {
+			log.Printf("Using cached translation: %s", cached)
+			sqlQuery, err, fromCache, source = cached, nil, true, rl.OriginCache
+		} else {
+			prompt := llm.WithExamples(llm.WithHint(req.Query, req.Hint), examples)
+			sqlQuery, err = await(r.Context(), func() (string, error) { return client.GenerateSQL(prompt) })
+			if normalized := llm.NormalizeUnits(sqlQuery, collector.MetricUnits); err == nil && normalized != sqlQuery {
+				log.Printf("Attempt %d: Converted thresholds to stored units: %s -> %s", attempt, sqlQuery, normalized)
+				sqlQuery = normalized
+			}
+		}
+		if r.Context().Err() != nil {
+			log.Printf("Attempt %d: Query cancelled", attempt)
+			countQueryError("query", "cancelled")
+			respondQueryError(w, nil, "Query cancelled", 0, trace)
+			return
+		}
 		if err != nil {
 			log.Printf("Attempt %d: Failed to generate MetricsQL: %v", attempt, err)
-			if attempt == maxRetries {
-				id, _ := rlDB.LogExperience("query", req.Query, "", fmt.Sprintf("Failed to generate SQL: %v", err))
-				respondError(w, fmt.Sprintf("Failed to generate MetricsQL after %d attempts: %v", maxRetries, err), id)
+			trace.attempt(source, "", err)
+			if attempt == maxRetries || errors.Is(err, llm.ErrProviderUnavailable) {
+				recordQueryFinalFailure(database, "generate")
+				countQueryError("query", "generate")
+				id, _ := rlDB.LogExperienceFrom("query", source, req.Query, "", fmt.Sprintf("Failed to generate SQL: %v", err))
+				autoRate(rlDB, id, source, false)
+				respondQueryError(w, nil, fmt.Sprintf("Failed to generate MetricsQL after %d attempts: %v", attempt, err), id, trace)
 				return
 			}
 			continue
 		}
 
+		if window := queryWindow(sqlQuery); !confirmed && confirmWindow > 0 && window > confirmWindow {
+			log.Printf("Attempt %d: Query spans %s (limit %s), asking for confirmation: %s", attempt, window, confirmWindow, sqlQuery)
+			respondJSON(w, QueryResponse{
+				Answer:               fmt.Sprintf("This query scans %s of data, more than the %s confirmation threshold. Resubmit with ?confirmed=1 to run it.", window, confirmWindow),
+				RequiresConfirmation: true,
+				Query:                sqlQuery,
+			})
+			return
+		}
+
 		log.Printf("Attempt %d: Executing Query: %s", attempt, sqlQuery)
 
-		if strings.HasPrefix(strings.ToUpper(sqlQuery), "LOG:") {
-			query := strings.TrimSpace(sqlQuery[4:])
-			results, err = database.QueryLogs(query)
-		} else {
-			// Default to Metrics or explicit METRIC: prefix
-			// If stripping METRIC: returned the same string, it might not have had the prefix or was already cleaned.
-			// However, since GenerateSQL is supposed to return the prefix, we should be careful.
-			// Let's use a more robust trim.
-			actualQuery := sqlQuery
-			if strings.HasPrefix(strings.ToUpper(actualQuery), "METRIC:") {
-				actualQuery = strings.TrimSpace(actualQuery[7:])
-			}
-			results, err = database.QueryMetrics(actualQuery)
+		results, structured, err = executeQuery(r.Context(), database, sqlQuery)
+		trace.attempt(source, sqlQuery, err)
+		if r.Context().Err() != nil {
+			log.Printf("Attempt %d: Query cancelled", attempt)
+			countQueryError("query", "cancelled")
+			respondQueryError(w, nil, "Query cancelled", 0, trace)
+			return
 		}
 
 		if err != nil {
 			log.Printf("Attempt %d: Query Execution Error: %v", attempt, err)
 
 			// Autonomous Self-Correction Logging: Log the failed query
-			rlDB.LogExperience("query", req.Query, sqlQuery, fmt.Sprintf("Execution Error: %v", err))
+			rlDB.LogExperienceFrom("query", source, req.Query, sqlQuery, fmt.Sprintf("Execution Error: %v", err))
+			if fromCache {
+				translations.forget(req.Query)
+			}
 
-			if attempt == maxRetries {
-				id, _ := rlDB.LogExperience("query", req.Query, sqlQuery, fmt.Sprintf("Final Execution Error: %v", err))
-				respondError(w, fmt.Sprintf("Failed to execute query after %d attempts: %v", maxRetries, err), id)
+			// Only a rejected or timed-out query is worth regenerating; if the
+			// database itself is down, another query won't help.
+			if attempt == maxRetries || !errors.Is(err, db.ErrBadQuery) {
+				recordQueryFinalFailure(database, "execute")
+				countQueryError("query", "execute")
+				id, _ := rlDB.LogExperienceFrom("query", source, req.Query, sqlQuery, fmt.Sprintf("Final Execution Error: %v", err))
+				autoRate(rlDB, id, source, false)
+				respondQueryError(w, nil, fmt.Sprintf("Failed to execute query after %d attempts: %v", attempt, err), id, trace)
 				return
 			}
 			continue
 		}
 		log.Printf("Attempt %d: Query Executed successfully.", attempt)
 		// Success!
+		attempts = attempt
 		break
 	}
+	recordQueryAttempts(database, attempts)
+	// Only the LLM's own translations are cached: a confirmed query is
+	// whatever the client sent, and must not become the answer for everyone
+	// else who asks the same question. A hint steers generation for this
+	// one request, so its translation isn't reused for the bare question.
+	if source == rl.OriginLLM && req.Hint == nil {
+		translations.put(req.Query, sqlQuery)
+	}
+
+	// LogsQL name matches are exact, so "logs from chrome" often comes back
+	// empty. Fall back to a fuzzy process-name filter for that question shape.
+	if isEmptyResult(results) {
+		if canned, ok := cannedLogQuery(req.Query); ok && canned != sqlQuery {
+			log.Printf("No data found, trying canned query: %s", canned)
+			cannedResults, cannedStructured, err := executeQuery(r.Context(), database, canned)
+			trace.attempt("fallback", canned, err)
+			if err == nil && !isEmptyResult(cannedResults) {
+				sqlQuery, results, structured, source = canned, cannedResults, cannedStructured, "fallback"
+			}
+		}
+	}
 
 	// Handle empty results before calling ExplainResults
 	results = strings.TrimSpace(results)
-	if results == "" || results == "[]" || results == "{}" || strings.HasPrefix(results, "error") {
+	if isEmptyResult(results) {
 		results = "NO_DATA_FOUND"
 	}
 
-	explanation, err := client.ExplainResults(req.Query, sqlQuery, results)
+	stream := newEventStream(w, r)
+	var explanation string
+	if stream != nil {
+		explanation, err = explainStreaming(r.Context(), stream, client, req.Query, sqlQuery, results, lang)
+	} else {
+		explanation, err = await(r.Context(), func() (string, error) {
+			return client.ExplainResults(req.Query, sqlQuery, results, lang)
+		})
+	}
+	if r.Context().Err() != nil || err != nil {
+		// The query and its results are worth seeing even without an
+		// explanation.
+		trace.finish(sqlQuery, structured, results, "")
+	}
+	if r.Context().Err() != nil {
+		log.Println("Query cancelled while explaining results")
+		countQueryError("query", "cancelled")
+		respondQueryError(w, stream, "Query cancelled", 0, trace)
+		return
+	}
 	if err != nil {
-		id, _ := rlDB.LogExperience("query", req.Query, sqlQuery, fmt.Sprintf("Failed to explain results: %v", err))
-		respondError(w, fmt.Sprintf("Failed to explain results: %v", err), id)
+		countQueryError("query", "explain")
+		id, _ := rlDB.LogExperienceFrom("query", source, req.Query, sqlQuery, fmt.Sprintf("Failed to explain results: %v", err))
+		autoRate(rlDB, id, source, false)
+		respondQueryError(w, stream, fmt.Sprintf("Failed to explain results: %v", err), id, trace)
 		return
 	}
 
 	// Log successful experience
-	id, _ := rlDB.LogExperience("query", req.Query, sqlQuery, "Success")
+	id, _ := rlDB.LogExperienceFrom("query", source, req.Query, sqlQuery, "Success")
+	autoRate(rlDB, id, source, attempts == 1)
 	log.Println("Query analysis finished.")
-	respondJSON(w, QueryResponse{InteractionID: id, Answer: explanation})
+	resp := QueryResponse{InteractionID: id, Answer: explanation}
+	if r.URL.Query().Get("include_results") == "1" {
+		resp.Results = structured
+	}
+	if r.URL.Query().Get("verbose") == "1" {
+		resp.Attempts = attempts
+	}
+	if trace != nil {
+		trace.finish(sqlQuery, structured, results, explanation)
+		resp.Trace = trace
+	}
+	answers.add("query", req.Query, resp)
+	respondQuery(w, stream, resp)
+}
+
+// checkSchemaDrift warns when the provider's prompt and the collectors disagree
+// about which metrics exist, so a new metric can't silently go unadvertised.
+func checkSchemaDrift(provider llm.Provider) (missing, unknown []string) {
+	d, ok := provider.(llm.SchemaDescriber)
+	if !ok {
+		return nil, nil
+	}
+	missing, unknown = llm.SchemaDrift(d.SchemaPrompt(), collector.MetricNames)
+	if len(missing) > 0 {
+		log.Printf("Warning: schema drift: collected metrics not described to the LLM: %s", strings.Join(missing, ", "))
+	}
+	if len(unknown) > 0 {
+		log.Printf("Warning: schema drift: LLM prompt mentions metrics no collector emits: %s", strings.Join(unknown, ", "))
+	}
+	return missing, unknown
+}
+
+// maxLanguageLength caps a ?lang= override, which goes into the prompt.
+const maxLanguageLength = 32
+
+// responseLanguage returns the ?lang= override for this request, falling back
+// to the configured default. Only explanations are localized; the generated
+// query stays language-neutral. The override ends up in the system prompt,
+// so it may only be a language name: letters, spaces and hyphens, up to
+// maxLanguageLength characters.
+func responseLanguage(r *http.Request, defaultLang string) (string, error) {
+	lang := strings.TrimSpace(r.URL.Query().Get("lang"))
+	if lang == "" {
+		return defaultLang, nil
+	}
+	if utf8.RuneCountInString(lang) > maxLanguageLength {
+		return "", fmt.Errorf("lang must be at most %d characters", maxLanguageLength)
+	}
+	for _, c := range lang {
+		if !unicode.IsLetter(c) && c != ' ' && c != '-' {
+			return "", fmt.Errorf("lang may only contain letters, spaces and hyphens")
+		}
+	}
+	return lang, nil
+}
+
+// queryWindow estimates how far back a generated METRIC:/RANGE:/LOG: query
+// reaches. QueryLogs always ANDs in _time:24h, so a log query never scans
+// more than that; a range query reaches its own window plus any range
+// selector inside it.
+func queryWindow(sqlQuery string) time.Duration {
+	upper := strings.ToUpper(sqlQuery)
+	if strings.HasPrefix(upper, "LOG:") {
+		if w := db.QueryWindow(sqlQuery[4:]); w > 0 && w < 24*time.Hour {
+			return w
+		}
+		return 24 * time.Hour
+	}
+	if strings.HasPrefix(upper, "RANGE:") {
+		window, query := parseRangeQuery(sqlQuery[6:])
+		return window + db.QueryWindow(query)
+	}
+	return db.QueryWindow(sqlQuery)
+}
+
+// executeQuery runs a generated METRIC:/RANGE:/LOG: query against the
+// matching backend and returns both the LLM-readable text and the typed rows.
+// A RANGE: query covers its window up to now, at db.RangeStep's resolution.
+func executeQuery(ctx context.Context, database *db.VictoriaDB, sqlQuery string) (string, *QueryResults, error) {
+	upper := strings.ToUpper(sqlQuery)
+	if strings.HasPrefix(upper, "LOG:") {
+		query := strings.TrimSpace(sqlQuery[4:])
+		logs, err := database.QueryLogsResultsContext(ctx, query)
+		if err != nil {
+			return "", nil, err
+		}
+		return db.FormatLogResults(logs), &QueryResults{Type: "log", Query: query, Logs: logs}, nil
+	}
+	if strings.HasPrefix(upper, "RANGE:") {
+		window, query := parseRangeQuery(sqlQuery[6:])
+		if query == "" {
+			return "", nil, fmt.Errorf("%w: RANGE: query has a window but no MetricsQL", db.ErrBadQuery)
+		}
+		end := time.Now()
+		series, err := database.QueryMetricsRangeContext(ctx, query, end.Add(-window), end, db.RangeStep(window))
+		if err != nil {
+			return "", nil, err
+		}
+		return db.FormatRangeResults(series), &QueryResults{Type: "range", Query: query, Series: series}, nil
+	}
+
+	// Default to Metrics or explicit METRIC: prefix
+	query := sqlQuery
+	if strings.HasPrefix(strings.ToUpper(query), "METRIC:") {
+		query = strings.TrimSpace(query[7:])
+	}
+	metrics, err := database.QueryMetricsResultsContext(ctx, query)
+	if err != nil {
+		return "", nil, err
+	}
+	return db.FormatMetricResults(metrics), &QueryResults{Type: "metric", Query: query, Metrics: metrics}, nil
+}
+
+// isEmptyResult reports whether a query result carries no usable data.
+func isEmptyResult(results string) bool {
+	results = strings.TrimSpace(results)
+	return results == "" || results == "[]" || results == "{}" || strings.HasPrefix(results, "error")
+}
+
+// logsFromPattern matches questions like "show me logs from Google Chrome".
+var logsFromPattern = regexp.MustCompile(`(?i)\blogs?\s+(?:from|for|of|by)\s+(.+)`)
+
+// cannedLogQuery returns a fuzzy processName query for "logs from <app>"
+// questions, stopping the app name at trailing words like "app" or "in the
+// last hour".
+func cannedLogQuery(question string) (string, bool) {
+	m := logsFromPattern.FindStringSubmatch(question)
+	if m == nil {
+		return "", false
+	}
+
+	stopWords := map[string]bool{
+		"in": true, "over": true, "during": true, "since": true, "within": true,
+		"today": true, "yesterday": true, "recently": true, "last": true, "past": true,
+		"app": true, "application": true, "process": true, "service": true,
+		"with": true, "that": true, "containing": true,
+	}
+
+	var words []string
+	for _, word := range strings.Fields(m[1]) {
+		word = strings.Trim(word, `"'?!.,`)
+		lower := strings.ToLower(word)
+		if lower == "the" && len(words) == 0 {
+			continue
+		}
+		if stopWords[lower] || word == "" {
+			break
+		}
+		words = append(words, word)
+	}
+	if len(words) == 0 {
+		return "", false
+	}
+
+	return "LOG:" + db.ProcessNameFilter(strings.Join(words, " ")), true
+}
+
+// lastWindowPattern matches "in the last 3 hours", "past day", "last 30 min".
+var lastWindowPattern = regexp.MustCompile(`(?i)\b(?:last|past)\s+(\d+)?\s*(min(?:ute)?s?|h(?:ou)?rs?|days?|weeks?)\b`)
+
+// questionWindow turns "in the last 3 hours" in question into a MetricsQL
+// window such as "3h".
+func questionWindow(question string) (string, bool) {
+	m := lastWindowPattern.FindStringSubmatch(question)
+	if m == nil {
+		return "", false
+	}
+
+	n := m[1]
+	if n == "" {
+		n = "1"
+	}
+	unit := strings.ToLower(m[2])
+	switch {
+	case strings.HasPrefix(unit, "m"):
+		unit = "m"
+	case strings.HasPrefix(unit, "h"):
+		unit = "h"
+	case strings.HasPrefix(unit, "d"):
+		unit = "d"
+	default:
+		unit = "w"
+	}
+	return n + unit, true
+}
+
+var (
+	// networkWordPattern matches questions about network traffic volume.
+	networkWordPattern = regexp.MustCompile(`(?i)\b(?:network|bytes|traffic|upload(?:ed)?|download(?:ed)?|sent|received)\b`)
+	// totalPattern matches asking for an overall amount: "how much",
+	// "how many bytes", "total".
+	totalPattern = regexp.MustCompile(`(?i)\b(?:how\s+(?:much|many)|total)\b`)
+	// breakdownPattern matches asking for a ranking or a split by process,
+	// app, or host, which a fleet-wide sum doesn't answer.
+	breakdownPattern = regexp.MustCompile(`(?i)\b(?:which|who|top|most|least|highest|lowest|biggest|largest|per|by|each|every|breakdown|process(?:es)?|apps?|applications?|programs?|hosts?|interfaces?|graph|chart|plot|trend)\b`)
+	// nonNetworkBytesPattern matches byte counts that aren't network traffic.
+	nonNetworkBytesPattern = regexp.MustCompile(`(?i)\b(?:disk|memory|ram|files?|written|writes?|read|swap)\b`)
+)
+
+// cannedCounterQuery answers "how many network bytes in the last N hours"
+// deterministically. The network metrics are cumulative counters, and models
+// tend to return the raw all-time total instead of increase() over the
+// window the user asked about. Only questions after the overall total
+// match; "which process sent the most traffic" and the like go to the LLM.
+func cannedCounterQuery(question string) (string, bool) {
+	window, ok := questionWindow(question)
+	if !ok || !networkWordPattern.MatchString(question) || !totalPattern.MatchString(question) {
+		return "", false
+	}
+	if breakdownPattern.MatchString(question) || nonNetworkBytesPattern.MatchString(question) {
+		return "", false
+	}
+
+	lower := strings.ToLower(question)
+	wantSent := strings.Contains(lower, "sent") || strings.Contains(lower, "send") || strings.Contains(lower, "upload")
+	wantReceived := strings.Contains(lower, "receiv") || strings.Contains(lower, "download")
+	if !wantSent && !wantReceived {
+		wantSent, wantReceived = true, true
+	}
+
+	var parts []string
+	for _, name := range collector.CounterNames {
+		if !strings.Contains(name, "network") {
+			continue
+		}
+		direction := "received"
+		if strings.Contains(name, "sent") {
+			direction = "sent"
+		}
+		if (direction == "sent" && !wantSent) || (direction == "received" && !wantReceived) {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf(`label_set(sum(increase(%s[%s])), "direction", "%s")`, name, window, direction))
+	}
+	if len(parts) == 0 {
+		return "", false
+	}
+	return "METRIC:" + strings.Join(parts, " or "), true
 }
 
 func respondJSON(w http.ResponseWriter, resp interface{}) {
@@ -411,42 +1198,96 @@ func respondError(w http.ResponseWriter, msg string, id int64) {
 	respondJSON(w, QueryResponse{InteractionID: id, Error: msg})
 }
 
-func handleRecommend(w http.ResponseWriter, r *http.Request, database *db.VictoriaDB, client llm.Provider, rlDB *rl.DB) {
+// recommendQuery is an extra metric added to the /recommend system data.
+type recommendQuery struct {
+	Title string
+	Query string
+}
+
+// recommendExtraQueries builds a query for each of names with a default
+// aggregation: the last hour's increase for counters, the top 5 series for
+// per-process and per-app metrics, and the average otherwise. Names missing
+// from the schema are logged and skipped.
+func recommendExtraQueries(names []string) []recommendQuery {
+	var queries []recommendQuery
+	for _, name := range names {
+		m, ok := collector.LookupMetric(name)
+		if !ok {
+			log.Printf("Ignoring unknown metric '%s' in recommend_extra_metrics", name)
+			continue
+		}
+		unit := ""
+		if m.Unit != "" && m.Unit != "count" && m.Unit != "boolean" {
+			unit = " (" + m.Unit + ")"
+		}
+		switch {
+		case m.Counter:
+			queries = append(queries, recommendQuery{fmt.Sprintf("Top 5 %s%s over the last hour", name, unit), fmt.Sprintf("topk(5, increase(%s[1h]))", name)})
+		case strings.HasPrefix(name, "process_") || strings.HasPrefix(name, "srum_app_"):
+			queries = append(queries, recommendQuery{fmt.Sprintf("Top 5 %s%s", name, unit), fmt.Sprintf("topk(5, %s)", name)})
+		default:
+			queries = append(queries, recommendQuery{fmt.Sprintf("Avg %s%s", name, unit), fmt.Sprintf("avg(%s)", name)})
+		}
+	}
+	return queries
+}
+
+// insufficientDataAnswer is what /recommend answers instead of
+// recommendations that carry no usable advice.
+const insufficientDataAnswer = "Insufficient data for recommendations: the collected metrics and logs don't point to anything specific yet. Try again after a few more collection cycles."
+
+func handleRecommend(w http.ResponseWriter, r *http.Request, database *db.VictoriaDB, client llm.Provider, rlDB *rl.DB, defaultLang string, extraQueries []recommendQuery, fallback string) {
 	if r.Method != http.MethodGet && r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	lang, err := responseLanguage(r, defaultLang)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	log.Println("Generating recommendations...")
+	countQuery("recommend")
 
 	var systemDataBuilder strings.Builder
 
+	// Core count lets the LLM interpret per-core process_cpu_pct values above 100
+	systemDataBuilder.WriteString(fmt.Sprintf("Logical CPU Cores: %d (process_cpu_pct is per-core and can exceed 100)\n", runtime.NumCPU()))
+
 	// CPU
-	cpuRes, err := database.QueryMetrics("avg(cpu_usage_pct)")
+	cpuRes, err := database.QueryMetricsContext(r.Context(), "avg(cpu_usage_pct)")
 	if err == nil {
 		systemDataBuilder.WriteString(fmt.Sprintf("Global Avg CPU: %s\n", cpuRes))
 	}
 
 	// Memory
-	memRes, err := database.QueryMetrics("avg(memory_used_mb)")
+	memRes, err := database.QueryMetricsContext(r.Context(), "avg(memory_used_mb)")
 	if err == nil {
 		systemDataBuilder.WriteString(fmt.Sprintf("Global Avg Memory Used (MB): %s\n", memRes))
 	}
 
 	// Top Processes by CPU
-	topCPU, err := database.QueryMetrics("topk(5, process_cpu_pct)")
+	topCPU, err := database.QueryMetricsContext(r.Context(), "topk(5, process_cpu_pct)")
 	if err == nil {
 		systemDataBuilder.WriteString(fmt.Sprintf("Top 5 Processes by CPU:\n%s\n", topCPU))
 	}
 
 	// Top Processes by Memory
-	topMem, err := database.QueryMetrics("topk(5, process_memory_mb)")
+	topMem, err := database.QueryMetricsContext(r.Context(), "topk(5, process_memory_mb)")
 	if err == nil {
 		systemDataBuilder.WriteString(fmt.Sprintf("Top 5 Processes by Memory:\n%s\n", topMem))
 	}
 
+	for _, q := range extraQueries {
+		res, err := database.QueryMetricsContext(r.Context(), q.Query)
+		if err == nil {
+			systemDataBuilder.WriteString(fmt.Sprintf("%s:\n%s\n", q.Title, res))
+		}
+	}
+
 	// Recent Error Logs
-	errLogs, err := database.QueryLogs(`* | filter eventMessage: "error" OR messageType: "error" | limit 10`)
+	errLogs, err := database.QueryLogsContext(r.Context(), `* | filter eventMessage: "error" OR messageType: "error" | limit 10`)
 	if err == nil {
 		systemDataBuilder.WriteString(fmt.Sprintf("Recent Error Logs:\n%s\n", errLogs))
 	}
@@ -454,16 +1295,68 @@ func handleRecommend(w http.ResponseWriter, r *http.Request, database *db.Victor
 	systemData := systemDataBuilder.String()
 	log.Printf("System Data for Recommendations:\n%s", systemData)
 
-	recommendations, err := client.GenerateRecommendations(systemData)
-	if err != nil {
+	failed := func(err error) {
+		countQueryError("recommend", "generate")
 		id, _ := rlDB.LogExperience("recommend", "Generate system recommendations", "", fmt.Sprintf("Failed to generate recommendations: %v", err))
 		respondError(w, fmt.Sprintf("Failed to generate recommendations: %v", err), id)
+	}
+	recommendations, err := client.GenerateRecommendations(systemData, lang)
+	if err != nil {
+		failed(err)
 		return
 	}
 
+	// Sparse data often gets an empty or boilerplate answer; don't present
+	// it as advice.
+	if low, reason := llm.LowInformation(recommendations); low && fallback != "off" {
+		log.Printf("Recommendations carry no usable advice (%s)", reason)
+		if fallback != "message" {
+			rlDB.LogExperience("recommend", "Generate system recommendations", "", fmt.Sprintf("Low-information response: %s", reason))
+			log.Println("Retrying recommendations with a stronger prompt...")
+			if recommendations, err = client.GenerateRecommendations(llm.StrongerRecommendPrompt(systemData), lang); err != nil {
+				failed(err)
+				return
+			}
+			low, reason = llm.LowInformation(recommendations)
+		}
+		if low {
+			countQueryError("recommend", "insufficient_data")
+			id, _ := rlDB.LogExperience("recommend", "Generate system recommendations", "", fmt.Sprintf("Insufficient data: %s", reason))
+			resp := QueryResponse{InteractionID: id, Answer: insufficientDataAnswer}
+			answers.add("recommend", "", resp)
+			respondJSON(w, resp)
+			return
+		}
+	}
+
 	id, _ := rlDB.LogExperience("recommend", "Generate system recommendations", "", "Success")
 	log.Println("Recommendations generated successfully.")
-	respondJSON(w, QueryResponse{InteractionID: id, Answer: recommendations})
+	resp := QueryResponse{InteractionID: id, Answer: recommendations}
+	answers.add("recommend", "", resp)
+	respondJSON(w, resp)
+}
+
+// autoFeedback rates /query interactions from how they went, per
+// auto_feedback_on_success.
+var autoFeedback bool
+
+// autoRate gives interaction id weak-positive feedback if good, and
+// weak-negative otherwise, when auto_feedback_on_success is on. Only
+// queries the LLM generated (source "llm", or "cache" for an earlier LLM
+// translation) are rated, so the automatic ratings describe the model.
+// Whether a row can become a few-shot example is decided by its origin,
+// which handleQuery records; see rl.GetSuccessfulExamples.
+func autoRate(rlDB *rl.DB, id int64, source string, good bool) {
+	if !autoFeedback || id == 0 || (source != rl.OriginLLM && source != rl.OriginCache) {
+		return
+	}
+	feedback := rl.FeedbackAutoBad
+	if good {
+		feedback = rl.FeedbackAutoGood
+	}
+	if err := rlDB.SetAutoFeedback(id, feedback); err != nil {
+		log.Printf("Failed to record automatic feedback for interaction %d: %v", id, err)
+	}
 }
 
 // FeedbackRequest defines the payload for submitting RL feedback.
@@ -474,21 +1367,95 @@ type FeedbackRequest struct {
 
 func handleFeedback(w http.ResponseWriter, r *http.Request, rlDB *rl.DB) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	var req FeedbackRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Feedback != 1 && req.Feedback != -1 {
+		writeError(w, http.StatusBadRequest, "Feedback must be 1 (good) or -1 (bad)")
 		return
 	}
 
-	if err := rlDB.UpdateFeedback(req.InteractionID, req.Feedback); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to update feedback: %v", err), http.StatusInternalServerError)
+	err := rlDB.UpdateFeedback(req.InteractionID, req.Feedback)
+	if errors.Is(err, rl.ErrNotFound) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Unknown interaction ID %d", req.InteractionID))
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to update feedback: %v", err))
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(`{"status": "ok"}`))
 }
+
+// handleMetrics serves Zenith's own metrics in Prometheus text format.
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	telemetry.Default.WritePrometheus(w)
+}
+
+// handleCollectedMetrics serves the latest collected value of every series
+// in OpenMetrics format, for Prometheus to scrape.
+func handleCollectedMetrics(w http.ResponseWriter, r *http.Request, latest *db.LatestSamples) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+	latest.WriteOpenMetrics(w)
+}
+
+// HealthzResponse reports the liveness of the server, its collection
+// scheduler and LLM circuit breaker, and the disk footprint of the embedded
+// databases.
+type HealthzResponse struct {
+	Status             string    `json:"status"` // "ok" or "degraded"
+	LLMBreaker         string    `json:"llm_breaker"`
+	SchedulerHeartbeat time.Time `json:"scheduler_heartbeat"`
+	SchedulerRestarts  int64     `json:"scheduler_restarts"`
+	VMDataBytes        int64     `json:"vm_data_bytes"`
+	VLogsDataBytes     int64     `json:"vlogs_data_bytes"`
+	Warnings           []string  `json:"warnings,omitempty"`
+}
+
+func handleHealthz(w http.ResponseWriter, r *http.Request, breaker *llm.Breaker, usage *DataDiskUsage, warnBytes int64, scheduler *schedulerWatchdog) {
+	resp := HealthzResponse{Status: "ok"}
+	resp.SchedulerHeartbeat = scheduler.lastHeartbeat()
+	resp.SchedulerRestarts = scheduler.restarts.Load()
+	if since := time.Since(resp.SchedulerHeartbeat); since > scheduler.stallAfter() {
+		resp.Warnings = append(resp.Warnings, fmt.Sprintf("Collection scheduler has not reported in for %s", since.Round(time.Second)))
+	}
+	if breaker == nil {
+		resp.LLMBreaker = "not configured"
+		resp.Warnings = append(resp.Warnings, "LLM provider not configured (collection-only mode)")
+	} else {
+		resp.LLMBreaker = breaker.State().String()
+		if breaker.State() != llm.BreakerClosed {
+			resp.Warnings = append(resp.Warnings, "LLM circuit breaker is "+breaker.State().String())
+		}
+	}
+
+	resp.VMDataBytes, resp.VLogsDataBytes = usage.snapshot()
+	if warnBytes > 0 {
+		if resp.VMDataBytes > warnBytes {
+			resp.Warnings = append(resp.Warnings, fmt.Sprintf("VictoriaMetrics data exceeds %d bytes", warnBytes))
+		}
+		if resp.VLogsDataBytes > warnBytes {
+			resp.Warnings = append(resp.Warnings, fmt.Sprintf("VictoriaLogs data exceeds %d bytes", warnBytes))
+		}
+	}
+
+	if len(resp.Warnings) > 0 {
+		resp.Status = "degraded"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
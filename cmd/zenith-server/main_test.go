@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCannedCounterQuery(t *testing.T) {
+	match := []string{
+		"how much network traffic in the last hour",
+		"How many bytes were received in the past day?",
+		"total bytes sent in the last 3 hours",
+		"what was the total network usage over the last 30 minutes",
+		"how much did I download in the last week",
+	}
+	for _, q := range match {
+		if _, ok := cannedCounterQuery(q); !ok {
+			t.Errorf("Expected %q to get the canned total", q)
+		}
+	}
+
+	noMatch := []string{
+		"which process sent the most network traffic in the last hour",
+		"top apps by network traffic in the last day",
+		"how much network traffic per app in the last hour",
+		"how much network traffic did Safari use in the last hour by process",
+		"which host received the most bytes in the last hour",
+		"how many bytes were written to disk in the last hour",
+		"graph total network traffic over the last hour",
+		"network traffic in the last hour",
+		"how much network traffic today",
+	}
+	for _, q := range noMatch {
+		if got, ok := cannedCounterQuery(q); ok {
+			t.Errorf("Expected %q to go to the LLM, got %s", q, got)
+		}
+	}
+
+	got, _ := cannedCounterQuery("total bytes sent in the last 3 hours")
+	if !strings.Contains(got, "increase(srum_network_bytes_sent_total[3h])") || strings.Contains(got, "received") {
+		t.Errorf("Expected the sent counter's increase over 3h, got %s", got)
+	}
+}
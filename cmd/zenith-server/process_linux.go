@@ -0,0 +1,11 @@
+//go:build linux
+
+package main
+
+import "syscall"
+
+// setParentDeathSignal makes the kernel SIGKILL the child when the server
+// exits for any reason, including being SIGKILLed itself.
+func setParentDeathSignal(attr *syscall.SysProcAttr) {
+	attr.Pdeathsig = syscall.SIGKILL
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !windows
+
+package main
+
+import "syscall"
+
+// setParentDeathSignal is a no-op where there is no parent-death signal
+// (e.g. macOS); orphans left by a crash are cleaned up by reapStaleChild on
+// the next start instead.
+func setParentDeathSignal(attr *syscall.SysProcAttr) {}
@@ -0,0 +1,26 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// configureChild puts a managed subprocess in its own process group, so a
+// terminal's Ctrl-C doesn't race our orderly shutdown, and (on Linux) asks the
+// kernel to kill it if the server dies.
+func configureChild(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	setParentDeathSignal(cmd.SysProcAttr)
+}
+
+// adoptChild ties a started subprocess's lifetime to the server's. On unix
+// this is handled by configureChild and the stale-PID reaper.
+func adoptChild(cmd *exec.Cmd) error { return nil }
+
+// interruptProcess asks a managed subprocess to shut down gracefully.
+func interruptProcess(p *os.Process) error {
+	return p.Signal(syscall.SIGTERM)
+}
@@ -0,0 +1,63 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// configureChild starts managed subprocesses in their own process group so
+// they can be sent CTRL_BREAK without it reaching the server itself.
+func configureChild(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+var (
+	jobOnce sync.Once
+	job     windows.Handle
+	jobErr  error
+)
+
+// childJob returns a job object that kills every process assigned to it
+// when its last handle closes, which happens when the server exits or dies.
+func childJob() (windows.Handle, error) {
+	jobOnce.Do(func() {
+		job, jobErr = windows.CreateJobObject(nil, nil)
+		if jobErr != nil {
+			return
+		}
+		info := windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION{}
+		info.BasicLimitInformation.LimitFlags = windows.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE
+		_, jobErr = windows.SetInformationJobObject(job, windows.JobObjectExtendedLimitInformation,
+			uintptr(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info)))
+	})
+	return job, jobErr
+}
+
+// adoptChild assigns a started subprocess to the server's kill-on-close job
+// object so it can't outlive the server.
+func adoptChild(cmd *exec.Cmd) error {
+	j, err := childJob()
+	if err != nil {
+		return fmt.Errorf("failed to create job object: %v", err)
+	}
+	h, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, uint32(cmd.Process.Pid))
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(h)
+	return windows.AssignProcessToJobObject(j, h)
+}
+
+// interruptProcess sends CTRL_BREAK to the subprocess's process group, the
+// closest Windows has to SIGTERM for console programs.
+func interruptProcess(p *os.Process) error {
+	return windows.GenerateConsoleCtrlEvent(windows.CTRL_BREAK_EVENT, uint32(p.Pid))
+}
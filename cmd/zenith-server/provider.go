@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os/exec"
+	"time"
+
+	"zenith/pkg/config"
+	"zenith/pkg/gemini"
+	"zenith/pkg/llamacpp"
+	"zenith/pkg/llm"
+	"zenith/pkg/ollama"
+)
+
+// llamaStartupTimeout bounds how long we wait for llama-server to load its
+// model before giving up on the provider.
+const llamaStartupTimeout = 60 * time.Second
+
+// providerOptions holds the effective (flag-overridden) provider settings.
+type providerOptions struct {
+	Name        string
+	APIKey      string
+	OllamaModel string
+	LlamaBin    string
+	LlamaModel  string
+}
+
+// initProvider validates the configuration for the chosen provider, starts
+// its backend if Zenith manages one, and checks that it is reachable. It
+// returns the llama-server process (if started) so the caller can stop it,
+// even when initialization ultimately fails.
+func initProvider(ctx context.Context, cfg *config.Config, opts providerOptions) (llm.Provider, *exec.Cmd, error) {
+	log.Printf("Initializing LLM Provider: %s", opts.Name)
+	temps := llm.Temperatures{
+		GenerateSQL: float32(cfg.GenerateSQLTemperature),
+		Explain:     float32(cfg.ExplainTemperature),
+		Recommend:   float32(cfg.RecommendTemperature),
+	}
+	log.Printf("LLM temperatures: generate_sql=%.2f explain=%.2f recommend=%.2f", temps.GenerateSQL, temps.Explain, temps.Recommend)
+	switch opts.Name {
+	case "gemini":
+		if opts.APIKey == "" {
+			return nil, nil, fmt.Errorf("gemini requires an API key (set gemini_api_key, GEMINI_API_KEY, or --key)")
+		}
+		client, err := gemini.NewClient(ctx, opts.APIKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create gemini client: %v", err)
+		}
+		client.Temperatures = temps
+		log.Println("Using Gemini Provider")
+		return client, nil, nil
+
+	case "ollama":
+		ollamaURL := fmt.Sprintf("http://%s:%d", cfg.OllamaHost, cfg.OllamaPort)
+		client := ollama.NewClient(ollamaURL, opts.OllamaModel)
+		client.Temperatures = temps
+		if err := client.Ping(); err != nil {
+			return nil, nil, err
+		}
+		log.Printf("Using Ollama Provider at %s (Model: %s)", ollamaURL, opts.OllamaModel)
+		return client, nil, nil
+
+	case "llamacpp":
+		log.Printf("Configured Llama.cpp Model: %s", opts.LlamaModel)
+		// Auto-download model if missing
+		if err := llamacpp.EnsureModel(opts.LlamaModel); err != nil {
+			return nil, nil, fmt.Errorf("failed to ensure llama model: %v", err)
+		}
+
+		// Start llama-server process
+		llamaURL := fmt.Sprintf("http://%s:%d", cfg.LlamaCppHost, cfg.LlamaCppPort)
+		log.Printf("Starting llama-server at %s with binary %s", llamaURL, opts.LlamaBin)
+		llamaCmd, err := tryStartProcess(opts.LlamaBin, "-m", opts.LlamaModel, "--host", cfg.LlamaCppHost, "--port", fmt.Sprintf("%d", cfg.LlamaCppPort))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		client := llamacpp.NewClient(llamaURL)
+		client.Temperatures = temps
+		if err := waitReady(client, llamaStartupTimeout); err != nil {
+			return nil, llamaCmd, err
+		}
+		log.Printf("Using Llama.cpp Provider at %s", llamaURL)
+		return client, llamaCmd, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unknown provider %q (expected gemini, ollama, or llamacpp)", opts.Name)
+	}
+}
+
+// waitReady polls p until it answers or timeout elapses.
+func waitReady(p llm.Pinger, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := p.Ping()
+		if err == nil || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// requireProvider wraps an LLM-backed handler so it answers 503 while the
+// server runs in collection-only mode.
+func requireProvider(provider llm.Provider, initErr error, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if provider == nil {
+			writeError(w, http.StatusServiceUnavailable, fmt.Sprintf("%v: %v", llm.ErrProviderNotConfigured, initErr))
+			return
+		}
+		next(w, r)
+	}
+}
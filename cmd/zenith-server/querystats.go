@@ -0,0 +1,107 @@
+package main
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+
+	"zenith/pkg/db"
+	"zenith/pkg/llm"
+	"zenith/pkg/telemetry"
+)
+
+// These track how effective the self-correcting retry loop in handleQuery
+// is: how many attempts successful queries needed, and how often all
+// attempts were exhausted.
+var (
+	queryAttempts      = telemetry.Default.Histogram("zenith_query_attempts", "Generate/execute attempts each successful query took.", []float64{1, 2, 3})
+	queryFinalFailures = telemetry.Default.Counter("zenith_query_final_failures_total", "Queries that failed after the retry loop gave up.")
+
+	finalFailureCount atomic.Int64
+)
+
+// These cover the request and collection paths more broadly, for scraping
+// /internal/metrics.
+var (
+	queriesTotal       = telemetry.Default.Counter("zenith_queries_total", "Requests to /query, /recommend and /report, by endpoint.")
+	queryErrors        = telemetry.Default.Counter("zenith_query_errors_total", "Failed /query, /recommend and /report requests, by endpoint and stage.")
+	llmLatency         = telemetry.Default.Histogram("zenith_llm_latency_seconds", "Time each LLM provider call took, by operation.", []float64{0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120})
+	collectionDuration = telemetry.Default.Histogram("zenith_collection_duration_seconds", "Time each collection cycle took, by kind.", []float64{0.5, 1, 2.5, 5, 10, 30, 60, 300})
+)
+
+var selfLabels = map[string]string{"host": "localhost"}
+
+// countQuery counts a request to endpoint ("query", "recommend", or
+// "report").
+func countQuery(endpoint string) {
+	queriesTotal.Inc(map[string]string{"endpoint": endpoint})
+}
+
+// countQueryError counts a request to endpoint that failed at stage
+// ("generate", "execute", "explain", or "cancelled").
+func countQueryError(endpoint, stage string) {
+	queryErrors.Inc(map[string]string{"endpoint": endpoint, "stage": stage})
+}
+
+// timeCollection records how long a collection cycle of kind ("regular" or
+// "srum") took since start.
+func timeCollection(kind string, start time.Time) {
+	collectionDuration.Observe(map[string]string{"kind": kind}, time.Since(start).Seconds())
+}
+
+// timedProvider records the latency of every call to the wrapped provider.
+// It sits inside the circuit breaker so fast-failed calls aren't counted.
+type timedProvider struct {
+	llm.Provider
+}
+
+func (p timedProvider) observe(op string, start time.Time) {
+	llmLatency.Observe(map[string]string{"op": op}, time.Since(start).Seconds())
+}
+
+func (p timedProvider) GenerateSQL(userQuery string) (string, error) {
+	defer p.observe("generate_sql", time.Now())
+	return p.Provider.GenerateSQL(userQuery)
+}
+
+func (p timedProvider) ExplainResults(userQuery, sql, results, language string) (string, error) {
+	defer p.observe("explain", time.Now())
+	return p.Provider.ExplainResults(userQuery, sql, results, language)
+}
+
+func (p timedProvider) ExplainResultsStream(userQuery, sql, results, language string, onToken func(string)) (string, error) {
+	defer p.observe("explain", time.Now())
+	return llm.ExplainStream(p.Provider, userQuery, sql, results, language, onToken)
+}
+
+func (p timedProvider) GenerateRecommendations(systemData, language string) (string, error) {
+	defer p.observe("recommend", time.Now())
+	return p.Provider.GenerateRecommendations(systemData, language)
+}
+
+// Ping passes through so the breaker can still health-check the backend.
+func (p timedProvider) Ping() error {
+	if pinger, ok := p.Provider.(llm.Pinger); ok {
+		return pinger.Ping()
+	}
+	return nil
+}
+
+// recordQueryAttempts records a successful query's attempt count both on
+// /metrics and in VictoriaMetrics, so it can be charted over time.
+func recordQueryAttempts(database *db.VictoriaDB, attempts int) {
+	queryAttempts.Observe(nil, float64(attempts))
+	if err := database.InsertMetric("zenith_query_attempts", float64(attempts), selfLabels); err != nil {
+		log.Printf("Error recording query attempts: %v", err)
+	}
+}
+
+// recordQueryFinalFailure counts a query the retry loop gave up on. stage is
+// "generate" or "execute".
+func recordQueryFinalFailure(database *db.VictoriaDB, stage string) {
+	queryFinalFailures.Inc(map[string]string{"stage": stage})
+	total := finalFailureCount.Add(1)
+	if err := database.InsertMetric("zenith_query_final_failures_total", float64(total), selfLabels); err != nil {
+		log.Printf("Error recording query failure: %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"zenith/pkg/db"
+)
+
+// defaultRangeWindow is how far back a RANGE: query without a window of its
+// own, or a /query_range request without a start, looks.
+const defaultRangeWindow = time.Hour
+
+// maxRangePoints caps the points per series a /query_range request may ask
+// for, the same limit Prometheus applies.
+const maxRangePoints = 11000
+
+// maxRangeWindow caps how much time a range query may cover: VictoriaMetrics
+// keeps a month by default, so a longer window only makes the backend scan
+// for data it no longer has.
+const maxRangeWindow = 31 * 24 * time.Hour
+
+// parseRangeQuery splits the body of a generated RANGE: query, a window
+// followed by MetricsQL (`1h avg(cpu_usage_pct)`). When the first word isn't
+// a duration the model left the window out, and the whole body is the query
+// over defaultRangeWindow. A window longer than maxRangeWindow is cut down
+// to it.
+func parseRangeQuery(body string) (time.Duration, string) {
+	body = strings.TrimSpace(body)
+	word, rest, _ := strings.Cut(body, " ")
+	if window, err := db.ParseDuration(strings.Trim(word, "[]")); err == nil && window > 0 {
+		return min(window, maxRangeWindow), strings.TrimSpace(rest)
+	}
+	return defaultRangeWindow, body
+}
+
+// rangeStep returns the step for a /query_range request from start to end:
+// the step parameter (a duration or bare seconds, as Prometheus accepts) or
+// db.RangeStep's default. It fails when the step would give more than
+// maxRangePoints points per series.
+func rangeStep(param string, start, end time.Time) (time.Duration, error) {
+	step := db.RangeStep(end.Sub(start))
+	if param != "" {
+		d, err := db.ParseDuration(param)
+		if err != nil {
+			sec, perr := strconv.ParseFloat(param, 64)
+			if perr != nil {
+				return 0, fmt.Errorf("Invalid step '%s'", param)
+			}
+			d = time.Duration(sec * float64(time.Second))
+		}
+		if d <= 0 {
+			return 0, fmt.Errorf("step must be positive")
+		}
+		step = d
+	}
+	if points := end.Sub(start) / step; points > maxRangePoints {
+		return 0, fmt.Errorf("Range of %d points per series exceeds the limit of %d; use a larger step", points, maxRangePoints)
+	}
+	return step, nil
+}
+
+// parseRangeTime reads a /query_range start or end: RFC 3339 or Unix
+// seconds, as the Prometheus API accepts.
+func parseRangeTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	sec, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("'%s' is neither RFC 3339 nor Unix seconds", s)
+	}
+	whole, frac := math.Modf(sec)
+	return time.Unix(int64(whole), int64(frac*1e9)), nil
+}
+
+// handleQueryRange serves GET /query_range?query=...&start=...&end=...&step=...,
+// running MetricsQL as-is over a time range and returning the series.
+// end defaults to now, start to an hour before end, and step to one that
+// gives about 60 points; the range may cover at most maxRangeWindow.
+func handleQueryRange(w http.ResponseWriter, r *http.Request, database *db.VictoriaDB) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	params := r.URL.Query()
+	query := strings.TrimSpace(params.Get("query"))
+	if query == "" {
+		writeError(w, http.StatusBadRequest, "Query is required")
+		return
+	}
+
+	end := time.Now()
+	if s := params.Get("end"); s != "" {
+		t, err := parseRangeTime(s)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid end: %v", err))
+			return
+		}
+		end = t
+	}
+	start := end.Add(-defaultRangeWindow)
+	if s := params.Get("start"); s != "" {
+		t, err := parseRangeTime(s)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid start: %v", err))
+			return
+		}
+		start = t
+	}
+	if !end.After(start) {
+		writeError(w, http.StatusBadRequest, "end must be after start")
+		return
+	}
+	if end.Sub(start) > maxRangeWindow {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Range of %s exceeds the limit of %s", end.Sub(start).Round(time.Second), maxRangeWindow))
+		return
+	}
+
+	step, err := rangeStep(params.Get("step"), start, end)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	log.Printf("Range query over %s (step %s): %s", end.Sub(start).Round(time.Second), step, query)
+	series, err := database.QueryMetricsRangeContext(r.Context(), query, start, end, step)
+	if err != nil {
+		log.Println("Error:", err)
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	respondJSON(w, RawQueryResponse{Results: &QueryResults{Type: "range", Query: query, Series: series}})
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"zenith/pkg/db"
+)
+
+func TestParseRangeQuery(t *testing.T) {
+	tests := []struct {
+		body   string
+		window time.Duration
+		query  string
+	}{
+		{"1h avg(cpu_usage_pct)", time.Hour, "avg(cpu_usage_pct)"},
+		{" [6h]  max(process_memory_mb) by (process_name) ", 6 * time.Hour, "max(process_memory_mb) by (process_name)"},
+		{"avg(cpu_usage_pct)", defaultRangeWindow, "avg(cpu_usage_pct)"},
+		{"sum(rate(x[5m]))", defaultRangeWindow, "sum(rate(x[5m]))"},
+		{"sum(rate(x[5m])) by (host)", defaultRangeWindow, "sum(rate(x[5m])) by (host)"},
+		{"1y avg(cpu_usage_pct)", maxRangeWindow, "avg(cpu_usage_pct)"},
+		{"0s avg(cpu_usage_pct)", defaultRangeWindow, "0s avg(cpu_usage_pct)"},
+		{"2h", 2 * time.Hour, ""},
+		{"", defaultRangeWindow, ""},
+	}
+	for _, tt := range tests {
+		window, query := parseRangeQuery(tt.body)
+		if window != tt.window || query != tt.query {
+			t.Errorf("parseRangeQuery(%q) = %s, %q; want %s, %q", tt.body, window, query, tt.window, tt.query)
+		}
+	}
+}
+
+func TestParseRangeTime(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"2023-11-14T22:13:20Z", time.Unix(1700000000, 0), false},
+		{"2023-11-14T23:13:20+01:00", time.Unix(1700000000, 0), false},
+		{"1700000000", time.Unix(1700000000, 0), false},
+		{"1700000000.5", time.Unix(1700000000, 5e8), false},
+		{"yesterday", time.Time{}, true},
+		{"", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseRangeTime(tt.in)
+		if (err != nil) != tt.wantErr || !got.Equal(tt.want) {
+			t.Errorf("parseRangeTime(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestRangeStep(t *testing.T) {
+	end := time.Unix(1700000000, 0)
+	tests := []struct {
+		param   string
+		window  time.Duration
+		want    time.Duration
+		wantErr string
+	}{
+		{"", time.Hour, time.Minute, ""},
+		{"", 24 * time.Hour, 24 * time.Minute, ""},
+		{"30s", time.Hour, 30 * time.Second, ""},
+		{"15", time.Hour, 15 * time.Second, ""},
+		{"0.5", time.Minute, 500 * time.Millisecond, ""},
+		{"1s", 3 * time.Hour, time.Second, ""},
+		{"1s", 4 * time.Hour, 0, "exceeds the limit"},
+		{"0", time.Hour, 0, "must be positive"},
+		{"-5", time.Hour, 0, "must be positive"},
+		{"soon", time.Hour, 0, "Invalid step"},
+	}
+	for _, tt := range tests {
+		got, err := rangeStep(tt.param, end.Add(-tt.window), end)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("rangeStep(%q, %s) = %s, %v; want error containing %q", tt.param, tt.window, got, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("rangeStep(%q, %s) = %s, %v; want %s", tt.param, tt.window, got, err, tt.want)
+		}
+	}
+}
+
+func TestHandleQueryRange(t *testing.T) {
+	var got url.Values
+	vm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Query()
+		w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"__name__":"cpu_usage_pct"},"values":[[1700000000,"12"]]}]}}`))
+	}))
+	defer vm.Close()
+	database := db.NewVictoriaDB(vm.URL, vm.URL)
+
+	tests := []struct {
+		target string
+		code   int
+		step   string
+	}{
+		{"/query_range?query=cpu_usage_pct&start=1700000000&end=1700003600", http.StatusOK, "60s"},
+		{"/query_range?query=cpu_usage_pct&start=1700000000&end=1700003600&step=10", http.StatusOK, "10s"},
+		{"/query_range?query=cpu_usage_pct&start=1600000000&end=1700000000", http.StatusBadRequest, ""},
+		{"/query_range?query=cpu_usage_pct&start=1700003600&end=1700000000", http.StatusBadRequest, ""},
+		{"/query_range?query=cpu_usage_pct&start=1700000000&end=1700086400&step=1s", http.StatusBadRequest, ""},
+		{"/query_range?start=1700000000", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		got = nil
+		rec := httptest.NewRecorder()
+		handleQueryRange(rec, httptest.NewRequest(http.MethodGet, tt.target, nil), database)
+		if rec.Code != tt.code {
+			t.Errorf("%s: expected %d, got %d: %s", tt.target, tt.code, rec.Code, rec.Body.String())
+			continue
+		}
+		if tt.code != http.StatusOK {
+			if got != nil {
+				t.Errorf("%s: expected the request to be rejected before reaching the backend", tt.target)
+			}
+			continue
+		}
+		if got.Get("step") != tt.step || !strings.Contains(rec.Body.String(), `"type":"range"`) {
+			t.Errorf("%s: expected step %s and range results, got step %q and %s", tt.target, tt.step, got.Get("step"), rec.Body.String())
+		}
+	}
+}
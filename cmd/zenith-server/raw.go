@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"zenith/pkg/db"
+)
+
+// RawQueryRequest is a MetricsQL ("metric") or LogsQL ("log") query to run
+// as-is, without involving the LLM.
+type RawQueryRequest struct {
+	Type  string `json:"type"`
+	Query string `json:"query"`
+}
+
+type RawQueryResponse struct {
+	Results *QueryResults `json:"results,omitempty"`
+}
+
+func handleRawQuery(w http.ResponseWriter, r *http.Request, database *db.VictoriaDB) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req RawQueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	query := strings.TrimSpace(req.Query)
+	if query == "" {
+		writeError(w, http.StatusBadRequest, "Query is required")
+		return
+	}
+
+	var prefixed string
+	switch req.Type {
+	case "metric":
+		prefixed = "METRIC:" + query
+	case "log":
+		prefixed = "LOG:" + query
+	default:
+		writeError(w, http.StatusBadRequest, `Type must be "metric" or "log"`)
+		return
+	}
+
+	log.Printf("Raw %s query: %s", req.Type, query)
+	if req.Type == "log" && wantsNDJSON(r) {
+		if err := streamRawLogs(w, r, database, query); err != nil {
+			log.Println("Error:", err)
+		}
+		return
+	}
+	_, results, err := executeQuery(r.Context(), database, prefixed)
+	if err != nil {
+		log.Println("Error:", err)
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	respondJSON(w, RawQueryResponse{Results: results})
+}
+
+// rawStreamFlushEvery is how many NDJSON lines a streamed log query writes
+// between flushes.
+const rawStreamFlushEvery = 100
+
+// wantsNDJSON reports whether the client asked for a streamed log result
+// with `Accept: application/x-ndjson`.
+func wantsNDJSON(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "application/x-ndjson")
+}
+
+// streamRawLogs writes the entries of a LogsQL query to w as NDJSON, one
+// LogResult per line, as they are decoded from VictoriaLogs, so a large
+// result is never held in memory. Until the first entry is written a
+// failure is an ordinary error response; after that the status is already
+// sent, so it ends the stream with an ErrorResponse line instead.
+func streamRawLogs(w http.ResponseWriter, r *http.Request, database *db.VictoriaDB, query string) error {
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	n := 0
+	err := database.StreamLogsContext(r.Context(), query, func(entry db.LogResult) error {
+		if n == 0 {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+		}
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+		n++
+		if flusher != nil && n%rawStreamFlushEvery == 0 {
+			flusher.Flush()
+		}
+		return nil
+	})
+	switch {
+	case err != nil && n == 0:
+		writeError(w, http.StatusBadGateway, err.Error())
+	case err != nil:
+		enc.Encode(ErrorResponse{Error: ErrorDetail{Code: errorCodes[http.StatusBadGateway], Message: err.Error()}})
+	case n == 0:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+	}
+	if flusher != nil {
+		flusher.Flush()
+	}
+	return err
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// childPIDFile is where the PID of a managed subprocess is recorded, next to
+// its log file, so a crashed server's orphan can be found on the next start.
+func childPIDFile(bin string) string {
+	return strings.TrimSuffix(filepath.Base(bin), filepath.Ext(bin)) + ".pid"
+}
+
+// reapStaleChild kills a subprocess left running by a previous server that
+// died without stopping it, so the new one can bind its port. The recorded
+// PID is only killed if it still belongs to a process of the same name.
+func reapStaleChild(bin string) {
+	pidFile := childPIDFile(bin)
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		return
+	}
+	defer os.Remove(pidFile)
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return
+	}
+	p, err := process.NewProcess(int32(pid))
+	if err != nil {
+		return
+	}
+	name, err := p.Name()
+	if err != nil || !strings.EqualFold(strings.TrimSuffix(name, ".exe"), strings.TrimSuffix(filepath.Base(bin), ".exe")) {
+		return
+	}
+
+	log.Printf("Killing orphaned %s (pid %d) left by a previous run", name, pid)
+	if err := p.Kill(); err != nil {
+		log.Printf("Failed to kill orphaned %s (pid %d): %v", name, pid, err)
+	}
+}
+
+func recordChildPID(bin string, cmd *exec.Cmd) {
+	if err := os.WriteFile(childPIDFile(bin), []byte(strconv.Itoa(cmd.Process.Pid)), 0644); err != nil {
+		log.Printf("Failed to record %s pid: %v", bin, err)
+	}
+}
+
+func forgetChildPID(cmd *exec.Cmd) {
+	os.Remove(childPIDFile(cmd.Path))
+}
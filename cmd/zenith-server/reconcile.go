@@ -0,0 +1,65 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"zenith/pkg/collector"
+	"zenith/pkg/db"
+)
+
+// ReconcileResponse compares the metric names stored in VictoriaMetrics with
+// the schema the LLM is told about.
+type ReconcileResponse struct {
+	// Orphans are stored metrics the schema doesn't know, so questions
+	// about them can't be answered; usually a renamed or ad-hoc metric.
+	Orphans []string `json:"orphans"`
+	// Absent are schema metrics with no stored data yet, which is expected
+	// for metrics from another platform or optional collectors.
+	Absent []string `json:"absent"`
+	// Internal are Zenith's own zenith_* self-monitoring metrics.
+	Internal []string `json:"internal"`
+}
+
+func handleSchemaReconcile(w http.ResponseWriter, r *http.Request, database *db.VictoriaDB) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	stored, err := database.StoredMetricNames()
+	if err != nil {
+		log.Printf("Error listing stored metric names: %v", err)
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	known := make(map[string]bool, len(collector.MetricNames))
+	for _, name := range collector.MetricNames {
+		known[name] = true
+	}
+
+	resp := ReconcileResponse{Orphans: []string{}, Absent: []string{}, Internal: []string{}}
+	present := make(map[string]bool, len(stored))
+	for _, name := range stored {
+		present[name] = true
+		switch {
+		case known[name]:
+		case strings.HasPrefix(name, "zenith_"):
+			resp.Internal = append(resp.Internal, name)
+		default:
+			resp.Orphans = append(resp.Orphans, name)
+		}
+	}
+	for _, name := range collector.MetricNames {
+		if !present[name] {
+			resp.Absent = append(resp.Absent, name)
+		}
+	}
+
+	if len(resp.Orphans) > 0 {
+		log.Printf("Schema reconcile: metrics stored but unknown to the schema: %s", strings.Join(resp.Orphans, ", "))
+	}
+	respondJSON(w, resp)
+}
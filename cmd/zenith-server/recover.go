@@ -0,0 +1,41 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"runtime/debug"
+
+	"zenith/pkg/telemetry"
+)
+
+var handlerPanics = telemetry.Default.Counter("zenith_handler_panics_total", "Panics recovered from HTTP handlers.")
+
+// recoverPanics keeps a panicking handler from taking the server down: it
+// logs the panic with its stack (and the query ID, for /query) and answers
+// 500 with a generic message. http.ErrAbortHandler is passed through, since
+// it is how a handler deliberately aborts a response.
+func recoverPanics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+			handlerPanics.Inc(nil)
+			id := w.Header().Get(queryIDHeader)
+			if id == "" {
+				id = r.Header.Get(queryIDHeader)
+			}
+			if id != "" {
+				log.Printf("Panic serving %s %s (query %s): %v\n%s", r.Method, r.URL.Path, id, p, debug.Stack())
+			} else {
+				log.Printf("Panic serving %s %s: %v\n%s", r.Method, r.URL.Path, p, debug.Stack())
+			}
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"log"
+
+	"zenith/pkg/llm"
+)
+
+// redactingProvider masks sensitive data in query results and system data
+// before the wrapped provider puts them into a prompt. The user's question
+// and the generated query are passed through unchanged.
+type redactingProvider struct {
+	llm.Provider
+	redactor *llm.Redactor
+}
+
+func (p redactingProvider) redact(what, s string) string {
+	s, n := p.redactor.Redact(s)
+	if n > 0 {
+		log.Printf("Redacted %d match(es) from %s before sending it to the LLM", n, what)
+	}
+	return s
+}
+
+func (p redactingProvider) ExplainResults(userQuery, sql, results, language string) (string, error) {
+	return p.Provider.ExplainResults(userQuery, sql, p.redact("query results", results), language)
+}
+
+func (p redactingProvider) ExplainResultsStream(userQuery, sql, results, language string, onToken func(string)) (string, error) {
+	return llm.ExplainStream(p.Provider, userQuery, sql, p.redact("query results", results), language, onToken)
+}
+
+func (p redactingProvider) GenerateRecommendations(systemData, language string) (string, error) {
+	return p.Provider.GenerateRecommendations(p.redact("system data", systemData), language)
+}
+
+// Ping passes through so the breaker can still health-check the backend.
+func (p redactingProvider) Ping() error {
+	if pinger, ok := p.Provider.(llm.Pinger); ok {
+		return pinger.Ping()
+	}
+	return nil
+}
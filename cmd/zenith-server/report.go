@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"zenith/pkg/db"
+	"zenith/pkg/llm"
+	"zenith/pkg/rl"
+)
+
+// errorLevels matches the messageType values that count as errors across
+// platforms (macOS Error/Fault, journald err/crit, Windows Error/Critical).
+const errorLevels = `(?i)(error|err|fault|crit|critical)`
+
+// reportSection is one block of data gathered for a digest.
+type reportSection struct {
+	Title string
+	Query string
+	Data  string
+}
+
+// Report is a digest of a period: the data it was built from and the LLM's
+// narrative summary of it.
+type Report struct {
+	Generated time.Time
+	Period    time.Duration
+	Summary   string
+	Sections  []reportSection
+}
+
+// gatherReport collects the period's top resource consumers and error
+// volume. Sections whose query fails are reported as unavailable rather
+// than failing the whole report.
+func gatherReport(ctx context.Context, database *db.VictoriaDB, period time.Duration) []reportSection {
+	window := fmt.Sprintf("%ds", int(period.Seconds()))
+	metricSections := []reportSection{
+		{Title: "Top 5 processes by peak CPU (% of one core)", Query: fmt.Sprintf("topk(5, max_over_time(process_cpu_pct[%s]))", window)},
+		{Title: "Top 5 processes by peak memory (MB)", Query: fmt.Sprintf("topk(5, max_over_time(process_memory_mb[%s]))", window)},
+		{Title: "Peak CPU usage (%)", Query: fmt.Sprintf("max_over_time(cpu_usage_pct[%s])", window)},
+		{Title: "Peak memory used (MB)", Query: fmt.Sprintf("max_over_time(memory_used_mb[%s])", window)},
+		{Title: "Error log entries per collection cycle, peak vs. average", Query: fmt.Sprintf(`label_set(max_over_time(log_event_count{level=~"%s"}[%s]), "stat", "peak") or label_set(avg_over_time(log_event_count{level=~"%s"}[%s]), "stat", "average")`, errorLevels, window, errorLevels, window)},
+	}
+
+	var sections []reportSection
+	for _, s := range metricSections {
+		res, err := database.QueryMetricsContext(ctx, s.Query)
+		switch {
+		case err != nil:
+			s.Data = fmt.Sprintf("unavailable: %v", err)
+		case isEmptyResult(res):
+			s.Data = "no data"
+		default:
+			s.Data = strings.TrimSpace(res)
+		}
+		sections = append(sections, s)
+	}
+
+	errFilter := fmt.Sprintf(`messageType:~%q`, errorLevels)
+	errs := reportSection{Title: "Processes logging the most errors", Query: fmt.Sprintf("%s (last %s) | stats by (processName) count()", errFilter, window)}
+	counts, err := database.CountLogsContext(ctx, errFilter, window, "processName")
+	if err != nil {
+		errs.Data = fmt.Sprintf("unavailable: %v", err)
+	} else {
+		errs.Data = topCounts(counts, 10)
+	}
+	return append(sections, errs)
+}
+
+// topCounts lists the n largest counts, one "name: count" per line.
+func topCounts(counts map[string]float64, n int) string {
+	if len(counts) == 0 {
+		return "no data"
+	}
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	if len(names) > n {
+		names = names[:n]
+	}
+	var b strings.Builder
+	for _, name := range names {
+		if name == "" {
+			name = "(unknown)"
+		}
+		fmt.Fprintf(&b, "%s: %.0f\n", name, counts[name])
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// buildReport gathers the period's data, has the LLM summarize it, and logs
+// the result as a "report" RL experience.
+func buildReport(ctx context.Context, database *db.VictoriaDB, client llm.Provider, rlDB *rl.DB, period time.Duration, lang string) (*Report, int64, error) {
+	rep := &Report{Generated: time.Now(), Period: period, Sections: gatherReport(ctx, database, period)}
+
+	var queries, data strings.Builder
+	for _, s := range rep.Sections {
+		fmt.Fprintf(&queries, "%s\n", s.Query)
+		fmt.Fprintf(&data, "%s:\n%s\n\n", s.Title, s.Data)
+	}
+
+	question := fmt.Sprintf("Write a short operations digest for this machine covering the last %s: the top resource consumers, any error spikes (a peak far above the average), and anything that needs attention.", formatPeriod(period))
+	summary, err := await(ctx, func() (string, error) {
+		return client.ExplainResults(question, queries.String(), data.String(), lang)
+	})
+	if err != nil {
+		id, _ := rlDB.LogExperience("report", question, queries.String(), fmt.Sprintf("Failed to summarize report: %v", err))
+		return nil, id, fmt.Errorf("failed to summarize report: %v", err)
+	}
+	rep.Summary = summary
+
+	id, _ := rlDB.LogExperience("report", question, queries.String(), "Success")
+	return rep, id, nil
+}
+
+// formatPeriod prints whole-hour periods as "24h" rather than "24h0m0s".
+func formatPeriod(d time.Duration) string {
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return d.String()
+}
+
+// Markdown renders the report as a Markdown document.
+func (r *Report) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Zenith report: last %s\n\n", formatPeriod(r.Period))
+	fmt.Fprintf(&b, "_Generated %s_\n\n", r.Generated.Format(time.RFC1123))
+	fmt.Fprintf(&b, "## Summary\n\n%s\n\n", strings.TrimSpace(r.Summary))
+	for _, s := range r.Sections {
+		fmt.Fprintf(&b, "## %s\n\n```\n%s\n```\n\n", s.Title, s.Data)
+	}
+	return b.String()
+}
+
+// HTML renders the report as a standalone HTML page, suitable as an email
+// body.
+func (r *Report) HTML() string {
+	var b strings.Builder
+	title := html.EscapeString(fmt.Sprintf("Zenith report: last %s", formatPeriod(r.Period)))
+	fmt.Fprintf(&b, "<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"><title>%s</title></head><body>\n", title)
+	fmt.Fprintf(&b, "<h1>%s</h1>\n<p><em>Generated %s</em></p>\n<h2>Summary</h2>\n", title, html.EscapeString(r.Generated.Format(time.RFC1123)))
+	for _, para := range strings.Split(strings.TrimSpace(r.Summary), "\n\n") {
+		fmt.Fprintf(&b, "<p>%s</p>\n", strings.ReplaceAll(html.EscapeString(para), "\n", "<br>"))
+	}
+	for _, s := range r.Sections {
+		fmt.Fprintf(&b, "<h2>%s</h2>\n<pre>%s</pre>\n", html.EscapeString(s.Title), html.EscapeString(s.Data))
+	}
+	b.WriteString("</body></html>\n")
+	return b.String()
+}
+
+// handleReport serves GET /report?period=24h&format=markdown|html.
+func handleReport(w http.ResponseWriter, r *http.Request, database *db.VictoriaDB, client llm.Provider, rlDB *rl.DB, defaultLang string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	period := 24 * time.Hour
+	if p := r.URL.Query().Get("period"); p != "" {
+		d, err := db.ParseDuration(p)
+		if err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid period '%s'", p))
+			return
+		}
+		period = d
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "markdown"
+	}
+	if format != "markdown" && format != "html" {
+		writeError(w, http.StatusBadRequest, "format must be markdown or html")
+		return
+	}
+	lang, err := responseLanguage(r, defaultLang)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	log.Printf("Generating report for the last %s...", formatPeriod(period))
+	countQuery("report")
+	rep, id, err := buildReport(r.Context(), database, client, rlDB, period, lang)
+	if err != nil {
+		countQueryError("report", "explain")
+		log.Println("Error:", err)
+		w.Header().Set("X-Interaction-ID", fmt.Sprint(id))
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	w.Header().Set("X-Interaction-ID", fmt.Sprint(id))
+	if format == "html" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(rep.HTML()))
+		return
+	}
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.Write([]byte(rep.Markdown()))
+}
+
+// startReportJob writes a report covering each interval to dir, as both
+// Markdown and HTML, every interval.
+func startReportJob(database *db.VictoriaDB, client llm.Provider, rlDB *rl.DB, interval time.Duration, dir, lang string) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Printf("Failed to create report directory %s, not generating reports: %v", dir, err)
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		log.Println("Generating scheduled report...")
+		rep, _, err := buildReport(context.Background(), database, client, rlDB, interval, lang)
+		if err != nil {
+			log.Printf("Scheduled report failed: %v", err)
+			continue
+		}
+		base := filepath.Join(dir, "zenith-report-"+rep.Generated.Format("2006-01-02T150405"))
+		if err := os.WriteFile(base+".md", []byte(rep.Markdown()), 0644); err != nil {
+			log.Printf("Failed to write report: %v", err)
+			continue
+		}
+		if err := os.WriteFile(base+".html", []byte(rep.HTML()), 0644); err != nil {
+			log.Printf("Failed to write report: %v", err)
+			continue
+		}
+		log.Printf("Report written to %s.md and %s.html", base, base)
+	}
+}
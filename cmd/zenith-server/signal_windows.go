@@ -0,0 +1,8 @@
+//go:build windows
+
+package main
+
+import "os"
+
+// notifyDumpSignal is a no-op on Windows, which has no SIGUSR1 equivalent.
+func notifyDumpSignal(c chan<- os.Signal) {}
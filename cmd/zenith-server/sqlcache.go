@@ -0,0 +1,107 @@
+package main
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+
+	"zenith/pkg/telemetry"
+)
+
+var sqlCacheLookups = telemetry.Default.Counter("zenith_sql_cache_lookups_total", "SQL translation cache lookups in /query, by result (hit or miss).")
+
+// sqlCache remembers the query each natural-language question was
+// translated to, so asking the same thing again skips the LLM. Entries are
+// evicted least-recently-used once the cache is full, and expire after ttl.
+// A nil *sqlCache is a disabled cache.
+type sqlCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	size    int
+	order   *list.List // front is most recently used
+	entries map[string]*list.Element
+}
+
+type sqlCacheEntry struct {
+	key     string
+	query   string
+	expires time.Time
+}
+
+func newSQLCache(size int, ttl time.Duration) *sqlCache {
+	if size < 1 {
+		size = 1
+	}
+	return &sqlCache{ttl: ttl, size: size, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+var translations *sqlCache
+
+// sqlCacheKey normalizes a question so that case and spacing differences
+// still hit the same entry.
+func sqlCacheKey(question string) string {
+	return strings.Join(strings.Fields(strings.ToLower(question)), " ")
+}
+
+func (c *sqlCache) get(question string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := sqlCacheKey(question)
+	el, ok := c.entries[key]
+	if ok && c.ttl > 0 && time.Now().After(el.Value.(*sqlCacheEntry).expires) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		ok = false
+	}
+	if !ok {
+		sqlCacheLookups.Inc(map[string]string{"result": "miss"})
+		return "", false
+	}
+	c.order.MoveToFront(el)
+	sqlCacheLookups.Inc(map[string]string{"result": "hit"})
+	return el.Value.(*sqlCacheEntry).query, true
+}
+
+// put records query as the translation of question. Only call it once the
+// query has executed successfully.
+func (c *sqlCache) put(question, query string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := sqlCacheKey(question)
+	entry := &sqlCacheEntry{key: key, query: query, expires: time.Now().Add(c.ttl)}
+	if el, ok := c.entries[key]; ok {
+		el.Value = entry
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*sqlCacheEntry).key)
+	}
+}
+
+// forget drops question's entry, for a cached query that stopped working.
+func (c *sqlCache) forget(question string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := sqlCacheKey(question)
+	if el, ok := c.entries[key]; ok {
+		c.order.Remove(el)
+		delete(c.entries, key)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"zenith/pkg/llm"
+)
+
+// serverState tracks runtime facts surfaced by the SIGUSR1 debug dump.
+type serverState struct {
+	inFlight atomic.Int64
+
+	mu                 sync.Mutex
+	lastCollection     time.Time
+	lastSRUMCollection time.Time
+}
+
+var state = &serverState{}
+
+func (s *serverState) markCollection() {
+	s.mu.Lock()
+	s.lastCollection = time.Now()
+	s.mu.Unlock()
+}
+
+func (s *serverState) markSRUMCollection() {
+	s.mu.Lock()
+	s.lastSRUMCollection = time.Now()
+	s.mu.Unlock()
+}
+
+// trackInFlight counts requests currently being served by h.
+func trackInFlight(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		state.inFlight.Add(1)
+		defer state.inFlight.Add(-1)
+		h(w, r)
+	}
+}
+
+// dumpState logs a snapshot of the server's runtime state for live debugging.
+func dumpState(provider string, breaker *llm.Breaker) {
+	state.mu.Lock()
+	lastCollection, lastSRUM := state.lastCollection, state.lastSRUMCollection
+	state.mu.Unlock()
+
+	formatTime := func(t time.Time) string {
+		if t.IsZero() {
+			return "never"
+		}
+		return t.Format(time.RFC3339) + " (" + time.Since(t).Round(time.Second).String() + " ago)"
+	}
+
+	log.Println("=== Zenith state dump ===")
+	log.Printf("Last collection:      %s", formatTime(lastCollection))
+	log.Printf("Last SRUM collection: %s", formatTime(lastSRUM))
+	log.Printf("In-flight requests:   %d", state.inFlight.Load())
+	if breaker == nil {
+		log.Printf("LLM provider:         %s (not configured, collection-only mode)", provider)
+	} else {
+		log.Printf("LLM provider:         %s (breaker %s, %d consecutive failures)", provider, breaker.State(), breaker.ConsecutiveFailures())
+	}
+	log.Printf("Goroutines:           %d", runtime.NumGoroutine())
+	log.Println("=========================")
+}
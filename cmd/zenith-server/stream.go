@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"zenith/pkg/llm"
+)
+
+// eventStream writes server-sent events. Nothing is sent until the first
+// event, so a request that fails early can still get a plain JSON error.
+type eventStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	started bool
+}
+
+// newEventStream returns a stream if the client asked for one with
+// `Accept: text/event-stream` and w can flush, and nil otherwise.
+func newEventStream(w http.ResponseWriter, r *http.Request) *eventStream {
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		return nil
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil
+	}
+	return &eventStream{w: w, flusher: flusher}
+}
+
+// send writes one event with data JSON-encoded on a single line.
+func (s *eventStream) send(event string, data interface{}) {
+	if !s.started {
+		s.w.Header().Set("Content-Type", "text/event-stream")
+		s.w.Header().Set("Cache-Control", "no-cache")
+		s.started = true
+	}
+	payload, _ := json.Marshal(data)
+	fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, payload)
+	s.flusher.Flush()
+}
+
+// respondQuery sends resp as a "done" event on a stream, or as JSON.
+func respondQuery(w http.ResponseWriter, stream *eventStream, resp QueryResponse) {
+	if stream == nil || !stream.started {
+		respondJSON(w, resp)
+		return
+	}
+	stream.send("done", resp)
+	log.Println("Response sent to client.")
+}
+
+// respondQueryError is respondError for a /query request, which may be
+// streaming and may have asked for a trace of the attempts made so far.
+func respondQueryError(w http.ResponseWriter, stream *eventStream, msg string, id int64, trace *QueryTrace) {
+	log.Println("Error:", msg)
+	resp := QueryResponse{InteractionID: id, Error: msg, Trace: trace}
+	if stream == nil || !stream.started {
+		respondJSON(w, resp)
+		return
+	}
+	stream.send("error", resp)
+}
+
+// explainStreaming runs ExplainResults, forwarding each token to the client
+// as a "token" event as the provider produces it. Like await, it stops
+// waiting as soon as ctx is done. A panic in the provider is returned as an
+// error, since the goroutine it runs on is outside the handler's recovery.
+func explainStreaming(ctx context.Context, stream *eventStream, client llm.Provider, userQuery, sql, results, language string) (string, error) {
+	tokens := make(chan string, 64)
+	var explanation string
+	var err error
+	go func() {
+		defer close(tokens)
+		defer func() {
+			if p := recover(); p != nil {
+				log.Printf("Panic in streaming LLM explanation: %v\n%s", p, debug.Stack())
+				explanation, err = "", fmt.Errorf("LLM explanation failed: %v", p)
+			}
+		}()
+		explanation, err = llm.ExplainStream(client, userQuery, sql, results, language, func(token string) {
+			select {
+			case tokens <- token:
+			case <-ctx.Done():
+			}
+		})
+	}()
+
+	for {
+		select {
+		case token, ok := <-tokens:
+			if !ok {
+				return explanation, err
+			}
+			stream.send("token", token)
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+)
+
+// panickingProvider panics when asked to explain results.
+type panickingProvider struct{ stubProvider }
+
+func (p *panickingProvider) ExplainResults(question, sql, results, lang string) (string, error) {
+	panic("provider bug")
+}
+
+func TestExplainStreaming_RecoversPanic(t *testing.T) {
+	rec := httptest.NewRecorder()
+	stream := &eventStream{w: rec, flusher: rec}
+	explanation, err := explainStreaming(context.Background(), stream, &panickingProvider{}, "q", "METRIC:up", "up: 1", "")
+	if err == nil || explanation != "" {
+		t.Fatalf("Expected the panic as an error, got %q, %v", explanation, err)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"log"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"zenith/pkg/telemetry"
+)
+
+// Restart policy for supervised subprocesses. After maxProcessRestarts
+// crashes in a row the supervisor gives up; a run that lasts stableRunTime
+// resets the count.
+const (
+	maxProcessRestarts = 5
+	restartBackoffBase = time.Second
+	restartBackoffMax  = time.Minute
+	stableRunTime      = 10 * time.Minute
+)
+
+var processRestarts = telemetry.Default.Counter("zenith_subprocess_restarts_total", "Restarts of crashed VictoriaMetrics/VictoriaLogs subprocesses.")
+
+// supervisedProcess restarts a subprocess with the same arguments when it
+// exits on its own, until Stop is called.
+type supervisedProcess struct {
+	bin  string
+	args []string
+
+	mu       sync.Mutex
+	cmd      *exec.Cmd
+	exited   chan struct{} // closed once cmd has been waited for
+	stopping bool
+	stop     chan struct{}
+}
+
+// superviseProcess starts bin like startProcess and keeps it running.
+func superviseProcess(bin string, args ...string) *supervisedProcess {
+	p := &supervisedProcess{bin: bin, args: args, stop: make(chan struct{})}
+	p.cmd = startProcess(bin, args...)
+	p.exited = make(chan struct{})
+	go p.run()
+	return p
+}
+
+func (p *supervisedProcess) run() {
+	name := filepath.Base(p.bin)
+	crashes := 0
+	for {
+		p.mu.Lock()
+		cmd, exited := p.cmd, p.exited
+		p.mu.Unlock()
+
+		started := time.Now()
+		err := cmd.Wait()
+		close(exited)
+
+		p.mu.Lock()
+		stopping := p.stopping
+		p.mu.Unlock()
+		if stopping {
+			return
+		}
+		forgetChildPID(cmd)
+
+		if time.Since(started) >= stableRunTime {
+			crashes = 0
+		}
+		crashes++
+		if crashes > maxProcessRestarts {
+			log.Printf("%s exited unexpectedly (%v) after %d restarts; giving up", name, err, maxProcessRestarts)
+			return
+		}
+
+		backoff := restartBackoffBase << (crashes - 1)
+		if backoff > restartBackoffMax {
+			backoff = restartBackoffMax
+		}
+		log.Printf("%s exited unexpectedly (%v); restarting in %s (attempt %d/%d)", name, err, backoff, crashes, maxProcessRestarts)
+
+		var pid int
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-time.After(backoff):
+			}
+
+			p.mu.Lock()
+			if p.stopping {
+				p.mu.Unlock()
+				return
+			}
+			next, err := tryStartProcess(p.bin, p.args...)
+			if err == nil {
+				p.cmd, p.exited = next, make(chan struct{})
+				pid = next.Process.Pid
+			}
+			p.mu.Unlock()
+			if err == nil {
+				break
+			}
+
+			crashes++
+			if crashes > maxProcessRestarts {
+				log.Printf("Failed to restart %s: %v; giving up", name, err)
+				return
+			}
+			log.Printf("Failed to restart %s: %v; retrying in %s", name, err, backoff)
+		}
+		processRestarts.Inc(map[string]string{"process": name})
+		log.Printf("Restarted %s (pid %d)", name, pid)
+	}
+}
+
+// Stop ends supervision and stops the current process gracefully.
+func (p *supervisedProcess) Stop() {
+	p.mu.Lock()
+	p.stopping = true
+	cmd, exited := p.cmd, p.exited
+	p.mu.Unlock()
+	close(p.stop)
+
+	select {
+	case <-exited:
+		// It already exited and the supervisor gave up on it.
+		return
+	default:
+	}
+	stopStartedProcess(cmd, exited)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"unicode/utf8"
+)
+
+// traceResultsLimit caps how much of the raw results a trace carries; the
+// full rows are available with ?include_results=1.
+const traceResultsLimit = 4000
+
+// QueryTrace shows how a /query answer was derived, for ?trace=1: each
+// query tried and where it came from, the one whose results were
+// explained, those results as the LLM saw them, and the explanation.
+// A nil *QueryTrace records nothing.
+type QueryTrace struct {
+	Question         string         `json:"question"`
+	Attempts         []TraceAttempt `json:"attempts"`
+	Query            string         `json:"query"`
+	QueryType        string         `json:"query_type"` // "metric", "range", or "log"
+	Results          string         `json:"results"`
+	ResultsTruncated bool           `json:"results_truncated,omitempty"`
+	Explanation      string         `json:"explanation"`
+}
+
+// TraceAttempt is one query /query generated or picked, and why it was
+// abandoned if it was.
+type TraceAttempt struct {
+	// Source is where the query came from: "llm", "cache", "canned",
+	// "confirmed", or "fallback" for the fuzzy log query tried after an
+	// empty result.
+	Source string `json:"source"`
+	Query  string `json:"query,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+func newQueryTrace(question string) *QueryTrace {
+	return &QueryTrace{Question: question, Attempts: []TraceAttempt{}}
+}
+
+// attempt records a query from source and the error it failed with, if any.
+func (t *QueryTrace) attempt(source, query string, err error) {
+	if t == nil {
+		return
+	}
+	a := TraceAttempt{Source: source, Query: query}
+	if err != nil {
+		a.Error = err.Error()
+	}
+	t.Attempts = append(t.Attempts, a)
+}
+
+// finish records the query that was explained, its type, the results given
+// to the LLM (truncated to traceResultsLimit), and the explanation.
+func (t *QueryTrace) finish(query string, structured *QueryResults, results, explanation string) {
+	if t == nil {
+		return
+	}
+	t.Query = query
+	if structured != nil {
+		t.QueryType = structured.Type
+	}
+	if len(results) > traceResultsLimit {
+		cut := traceResultsLimit
+		for cut > 0 && !utf8.RuneStart(results[cut]) {
+			cut--
+		}
+		results, t.ResultsTruncated = results[:cut], true
+	}
+	t.Results = results
+	t.Explanation = explanation
+}
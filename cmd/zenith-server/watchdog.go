@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"zenith/pkg/db"
+	"zenith/pkg/telemetry"
+)
+
+var (
+	collectionPanics  = telemetry.Default.Counter("zenith_collection_panics_total", "Panics recovered from collection cycles, by kind.")
+	schedulerRestarts = telemetry.Default.Counter("zenith_scheduler_restarts_total", "Times the watchdog restarted a stalled collection scheduler.")
+)
+
+// recoverCollection runs one collection cycle of kind ("regular" or
+// "srum"), logging and counting a panic instead of letting it kill the
+// scheduler.
+func recoverCollection(kind string, fn func()) {
+	defer func() {
+		if p := recover(); p != nil {
+			collectionPanics.Inc(map[string]string{"kind": kind})
+			log.Printf("Recovered from panic in %s collection: %v\n%s", kind, p, debug.Stack())
+		}
+	}()
+	fn()
+}
+
+// schedulerWatchdog keeps the collection scheduler running. The scheduler
+// beats a heartbeat while it is idle and after every cycle; if the
+// heartbeat goes stale (the goroutine died or a collector hung), the
+// watchdog starts a new scheduler. Each scheduler carries a generation, so a
+// hung one that eventually returns sees it has been replaced and exits.
+type schedulerWatchdog struct {
+	database *db.VictoriaDB
+
+	mu       sync.Mutex
+	interval string        // as configured, passed to the collectors
+	every    time.Duration // interval, parsed
+	reset    chan struct{} // signals the scheduler that the interval changed
+
+	generation atomic.Int64
+	heartbeat  atomic.Int64 // unix nanoseconds
+	restarts   atomic.Int64
+}
+
+func newSchedulerWatchdog(database *db.VictoriaDB, interval string) *schedulerWatchdog {
+	s := &schedulerWatchdog{
+		database: database,
+		interval: interval,
+		every:    parseCollectInterval(interval),
+		reset:    make(chan struct{}, 1),
+	}
+	s.heartbeat.Store(time.Now().UnixNano())
+	return s
+}
+
+// currentInterval returns the collection interval as configured and parsed.
+func (s *schedulerWatchdog) currentInterval() (string, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.interval, s.every
+}
+
+// setInterval changes the collection interval; the running scheduler
+// resets its ticker at its next wakeup.
+func (s *schedulerWatchdog) setInterval(interval string, every time.Duration) {
+	s.mu.Lock()
+	s.interval, s.every = interval, every
+	s.mu.Unlock()
+	select {
+	case s.reset <- struct{}{}:
+	default: // a reset is already pending and will pick this up
+	}
+}
+
+// beat records that the scheduler of generation gen is alive, and reports
+// whether it is still the current one.
+func (s *schedulerWatchdog) beat(gen int64) bool {
+	if s.generation.Load() != gen {
+		return false
+	}
+	s.heartbeat.Store(time.Now().UnixNano())
+	return true
+}
+
+// lastHeartbeat returns when the scheduler last reported in.
+func (s *schedulerWatchdog) lastHeartbeat() time.Time {
+	return time.Unix(0, s.heartbeat.Load())
+}
+
+// stallAfter is how long the heartbeat may go unchanged before the
+// scheduler counts as stalled: long enough for a slow collection cycle.
+func (s *schedulerWatchdog) stallAfter() time.Duration {
+	_, every := s.currentInterval()
+	return max(15*time.Minute, 3*every)
+}
+
+// run starts the scheduler and restarts it whenever it stalls. It never
+// returns.
+func (s *schedulerWatchdog) run() {
+	s.start()
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		if since := time.Since(s.lastHeartbeat()); since > s.stallAfter() {
+			s.restarts.Add(1)
+			schedulerRestarts.Inc(nil)
+			log.Printf("Collection scheduler has not reported in for %s, restarting it", since.Round(time.Second))
+			s.start()
+		}
+	}
+}
+
+// start launches a new scheduler generation, retiring any previous one.
+func (s *schedulerWatchdog) start() {
+	gen := s.generation.Add(1)
+	s.beat(gen)
+	go startScheduler(s, gen)
+}
+
+// minCollectInterval keeps /config/interval from setting an interval so
+// short that collection cycles run back to back.
+const minCollectInterval = 10 * time.Second
+
+// CollectIntervalRequest is the body of POST /config/interval, and what GET
+// returns.
+type CollectIntervalRequest struct {
+	Interval string `json:"interval"`
+}
+
+// handleCollectInterval serves /config/interval: GET returns the collection
+// interval, POST changes it (e.g. {"interval":"15m"}) without a restart.
+func handleCollectInterval(w http.ResponseWriter, r *http.Request, s *schedulerWatchdog) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req CollectIntervalRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		every, err := db.ParseDuration(req.Interval)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid interval: %v", err))
+			return
+		}
+		if every < minCollectInterval {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Interval must be at least %s", minCollectInterval))
+			return
+		}
+		s.setInterval(req.Interval, every)
+		log.Printf("Collection interval changed to %s", req.Interval)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	interval, _ := s.currentInterval()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(CollectIntervalRequest{Interval: interval})
+}
@@ -0,0 +1,39 @@
+package collector
+
+import (
+	"time"
+
+	"zenith/pkg/db"
+)
+
+// metricBatch collects samples in memory and writes them with a single
+// MetricSink.InsertMetrics call, instead of one request per sample.
+type metricBatch struct {
+	database MetricSink
+	tracker  *seriesTracker
+	metrics  []db.Metric
+}
+
+// newMetricBatch starts a batch for database. If tracker is non-nil, every
+// series added is recorded in it for staleness markers.
+func newMetricBatch(database MetricSink, tracker *seriesTracker) *metricBatch {
+	return &metricBatch{database: database, tracker: tracker}
+}
+
+func (b *metricBatch) InsertMetric(name string, value float64, labels map[string]string) {
+	b.InsertMetricAt(name, value, labels, time.Time{})
+}
+
+func (b *metricBatch) InsertMetricAt(name string, value float64, labels map[string]string, ts time.Time) {
+	if b.tracker != nil {
+		b.tracker.record(name, labels)
+	}
+	b.metrics = append(b.metrics, db.Metric{Name: name, Value: value, Labels: labels, Timestamp: ts})
+}
+
+// Flush writes the collected samples and empties the batch.
+func (b *metricBatch) Flush() error {
+	err := b.database.InsertMetrics(b.metrics)
+	b.metrics = nil
+	return err
+}
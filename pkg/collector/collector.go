@@ -1,8 +1,24 @@
 package collector
 
-import "zenith/pkg/db"
+import (
+	"fmt"
+	"time"
 
-type Collector interface {
-	CollectLogs(database *db.VictoriaDB, duration string) error
-	CollectMetrics(database *db.VictoriaDB) error
+	"zenith/pkg/db"
+	"zenith/pkg/telemetry"
+)
+
+// collectErrors reports collector failures, collapsing a failure that
+// repeats every cycle into one line per hour.
+var collectErrors = telemetry.NewDeduper(time.Hour, func(s string) { fmt.Println(s) })
+
+// collectWindow parses the duration a collector looks back over, e.g. the
+// collection interval passed to CollectLogs. An invalid one, which the
+// server rejects at startup, falls back to 5m.
+func collectWindow(duration string) time.Duration {
+	dur, err := db.ParseDuration(duration)
+	if err != nil {
+		return 5 * time.Minute
+	}
+	return dur
 }
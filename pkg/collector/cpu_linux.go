@@ -0,0 +1,81 @@
+//go:build linux
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sampleCPUPercent measures total CPU usage over interval by reading the
+// aggregate jiffies in /proc/stat before and after sleeping.
+func sampleCPUPercent(interval time.Duration) (float64, error) {
+	before, err := readCPUTimes()
+	if err != nil {
+		return 0, err
+	}
+	time.Sleep(interval)
+	after, err := readCPUTimes()
+	if err != nil {
+		return 0, err
+	}
+	return cpuBusyPercent(before, after), nil
+}
+
+// cpuTimes holds the busy and total jiffies from the aggregate "cpu" line.
+type cpuTimes struct {
+	busy, total uint64
+}
+
+func readCPUTimes() (cpuTimes, error) {
+	f, err := os.Open("/proc/stat")
+	if err != nil {
+		return cpuTimes{}, err
+	}
+	defer f.Close()
+	return parseCPUTimes(f)
+}
+
+// parseCPUTimes reads the aggregate "cpu" line of /proc/stat. idle and
+// iowait count as idle; guest time is already included in user and nice.
+func parseCPUTimes(r io.Reader) (cpuTimes, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 || fields[0] != "cpu" {
+			continue
+		}
+		var t cpuTimes
+		// user nice system idle iowait irq softirq steal [guest guest_nice]
+		for i, field := range fields[1:] {
+			if i >= 8 {
+				break
+			}
+			v, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return cpuTimes{}, fmt.Errorf("invalid /proc/stat value %q: %v", field, err)
+			}
+			t.total += v
+			if i != 3 && i != 4 {
+				t.busy += v
+			}
+		}
+		return t, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return cpuTimes{}, err
+	}
+	return cpuTimes{}, fmt.Errorf("no aggregate cpu line in /proc/stat")
+}
+
+func cpuBusyPercent(before, after cpuTimes) float64 {
+	if after.total <= before.total || after.busy < before.busy {
+		return 0
+	}
+	return float64(after.busy-before.busy) / float64(after.total-before.total) * 100
+}
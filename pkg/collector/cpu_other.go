@@ -0,0 +1,22 @@
+//go:build !linux
+
+package collector
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+)
+
+// sampleCPUPercent measures total CPU usage over interval.
+func sampleCPUPercent(interval time.Duration) (float64, error) {
+	percent, err := cpu.Percent(interval, false)
+	if err != nil {
+		return 0, err
+	}
+	if len(percent) == 0 {
+		return 0, fmt.Errorf("no CPU usage reported")
+	}
+	return percent[0], nil
+}
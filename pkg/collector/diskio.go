@@ -0,0 +1,47 @@
+package collector
+
+import (
+	"sync"
+	"time"
+)
+
+// diskCounters is a disk's cumulative bytes read and written.
+type diskCounters struct {
+	Read, Written uint64
+}
+
+// diskIOTracker turns cumulative per-disk byte counters into read and
+// write rates averaged over the time between two collections.
+type diskIOTracker struct {
+	mu   sync.Mutex
+	prev map[string]diskCounters
+	at   time.Time
+}
+
+// record writes disk_read_kb and disk_write_kb (KB per second since the
+// previous call) for each disk in cur. The first call only records a
+// baseline, and a disk whose counters went backwards (a reset, or a
+// reattached device) is skipped until its next sample.
+func (t *diskIOTracker) record(database MetricSink, cur map[string]diskCounters, now time.Time) error {
+	t.mu.Lock()
+	prev, at := t.prev, t.at
+	t.prev, t.at = cur, now
+	t.mu.Unlock()
+
+	elapsed := now.Sub(at).Seconds()
+	if prev == nil || elapsed <= 0 {
+		return nil
+	}
+
+	out := newMetricBatch(database, nil)
+	for name, c := range cur {
+		p, ok := prev[name]
+		if !ok || c.Read < p.Read || c.Written < p.Written {
+			continue
+		}
+		labels := map[string]string{"host": "localhost", "disk": name}
+		out.InsertMetric("disk_read_kb", float64(c.Read-p.Read)/1024/elapsed, labels)
+		out.InsertMetric("disk_write_kb", float64(c.Written-p.Written)/1024/elapsed, labels)
+	}
+	return out.Flush()
+}
@@ -0,0 +1,41 @@
+package collector
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiskIOTracker(t *testing.T) {
+	var tracker diskIOTracker
+	start := time.Date(2026, 3, 6, 10, 0, 0, 0, time.UTC)
+
+	sink := &recordingSink{}
+	if err := tracker.record(sink, map[string]diskCounters{"disk0": {Read: 1 << 20, Written: 4 << 20}}, start); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.metrics) != 0 {
+		t.Errorf("Expected the first sample to only set a baseline, got %v", sink.samples())
+	}
+
+	next := map[string]diskCounters{
+		"disk0": {Read: 1<<20 + 10*1024*60, Written: 4<<20 + 512*60}, // +10 KB/s read, +0.5 KB/s written
+		"disk4": {Read: 1 << 30, Written: 1 << 30},                   // new device: no baseline yet
+	}
+	if err := tracker.record(sink, next, start.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	assertSamples(t, sink, []string{
+		`disk_read_kb{disk="disk0",host="localhost"} 10`,
+		`disk_write_kb{disk="disk0",host="localhost"} 0.5`,
+	})
+
+	sink = &recordingSink{}
+	reset := map[string]diskCounters{"disk0": {Read: 0, Written: 0}, "disk4": {Read: 1<<30 + 2048*1024*30, Written: 1 << 30}}
+	if err := tracker.record(sink, reset, start.Add(90*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	assertSamples(t, sink, []string{
+		`disk_read_kb{disk="disk4",host="localhost"} 2048`,
+		`disk_write_kb{disk="disk4",host="localhost"} 0`,
+	})
+}
@@ -0,0 +1,27 @@
+package collector
+
+import "sync"
+
+var (
+	eventLogMu       sync.Mutex
+	eventLogChannels = []string{"System", "Application"}
+)
+
+// SetEventLogChannels sets the Windows Event Log channels CollectLogs
+// queries, e.g. "Security" or
+// "Microsoft-Windows-WindowsUpdateClient/Operational". An empty list keeps
+// the default System and Application. It has no effect on other platforms.
+func SetEventLogChannels(channels []string) {
+	eventLogMu.Lock()
+	defer eventLogMu.Unlock()
+	if len(channels) == 0 {
+		channels = []string{"System", "Application"}
+	}
+	eventLogChannels = channels
+}
+
+func currentEventLogChannels() []string {
+	eventLogMu.Lock()
+	defer eventLogMu.Unlock()
+	return eventLogChannels
+}
@@ -0,0 +1,20 @@
+package collector
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSetEventLogChannels(t *testing.T) {
+	defer SetEventLogChannels(nil)
+
+	SetEventLogChannels([]string{"Security", "Microsoft-Windows-WindowsUpdateClient/Operational"})
+	if got := currentEventLogChannels(); !slices.Equal(got, []string{"Security", "Microsoft-Windows-WindowsUpdateClient/Operational"}) {
+		t.Errorf("Expected the configured channels, got %v", got)
+	}
+
+	SetEventLogChannels(nil)
+	if got := currentEventLogChannels(); !slices.Equal(got, []string{"System", "Application"}) {
+		t.Errorf("Expected an empty list to restore the defaults, got %v", got)
+	}
+}
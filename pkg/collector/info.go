@@ -0,0 +1,53 @@
+package collector
+
+import (
+	"runtime"
+	"strings"
+)
+
+// Info summarizes what the collectors gather on this platform with the
+// current settings.
+type Info struct {
+	Platform      string   `json:"platform"`
+	Collectors    []string `json:"collectors"`
+	Metrics       []string `json:"metrics"`
+	LogSources    []string `json:"log_sources"`
+	CPUSubSamples int      `json:"cpu_sub_samples"`
+	MaxLogAge     string   `json:"max_log_age,omitempty"`
+}
+
+// Describe reports the active collectors, the metric names they will
+// produce, and the log sources they read.
+func Describe() Info {
+	info := Info{
+		Platform:      runtime.GOOS + "/" + runtime.GOARCH,
+		Collectors:    platformCollectors,
+		Metrics:       PlatformMetricNames(runtime.GOOS),
+		LogSources:    logSources(),
+		CPUSubSamples: cpuSamples,
+	}
+	if maxLogAge > 0 {
+		info.MaxLogAge = maxLogAge.String()
+	}
+	return info
+}
+
+// PlatformMetricNames lists the metrics emitted on goos with the current
+// settings, in registry order.
+func PlatformMetricNames(goos string) []string {
+	return metricNames(func(m Metric) bool {
+		// The CPU spread series only exist when sub-sampling is on.
+		if cpuSamples <= 1 && strings.HasPrefix(m.Name, "cpu_usage_pct_") {
+			return false
+		}
+		if len(m.Platforms) == 0 {
+			return true
+		}
+		for _, p := range m.Platforms {
+			if p == goos {
+				return true
+			}
+		}
+		return false
+	})
+}
@@ -0,0 +1,9 @@
+//go:build darwin
+
+package collector
+
+var platformCollectors = []string{"cpu", "memory", "file_descriptors", "process", "unified_log"}
+
+func logSources() []string {
+	return []string{"macOS unified log (log show --last <collect interval>)"}
+}
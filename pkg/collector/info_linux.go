@@ -0,0 +1,9 @@
+//go:build linux
+
+package collector
+
+var platformCollectors = []string{"cpu", "memory", "file_descriptors", "process", "journald"}
+
+func logSources() []string {
+	return []string{"systemd journal (journalctl --since <collect interval> ago)"}
+}
@@ -0,0 +1,26 @@
+package collector
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPlatformMetricNames(t *testing.T) {
+	defer SetCPUSampling(1, 0)
+
+	darwin := PlatformMetricNames("darwin")
+	if slices.Contains(darwin, "srum_app_cycle_time_total") {
+		t.Error("Expected SRUM metrics to be Windows-only")
+	}
+	if !slices.Contains(PlatformMetricNames("windows"), "srum_app_cycle_time_total") {
+		t.Error("Expected SRUM metrics on Windows")
+	}
+	if slices.Contains(darwin, "cpu_usage_pct_max") {
+		t.Error("Expected no CPU spread series without sub-sampling")
+	}
+
+	SetCPUSampling(5, 0)
+	if !slices.Contains(PlatformMetricNames("darwin"), "cpu_usage_pct_max") {
+		t.Error("Expected CPU spread series with sub-sampling enabled")
+	}
+}
@@ -0,0 +1,13 @@
+//go:build windows
+
+package collector
+
+var platformCollectors = []string{"cpu", "memory", "file_descriptors", "process", "network", "process_io", "event_log", "srum"}
+
+func logSources() []string {
+	sources := make([]string, 0, len(eventLogChannels))
+	for _, channel := range eventLogChannels {
+		sources = append(sources, "Windows Event Log: "+channel)
+	}
+	return sources
+}
@@ -0,0 +1,32 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// decodeJSONArrayOrObject decodes command output that may be a JSON array of
+// T, a single T object, or nothing at all. PowerShell's ConvertTo-Json emits a
+// bare object when a pipeline yields one result and an array when it yields
+// many, and Windows tools often prefix output with a UTF-8 BOM.
+func decodeJSONArrayOrObject[T any](data []byte) ([]T, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil, nil
+	}
+
+	if data[0] == '[' {
+		var items []T
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, err
+		}
+		return items, nil
+	}
+
+	var item T
+	if err := json.Unmarshal(data, &item); err != nil {
+		return nil, err
+	}
+	return []T{item}, nil
+}
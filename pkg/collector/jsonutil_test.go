@@ -0,0 +1,46 @@
+package collector
+
+import "testing"
+
+type jsonProc struct {
+	Name string `json:"Name"`
+	Id   int    `json:"Id"`
+}
+
+func TestDecodeJSONArrayOrObject(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []jsonProc
+	}{
+		{"object", `{"Name":"svchost","Id":4}`, []jsonProc{{"svchost", 4}}},
+		{"array", `[{"Name":"svchost","Id":4},{"Name":"explorer","Id":8}]`, []jsonProc{{"svchost", 4}, {"explorer", 8}}},
+		{"empty", "  \r\n", nil},
+		{"null", "null", nil},
+		{"bom object", "\xef\xbb\xbf{\"Name\":\"lsass\",\"Id\":12}", []jsonProc{{"lsass", 12}}},
+		{"bom array", "\xef\xbb\xbf [{\"Name\":\"lsass\",\"Id\":12}]", []jsonProc{{"lsass", 12}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeJSONArrayOrObject[jsonProc]([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("decodeJSONArrayOrObject(%q) failed: %v", tt.input, err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %d items, got %d: %+v", len(tt.want), len(got), got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Item %d: expected %+v, got %+v", i, tt.want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeJSONArrayOrObject_Invalid(t *testing.T) {
+	if _, err := decodeJSONArrayOrObject[jsonProc]([]byte(`{"Name":`)); err == nil {
+		t.Fatal("Expected an error for truncated JSON")
+	}
+}
@@ -0,0 +1,57 @@
+package collector
+
+import "time"
+
+// maxLogAge, when positive, drops log entries older than now-maxLogAge even
+// if the platform log source returned them. `log show --last` in particular
+// is not strict about its window.
+var maxLogAge time.Duration
+
+// SetMaxLogAge sets the age cutoff applied to collected log entries. Zero
+// disables it.
+func SetMaxLogAge(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	maxLogAge = d
+}
+
+// logTimestampLayouts are the timestamp formats the log sources produce:
+// macOS `log show --style json` and Windows event SystemTime.
+var logTimestampLayouts = []string{
+	"2006-01-02 15:04:05.000000-0700",
+	time.RFC3339Nano,
+}
+
+func parseLogTimestamp(ts string) (time.Time, bool) {
+	for _, layout := range logTimestampLayouts {
+		if t, err := time.Parse(layout, ts); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// normalizeLogTimestamp rewrites a log source timestamp as RFC3339Nano in
+// UTC, which VictoriaLogs parses as the entry's _time. Unparseable values
+// are returned unchanged.
+func normalizeLogTimestamp(ts string) string {
+	t, ok := parseLogTimestamp(ts)
+	if !ok {
+		return ts
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// logTooOld reports whether an entry stamped ts falls before the
+// maxLogAge cutoff. Entries whose timestamp can't be parsed are kept.
+func logTooOld(ts string, now time.Time) bool {
+	if maxLogAge <= 0 {
+		return false
+	}
+	t, ok := parseLogTimestamp(ts)
+	if !ok {
+		return false
+	}
+	return t.Before(now.Add(-maxLogAge))
+}
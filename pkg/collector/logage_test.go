@@ -0,0 +1,47 @@
+package collector
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLogTooOld(t *testing.T) {
+	defer SetMaxLogAge(0)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	if logTooOld("2024-05-01 09:00:00.000000+0000", now) {
+		t.Error("Expected no cutoff while MaxLogAge is unset")
+	}
+
+	SetMaxLogAge(time.Hour)
+	tests := []struct {
+		ts   string
+		want bool
+	}{
+		{"2024-05-01 11:30:00.123456+0000", false},
+		{"2024-05-01 10:59:59.000000+0000", true},
+		{"2024-05-01 13:30:00.000000+0200", false}, // 11:30 UTC
+		{"2024-05-01T10:00:00.0000000Z", true},
+		{"2024-05-01T11:45:00Z", false},
+		{"not a timestamp", false},
+	}
+	for _, tt := range tests {
+		if got := logTooOld(tt.ts, now); got != tt.want {
+			t.Errorf("logTooOld(%q) = %v, want %v", tt.ts, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeLogTimestamp(t *testing.T) {
+	tests := []struct{ in, want string }{
+		// As printed by `log show --style json` on macOS 14.
+		{"2024-01-01 12:00:00.123456-0800", "2024-01-01T20:00:00.123456Z"},
+		{"2024-05-01T10:00:00.5+02:00", "2024-05-01T08:00:00.5Z"},
+		{"not a timestamp", "not a timestamp"},
+	}
+	for _, tt := range tests {
+		if got := normalizeLogTimestamp(tt.in); got != tt.want {
+			t.Errorf("normalizeLogTimestamp(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package collector
+
+import "fmt"
+
+// CollectLogCounts records how many log entries the log store (counter)
+// received over the last duration, per level, as the log_event_count
+// metric. Run it after CollectLogs so log volume and error rates can be
+// charted as trends.
+func CollectLogCounts(counter LogCounter, database MetricSink, duration string) error {
+	dur := collectWindow(duration)
+	window := fmt.Sprintf("%ds", int(dur.Seconds()))
+
+	counts, err := counter.CountLogs("*", window, "messageType")
+	if err != nil {
+		return fmt.Errorf("failed to count logs: %v", err)
+	}
+
+	out := newMetricBatch(database, nil)
+	for level, n := range counts {
+		if level == "" {
+			level = "unknown"
+		}
+		out.InsertMetric("log_event_count", n, map[string]string{"host": "localhost", "level": level})
+	}
+	return out.Flush()
+}
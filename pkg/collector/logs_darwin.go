@@ -3,9 +3,11 @@
 package collector
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os/exec"
+	"strings"
+	"sync"
 	"time"
 	"zenith/pkg/db"
 )
@@ -21,28 +23,88 @@ type LogShowEntry struct {
 	EventMessage string `json:"eventMessage"`
 }
 
-func CollectLogs(database *db.VictoriaDB, duration string) error {
-	dur, err := time.ParseDuration(duration)
-	if err != nil {
-		dur = 5 * time.Minute
+// logEntry converts a `log show` entry to the db.LogEntry stored in
+// VictoriaLogs.
+func (raw LogShowEntry) logEntry() db.LogEntry {
+	return db.LogEntry{
+		Timestamp:    normalizeLogTimestamp(raw.Timestamp),
+		ProcessID:    raw.ProcessID,
+		ProcessName:  raw.ProcessName,
+		Subsystem:    raw.Subsystem,
+		Category:     raw.Category,
+		LogLevel:     fmt.Sprintf("%d", raw.LogLevel),
+		EventMessage: raw.EventMessage,
+	}
+}
+
+// logShowBackoff is how long log collection pauses after `log show` is
+// refused for lack of permission. Retrying every cycle only repeats the error.
+const logShowBackoff = time.Hour
+
+var (
+	logShowMu           sync.Mutex
+	logShowBlockedUntil time.Time
+	logShowWarned       bool
+)
+
+// isLogShowPermissionDenied reports whether `log show` failed because the
+// process may not read the unified log, as opposed to any other failure.
+func isLogShowPermissionDenied(stderr string) bool {
+	lower := strings.ToLower(stderr)
+	for _, s := range []string{"not permitted", "permission denied", "must be admin", "must be root", "full disk access"} {
+		if strings.Contains(lower, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// logShowDenied records a permission failure and returns the error to
+// report: full instructions the first time, a short reminder afterwards.
+func logShowDenied(stderr string) error {
+	logShowMu.Lock()
+	defer logShowMu.Unlock()
+	logShowBlockedUntil = time.Now().Add(logShowBackoff)
+	if logShowWarned {
+		return fmt.Errorf("log show still lacks permission to read the unified log; retrying in %s", logShowBackoff)
 	}
+	logShowWarned = true
+	return fmt.Errorf("log show was denied access to the unified log (%s). "+
+		"Grant Full Disk Access to zenith-server (or the terminal that runs it) in "+
+		"System Settings > Privacy & Security > Full Disk Access, or run it as an administrator. "+
+		"Log collection will retry in %s", strings.TrimSpace(stderr), logShowBackoff)
+}
+
+func CollectLogs(database LogSink, duration string) error {
+	logShowMu.Lock()
+	blocked := time.Now().Before(logShowBlockedUntil)
+	logShowMu.Unlock()
+	if blocked {
+		return nil
+	}
+
+	dur := collectWindow(duration)
 
 	// Calculate the last N minutes/hours for `log show`
 	// `log show` uses a specific format for --last
 	lastArg := fmt.Sprintf("%ds", int(dur.Seconds()))
 
-	cmd := exec.Command("log", "show", "--last", lastArg, "--style", "json")
-	output, err := cmd.Output()
+	args := []string{"show", "--last", lastArg, "--style", "json"}
+	if predicate := currentLogShowPredicate(); predicate != "" {
+		args = append(args, "--predicate", predicate)
+	}
+	cmd := exec.Command("log", args...)
+	output, err := collectionOutput(cmd)
 	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && isLogShowPermissionDenied(string(exitErr.Stderr)) {
+			return logShowDenied(string(exitErr.Stderr))
+		}
 		return fmt.Errorf("failed to run log show: %v", err)
 	}
 
-	if len(output) == 0 {
-		return nil
-	}
-
-	var rawEntries []LogShowEntry
-	if err := json.Unmarshal(output, &rawEntries); err != nil {
+	rawEntries, err := decodeJSONArrayOrObject[LogShowEntry](output)
+	if err != nil {
 		return fmt.Errorf("failed to parse log JSON: %v", err)
 	}
 
@@ -50,15 +112,13 @@ func CollectLogs(database *db.VictoriaDB, duration string) error {
 		return nil
 	}
 
+	now := time.Now()
 	var logs []db.LogEntry
 	for _, raw := range rawEntries {
-		logs = append(logs, db.LogEntry{
-			Timestamp:    raw.Timestamp,
-			ProcessName:  raw.ProcessName,
-			Category:     raw.Category,
-			LogLevel:     fmt.Sprintf("%d", raw.LogLevel),
-			EventMessage: raw.EventMessage,
-		})
+		if logTooOld(raw.Timestamp, now) {
+			continue
+		}
+		logs = append(logs, raw.logEntry())
 	}
 
 	if len(logs) > 0 {
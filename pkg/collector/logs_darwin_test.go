@@ -17,3 +17,42 @@ func TestCollectLogsDarwin(t *testing.T) {
 
 	t.Log("Successfully called CollectLogs without error")
 }
+
+func TestIsLogShowPermissionDenied(t *testing.T) {
+	denied := []string{
+		"log: Operation not permitted",
+		"log: Must be admin to run 'show' command",
+	}
+	for _, stderr := range denied {
+		if !isLogShowPermissionDenied(stderr) {
+			t.Errorf("Expected %q to be a permission failure", stderr)
+		}
+	}
+	if isLogShowPermissionDenied("log: Could not open local log store: No such file or directory") {
+		t.Error("Expected a missing log store not to be a permission failure")
+	}
+}
+
+func TestLogShowEntry_LogEntry(t *testing.T) {
+	raw := LogShowEntry{
+		Timestamp:    "2024-05-01 10:15:00.123456-0700",
+		ProcessID:    42,
+		ProcessName:  "/usr/libexec/wifid",
+		Subsystem:    "com.apple.wifi",
+		Category:     "scan",
+		LogLevel:     16,
+		EventMessage: "scan complete",
+	}
+	want := db.LogEntry{
+		Timestamp:    normalizeLogTimestamp(raw.Timestamp),
+		ProcessID:    42,
+		ProcessName:  "/usr/libexec/wifid",
+		Subsystem:    "com.apple.wifi",
+		Category:     "scan",
+		LogLevel:     "16",
+		EventMessage: "scan complete",
+	}
+	if got := raw.logEntry(); got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+}
@@ -0,0 +1,128 @@
+//go:build linux
+
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"time"
+	"zenith/pkg/db"
+)
+
+// JournalEntry is the subset of a `journalctl --output json` record Zenith
+// keeps. journald encodes every field as a string.
+type JournalEntry struct {
+	RealtimeTimestamp string          `json:"__REALTIME_TIMESTAMP"`
+	PID               string          `json:"_PID"`
+	Comm              string          `json:"_COMM"`
+	SyslogIdentifier  string          `json:"SYSLOG_IDENTIFIER"`
+	Priority          string          `json:"PRIORITY"`
+	Message           json.RawMessage `json:"MESSAGE"`
+}
+
+// journalPriorities maps syslog PRIORITY values to the level names the
+// Windows collector uses, so messageType filters work across platforms.
+var journalPriorities = map[string]string{
+	"0": "critical", "1": "critical", "2": "critical",
+	"3": "error",
+	"4": "warning",
+	"5": "info", "6": "info",
+	"7": "debug",
+}
+
+func CollectLogs(database LogSink, duration string) error {
+	dur := collectWindow(duration)
+
+	since := fmt.Sprintf("%d seconds ago", int(dur.Seconds()))
+	cmd := exec.Command("journalctl", "--since", since, "--output", "json", "--no-pager")
+	output, err := collectionOutput(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to run journalctl: %v", err)
+	}
+
+	logs, err := parseJournal(output, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to parse journal JSON: %v", err)
+	}
+
+	if len(logs) > 0 {
+		if err := database.InsertLogs(logs); err != nil {
+			return fmt.Errorf("failed to insert logs: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// parseJournal converts journalctl's newline-delimited JSON records into
+// log entries, dropping any older than the max log age.
+func parseJournal(output []byte, now time.Time) ([]db.LogEntry, error) {
+	var logs []db.LogEntry
+	dec := json.NewDecoder(bytes.NewReader(output))
+	for {
+		var raw JournalEntry
+		if err := dec.Decode(&raw); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return logs, err
+		}
+
+		entry, ok := raw.logEntry()
+		if !ok || logTooOld(entry.Timestamp, now) {
+			continue
+		}
+		logs = append(logs, entry)
+	}
+	return logs, nil
+}
+
+// logEntry converts a journald record to the db.LogEntry stored in
+// VictoriaLogs. It reports false for a record without a usable timestamp.
+func (raw JournalEntry) logEntry() (db.LogEntry, bool) {
+	usec, err := strconv.ParseInt(raw.RealtimeTimestamp, 10, 64)
+	if err != nil {
+		return db.LogEntry{}, false
+	}
+
+	name := raw.Comm
+	if name == "" {
+		name = raw.SyslogIdentifier
+	}
+	level, ok := journalPriorities[raw.Priority]
+	if !ok {
+		level = "info"
+	}
+	pid, _ := strconv.Atoi(raw.PID)
+
+	return db.LogEntry{
+		Timestamp:    time.UnixMicro(usec).UTC().Format(time.RFC3339Nano),
+		ProcessID:    pid,
+		ProcessName:  name,
+		Subsystem:    raw.SyslogIdentifier,
+		LogLevel:     level,
+		EventMessage: journalMessage(raw.Message),
+	}, true
+}
+
+// journalMessage decodes MESSAGE, which journald emits as an array of bytes
+// instead of a string when the message isn't valid UTF-8.
+func journalMessage(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var ints []int
+	if err := json.Unmarshal(raw, &ints); err == nil {
+		b := make([]byte, 0, len(ints))
+		for _, v := range ints {
+			b = append(b, byte(v))
+		}
+		return string(b)
+	}
+	return ""
+}
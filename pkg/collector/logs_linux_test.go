@@ -0,0 +1,35 @@
+//go:build linux
+
+package collector
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseJournal(t *testing.T) {
+	output := []byte(`{"__REALTIME_TIMESTAMP":"1714564800123456","_PID":"812","_COMM":"sshd","SYSLOG_IDENTIFIER":"sshd","PRIORITY":"3","MESSAGE":"error: maximum authentication attempts exceeded"}
+{"__REALTIME_TIMESTAMP":"1714564801000000","SYSLOG_IDENTIFIER":"kernel","PRIORITY":"6","MESSAGE":[104,105,255]}
+`)
+
+	logs, err := parseJournal(output, time.Now())
+	if err != nil {
+		t.Fatalf("parseJournal failed: %v", err)
+	}
+	if len(logs) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(logs))
+	}
+
+	first := logs[0]
+	if first.Timestamp != "2024-05-01T12:00:00.123456Z" {
+		t.Errorf("Unexpected timestamp %q", first.Timestamp)
+	}
+	if first.ProcessName != "sshd" || first.ProcessID != 812 || first.LogLevel != "error" {
+		t.Errorf("Unexpected entry %+v", first)
+	}
+
+	second := logs[1]
+	if second.ProcessName != "kernel" || second.LogLevel != "info" || second.EventMessage != "hi\xff" {
+		t.Errorf("Unexpected entry %+v", second)
+	}
+}
@@ -4,7 +4,10 @@ package collector
 
 import (
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 	"unsafe"
@@ -21,14 +24,29 @@ var (
 	procEvtClose  = modwevtapi.NewProc("EvtClose")
 	procEvtNext   = modwevtapi.NewProc("EvtNext")
 	procEvtRender = modwevtapi.NewProc("EvtRender")
+
+	procEvtOpenPublisherMetadata = modwevtapi.NewProc("EvtOpenPublisherMetadata")
+	procEvtFormatMessage         = modwevtapi.NewProc("EvtFormatMessage")
 )
 
 const (
 	EvtQueryChannelPath      = 0x1
 	EvtQueryReverseDirection = 0x200
 	EvtRenderEventXml        = 1
+	EvtFormatMessageEvent    = 1
 )
 
+// EvtFormatMessage errors that still leave a usable message in the buffer,
+// with the unresolved inserts left as %1-style placeholders.
+const (
+	errorEvtUnresolvedValueInsert     syscall.Errno = 15029
+	errorEvtUnresolvedParameterInsert syscall.Errno = 15030
+	errorEvtMaxInsertsReached         syscall.Errno = 15031
+)
+
+// errorEvtChannelNotFound is ERROR_EVT_CHANNEL_NOT_FOUND.
+const errorEvtChannelNotFound syscall.Errno = 15007
+
 func EvtQuery(session windows.Handle, path *uint16, query *uint16, flags uint32) (windows.Handle, error) {
 	r0, _, e1 := syscall.Syscall6(procEvtQuery.Addr(), 4, uintptr(session), uintptr(unsafe.Pointer(path)), uintptr(unsafe.Pointer(query)), uintptr(flags), 0, 0)
 	handle := windows.Handle(r0)
@@ -74,6 +92,29 @@ func EvtRender(context windows.Handle, fragment windows.Handle, flags uint32, bu
 	return nil
 }
 
+func EvtOpenPublisherMetadata(session windows.Handle, publisherID *uint16, logFilePath *uint16, locale uint32, flags uint32) (windows.Handle, error) {
+	r0, _, e1 := syscall.Syscall6(procEvtOpenPublisherMetadata.Addr(), 5, uintptr(session), uintptr(unsafe.Pointer(publisherID)), uintptr(unsafe.Pointer(logFilePath)), uintptr(locale), uintptr(flags), 0)
+	handle := windows.Handle(r0)
+	if handle == 0 {
+		if e1 != 0 {
+			return 0, error(e1)
+		}
+		return 0, syscall.EINVAL
+	}
+	return handle, nil
+}
+
+func EvtFormatMessage(publisherMetadata windows.Handle, event windows.Handle, messageID uint32, valueCount uint32, values uintptr, flags uint32, bufferSize uint32, buffer *uint16, bufferUsed *uint32) error {
+	r1, _, e1 := syscall.Syscall9(procEvtFormatMessage.Addr(), 9, uintptr(publisherMetadata), uintptr(event), uintptr(messageID), uintptr(valueCount), values, uintptr(flags), uintptr(bufferSize), uintptr(unsafe.Pointer(buffer)), uintptr(unsafe.Pointer(bufferUsed)))
+	if r1 == 0 {
+		if e1 != 0 {
+			return error(e1)
+		}
+		return syscall.EINVAL
+	}
+	return nil
+}
+
 // Windows Event Log XML Structure
 type WinEventXML struct {
 	System struct {
@@ -97,46 +138,71 @@ type WinEventXML struct {
 	} `xml:"RenderingInfo"`
 }
 
-func CollectLogs(database *db.VictoriaDB, duration string) error {
-	// Query channels "System" and "Application" for recent events
-	channels := []string{"System", "Application"}
+func CollectLogs(database LogSink, duration string) error {
 
 	// Calculate start time based on duration (simple approximation for query)
 	// Real query syntax: *[System[TimeCreated[timediff(@SystemTime) <= 300000]]] (300000ms = 5m)
 	// We'll simplify to just getting the last N records if timediff is hard in pure query,
 	// but XPath 1.0 subset in EvtQuery supports timediff.
 
-	// Default 5m = 300000ms
-	ms := int64(300000)
-	dur, err := time.ParseDuration(duration)
-	if err == nil {
-		ms = dur.Milliseconds()
-	}
+	ms := collectWindow(duration).Milliseconds()
 
 	query := fmt.Sprintf("*[System[TimeCreated[timediff(@SystemTime) <= %d]]]", ms)
 
-	for _, channel := range channels {
+	for _, channel := range currentEventLogChannels() {
+		if channelUnavailable(channel) {
+			continue
+		}
 		if err := collectChannelLogs(database, channel, query); err != nil {
+			if errors.Is(err, windows.ERROR_ACCESS_DENIED) || errors.Is(err, errorEvtChannelNotFound) {
+				markChannelUnavailable(channel)
+				collectErrors.Printf("warning: skipping event log channel %s from now on: %v\n", channel, err)
+				continue
+			}
 			// Log error but continue to next channel
-			fmt.Printf("failed to collect logs from channel %s: %v\n", channel, err)
+			collectErrors.Printf("failed to collect logs from channel %s: %v\n", channel, err)
 		}
 	}
 
 	return nil
 }
 
-func collectChannelLogs(database *db.VictoriaDB, channel, query string) error {
+var (
+	unavailableMu       sync.Mutex
+	unavailableChannels = make(map[string]bool)
+)
+
+// channelUnavailable reports whether channel failed in a way that won't fix
+// itself: it doesn't exist, or reading it needs rights the server lacks (as
+// Security does without admin).
+func channelUnavailable(channel string) bool {
+	unavailableMu.Lock()
+	defer unavailableMu.Unlock()
+	return unavailableChannels[channel]
+}
+
+func markChannelUnavailable(channel string) {
+	unavailableMu.Lock()
+	defer unavailableMu.Unlock()
+	unavailableChannels[channel] = true
+}
+
+func collectChannelLogs(database LogSink, channel, query string) error {
 	path, _ := syscall.UTF16PtrFromString(channel)
 	q, _ := syscall.UTF16PtrFromString(query)
 
 	hSubscription, err := EvtQuery(0, path, q, EvtQueryChannelPath|EvtQueryReverseDirection)
 	if err != nil {
-		return fmt.Errorf("EvtQuery failed: %v", err)
+		return fmt.Errorf("EvtQuery failed: %w", err)
 	}
 	defer EvtClose(hSubscription)
 
 	events := make([]windows.Handle, 10)
 	var returned uint32
+	var logs []db.LogEntry
+
+	publishers := make(publisherCache)
+	defer publishers.close()
 
 	for {
 		err := EvtNext(hSubscription, uint32(len(events)), &events[0], 2000, 0, &returned)
@@ -161,40 +227,49 @@ func collectChannelLogs(database *db.VictoriaDB, channel, query string) error {
 			if err := xml.Unmarshal([]byte(xmlContent), &event); err != nil {
 				continue
 			}
-
-			// Map Windows Event Level to something VictoriaLogs can filter on
-			// 1: Critical, 2: Error, 3: Warning, 4: Information, 5: Verbose
-			levelStr := "info"
-			switch event.System.Level {
-			case 1:
-				levelStr = "critical"
-			case 2:
-				levelStr = "error"
-			case 3:
-				levelStr = "warning"
-			case 4:
-				levelStr = "info"
-			case 5:
-				levelStr = "debug"
+			if logTooOld(event.System.TimeCreated.SystemTime, time.Now()) {
+				continue
 			}
 
-			// Format for VictoriaLogs
-			entry := db.LogEntry{
-				Timestamp:   event.System.TimeCreated.SystemTime,
-				ProcessName: event.System.Provider.Name,
-				Category:    fmt.Sprintf("EventID: %d", event.System.EventID),
-				LogLevel:    levelStr,
-				// Message rendering requires a publisher metadata handle which is complex.
-				// We'll use the provider name and EventID as the core message for now,
-				// or if RenderingInfo is present (rare without explicit format render).
-				EventMessage: fmt.Sprintf("EventID %d from %s", event.System.EventID, event.System.Provider.Name),
-			}
-			database.InsertLog(entry)
+			logs = append(logs, event.logEntry(eventMessage(publishers, eventHandle, event)))
+		}
+	}
+	if len(logs) > 0 {
+		if err := database.InsertLogs(logs); err != nil {
+			return fmt.Errorf("failed to insert logs: %v", err)
 		}
 	}
 	return nil
 }
 
+// logEntry converts an event to the db.LogEntry stored in VictoriaLogs,
+// with message as its rendered text.
+func (event WinEventXML) logEntry(message string) db.LogEntry {
+	// Map Windows Event Level to something VictoriaLogs can filter on
+	// 1: Critical, 2: Error, 3: Warning, 4: Information, 5: Verbose
+	levelStr := "info"
+	switch event.System.Level {
+	case 1:
+		levelStr = "critical"
+	case 2:
+		levelStr = "error"
+	case 3:
+		levelStr = "warning"
+	case 4:
+		levelStr = "info"
+	case 5:
+		levelStr = "debug"
+	}
+
+	return db.LogEntry{
+		Timestamp:    event.System.TimeCreated.SystemTime,
+		ProcessName:  event.System.Provider.Name,
+		Category:     fmt.Sprintf("EventID: %d", event.System.EventID),
+		LogLevel:     levelStr,
+		EventMessage: message,
+	}
+}
+
 func renderEventXML(event windows.Handle) (string, error) {
 	var bufferSize uint32
 	var propertyCount uint32
@@ -213,3 +288,65 @@ func renderEventXML(event windows.Handle) (string, error) {
 
 	return syscall.UTF16ToString(buffer), nil
 }
+
+// publisherCache holds one publisher metadata handle per event provider for
+// a collection pass; 0 records a provider whose metadata can't be opened
+// (e.g. its message DLL was uninstalled), so it isn't retried per event.
+type publisherCache map[string]windows.Handle
+
+func (c publisherCache) get(provider string) windows.Handle {
+	if h, ok := c[provider]; ok {
+		return h
+	}
+	var h windows.Handle
+	if name, err := syscall.UTF16PtrFromString(provider); err == nil {
+		h, _ = EvtOpenPublisherMetadata(0, name, nil, 0, 0)
+	}
+	c[provider] = h
+	return h
+}
+
+func (c publisherCache) close() {
+	for _, h := range c {
+		if h != 0 {
+			EvtClose(h)
+		}
+	}
+}
+
+// eventMessage renders an event's human-readable message from its
+// publisher's message table, falling back to the message embedded in the
+// event's RenderingInfo and then to "EventID N from Provider".
+func eventMessage(publishers publisherCache, eventHandle windows.Handle, event WinEventXML) string {
+	if meta := publishers.get(event.System.Provider.Name); meta != 0 {
+		if msg, err := formatEventMessage(meta, eventHandle); err == nil && msg != "" {
+			return msg
+		}
+	}
+	if msg := strings.TrimSpace(event.RenderingInfo.Message); msg != "" {
+		return msg
+	}
+	return fmt.Sprintf("EventID %d from %s", event.System.EventID, event.System.Provider.Name)
+}
+
+// formatEventMessage calls EvtFormatMessage for the event's message text.
+func formatEventMessage(meta, event windows.Handle) (string, error) {
+	var used uint32
+	err := EvtFormatMessage(meta, event, 0, 0, 0, EvtFormatMessageEvent, 0, nil, &used)
+	if err != windows.ERROR_INSUFFICIENT_BUFFER {
+		if err == nil {
+			err = syscall.EINVAL
+		}
+		return "", err
+	}
+
+	// The sizes EvtFormatMessage reports are in characters, not bytes.
+	buffer := make([]uint16, used)
+	err = EvtFormatMessage(meta, event, 0, 0, 0, EvtFormatMessageEvent, used, &buffer[0], &used)
+	switch err {
+	case nil, errorEvtUnresolvedValueInsert, errorEvtUnresolvedParameterInsert, errorEvtMaxInsertsReached:
+	default:
+		return "", err
+	}
+	return strings.TrimSpace(syscall.UTF16ToString(buffer)), nil
+}
@@ -0,0 +1,71 @@
+package collector
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// macOS log store scopes, named after OSLogStore's.
+const (
+	MacOSLogScopeSystem         = "system"
+	MacOSLogScopeCurrentProcess = "currentProcessIdentifier"
+)
+
+var (
+	logScopeMu         sync.Mutex
+	macOSLogScope      = MacOSLogScopeSystem
+	macOSLogSubsystems []string
+)
+
+// SetMacOSLogScope chooses which macOS logs CollectLogs reads: the whole
+// system store ("system", the default) or only zenith-server's own entries
+// ("currentProcessIdentifier"), optionally narrowed to subsystems. It has no
+// effect on other platforms.
+func SetMacOSLogScope(scope string, subsystems []string) error {
+	if scope == "" {
+		scope = MacOSLogScopeSystem
+	}
+	if scope != MacOSLogScopeSystem && scope != MacOSLogScopeCurrentProcess {
+		return fmt.Errorf("invalid macOS log scope %q, expected %q or %q", scope, MacOSLogScopeSystem, MacOSLogScopeCurrentProcess)
+	}
+	for _, s := range subsystems {
+		if strings.TrimSpace(s) == "" {
+			return fmt.Errorf("empty macOS log subsystem")
+		}
+	}
+	logScopeMu.Lock()
+	defer logScopeMu.Unlock()
+	macOSLogScope, macOSLogSubsystems = scope, subsystems
+	return nil
+}
+
+// logShowPredicate builds the `log show --predicate` filter for scope and
+// subsystems, or "" to read everything.
+func logShowPredicate(scope string, subsystems []string, pid int) string {
+	var clauses []string
+	if scope == MacOSLogScopeCurrentProcess {
+		clauses = append(clauses, "processIdentifier == "+strconv.Itoa(pid))
+	}
+	if len(subsystems) > 0 {
+		matches := make([]string, len(subsystems))
+		for i, s := range subsystems {
+			matches[i] = "subsystem == " + strconv.Quote(s)
+		}
+		if len(matches) == 1 || len(clauses) == 0 {
+			clauses = append(clauses, strings.Join(matches, " OR "))
+		} else {
+			clauses = append(clauses, "("+strings.Join(matches, " OR ")+")")
+		}
+	}
+	return strings.Join(clauses, " AND ")
+}
+
+// currentLogShowPredicate is logShowPredicate for the configured scope.
+func currentLogShowPredicate() string {
+	logScopeMu.Lock()
+	defer logScopeMu.Unlock()
+	return logShowPredicate(macOSLogScope, macOSLogSubsystems, os.Getpid())
+}
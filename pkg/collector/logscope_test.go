@@ -0,0 +1,39 @@
+package collector
+
+import "testing"
+
+func TestLogShowPredicate(t *testing.T) {
+	tests := []struct {
+		scope      string
+		subsystems []string
+		want       string
+	}{
+		{MacOSLogScopeSystem, nil, ""},
+		{MacOSLogScopeCurrentProcess, nil, "processIdentifier == 42"},
+		{MacOSLogScopeSystem, []string{"com.apple.wifi"}, `subsystem == "com.apple.wifi"`},
+		{MacOSLogScopeSystem, []string{"com.apple.wifi", "com.example.app"}, `subsystem == "com.apple.wifi" OR subsystem == "com.example.app"`},
+		{MacOSLogScopeCurrentProcess, []string{"com.apple.wifi", "com.example.app"}, `processIdentifier == 42 AND (subsystem == "com.apple.wifi" OR subsystem == "com.example.app")`},
+	}
+	for _, tt := range tests {
+		if got := logShowPredicate(tt.scope, tt.subsystems, 42); got != tt.want {
+			t.Errorf("logShowPredicate(%q, %q) = %q, want %q", tt.scope, tt.subsystems, got, tt.want)
+		}
+	}
+}
+
+func TestSetMacOSLogScope(t *testing.T) {
+	defer SetMacOSLogScope("", nil)
+
+	if err := SetMacOSLogScope("user", nil); err == nil {
+		t.Error("Expected an unknown scope to be rejected")
+	}
+	if err := SetMacOSLogScope(MacOSLogScopeSystem, []string{" "}); err == nil {
+		t.Error("Expected an empty subsystem to be rejected")
+	}
+	if err := SetMacOSLogScope("", []string{"com.apple.wifi"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := currentLogShowPredicate(); got != `subsystem == "com.apple.wifi"` {
+		t.Errorf("Expected the empty scope to default to system, got predicate %q", got)
+	}
+}
@@ -3,47 +3,46 @@
 package collector
 
 import (
-	"fmt"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"time"
 
-	"zenith/pkg/db"
-
-	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/disk"
 	"github.com/shirou/gopsutil/v4/mem"
 	"github.com/shirou/gopsutil/v4/process"
+	"golang.org/x/sys/unix"
 )
 
-func CollectMetrics(database *db.VictoriaDB) error {
+func CollectMetrics(database MetricSink) error {
 	if err := collectCPUMetrics(database); err != nil {
-		fmt.Printf("failed to collect CPU metrics: %v\n", err)
+		collectErrors.Printf("failed to collect CPU metrics: %v\n", err)
 	}
 
 	if err := collectMemoryMetrics(database); err != nil {
-		fmt.Printf("failed to collect memory metrics: %v\n", err)
+		collectErrors.Printf("failed to collect memory metrics: %v\n", err)
 	}
 
-	if err := CollectProcessMetrics(database); err != nil {
-		fmt.Printf("failed to collect process metrics: %v\n", err)
+	if err := collectSystemFDMetrics(database); err != nil {
+		collectErrors.Printf("failed to collect file descriptor metrics: %v\n", err)
 	}
 
-	return nil
-}
+	if err := CollectProcessMetrics(database); err != nil {
+		collectErrors.Printf("failed to collect process metrics: %v\n", err)
+	}
 
-func collectCPUMetrics(database *db.VictoriaDB) error {
-	percent, err := cpu.Percent(time.Second, false)
-	if err != nil {
-		return err
+	if err := collectThermalMetrics(database); err != nil {
+		collectErrors.Printf("failed to collect thermal metrics: %v\n", err)
 	}
-	if len(percent) > 0 {
-		labels := map[string]string{"host": "localhost"}
-		return database.InsertMetric("cpu_usage_pct", percent[0], labels)
+
+	if err := collectDiskIOMetrics(database); err != nil {
+		collectErrors.Printf("failed to collect disk I/O metrics: %v\n", err)
 	}
+
 	return nil
 }
 
-func collectMemoryMetrics(database *db.VictoriaDB) error {
+func collectMemoryMetrics(database MetricSink) error {
 	v, err := mem.VirtualMemory()
 	if err != nil {
 		return err
@@ -55,15 +54,47 @@ func collectMemoryMetrics(database *db.VictoriaDB) error {
 	return nil
 }
 
-func CollectProcessMetrics(database *db.VictoriaDB) error {
+// diskIO keeps the previous cycle's counters for collectDiskIOMetrics.
+var diskIO diskIOTracker
+
+// collectDiskIOMetrics records each disk's read and write rate from the
+// IOBlockStorageDriver statistics (the counters `ioreg` and `iostat` show).
+func collectDiskIOMetrics(database MetricSink) error {
+	counters, err := disk.IOCounters()
+	if err != nil {
+		return err
+	}
+	cur := make(map[string]diskCounters, len(counters))
+	for name, c := range counters {
+		cur[name] = diskCounters{Read: c.ReadBytes, Written: c.WriteBytes}
+	}
+	return diskIO.record(database, cur, time.Now())
+}
+
+// collectSystemFDMetrics records the number of open files system-wide, as
+// counted by the kernel (kern.num_files).
+func collectSystemFDMetrics(database MetricSink) error {
+	n, err := unix.SysctlUint32("kern.num_files")
+	if err != nil {
+		return err
+	}
+	database.InsertMetric("system_open_fds", float64(n), map[string]string{"host": "localhost"})
+	return nil
+}
+
+func CollectProcessMetrics(database MetricSink) error {
 	procs, err := process.Processes()
 	if err != nil {
 		return err
 	}
 
+	// Write every process's samples in one request, recording the series so
+	// vanished processes can be marked stale.
+	out := newMetricBatch(database, processSeries)
+
 	for _, p := range procs {
 		memInfo, err := p.MemoryInfo()
-		if err != nil || memInfo.RSS < 50*1024*1024 { // 50MB
+		if err != nil || !keepProcess(memInfo.RSS) {
 			continue
 		}
 
@@ -79,18 +110,28 @@ func CollectProcessMetrics(database *db.VictoriaDB) error {
 			"pid":          strconv.Itoa(int(p.Pid)),
 			"process_name": name,
 		}
-		database.InsertMetric("process_memory_mb", float64(memInfo.RSS)/1024/1024, labels)
+		out.InsertMetric("process_memory_mb", float64(memInfo.RSS)/1024/1024, labels)
+
+		if fds, err := p.NumFDs(); err == nil {
+			out.InsertMetric("process_open_fds", float64(fds), labels)
+		}
 
 		cpuPct, err := p.CPUPercent()
-		if err == nil && cpuPct > 1.0 {
-			database.InsertMetric("process_cpu_pct", cpuPct, labels)
+		if err == nil && keepProcessCPU(cpuPct) {
+			// CPUPercent is relative to a single core and can exceed 100 on
+			// multi-core machines; also emit a 0-100 share of the whole machine.
+			out.InsertMetric("process_cpu_pct", cpuPct, labels)
+			out.InsertMetric("process_cpu_pct_normalized", cpuPct/float64(runtime.NumCPU()), labels)
 		}
 	}
-	return nil
+	if err := out.Flush(); err != nil {
+		return err
+	}
+	return processSeries.endCycle(database)
 }
 
 // CollectSrumHistoricalMetrics is a no-op on non-Windows platforms.
 // SRUM is a Windows-only data source.
-func CollectSrumHistoricalMetrics(database *db.VictoriaDB) error {
+func CollectSrumHistoricalMetrics(database MetricSink) error {
 	return nil
 }
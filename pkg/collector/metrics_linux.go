@@ -0,0 +1,273 @@
+//go:build linux
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is USER_HZ, the unit of the jiffy counters in /proc. It is 100
+// on every mainstream architecture and can't be read without cgo.
+const clockTicks = 100
+
+func CollectMetrics(database MetricSink) error {
+	if err := collectCPUMetrics(database); err != nil {
+		collectErrors.Printf("failed to collect CPU metrics: %v\n", err)
+	}
+
+	if err := collectMemoryMetrics(database); err != nil {
+		collectErrors.Printf("failed to collect memory metrics: %v\n", err)
+	}
+
+	if err := collectSystemFDMetrics(database); err != nil {
+		collectErrors.Printf("failed to collect file descriptor metrics: %v\n", err)
+	}
+
+	if err := collectServiceMetrics(database); err != nil {
+		collectErrors.Printf("failed to collect service metrics: %v\n", err)
+	}
+
+	if err := CollectProcessMetrics(database); err != nil {
+		collectErrors.Printf("failed to collect process metrics: %v\n", err)
+	}
+
+	return nil
+}
+
+func collectMemoryMetrics(database MetricSink) error {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := parseMeminfo(f)
+	if err != nil {
+		return err
+	}
+
+	// Match gopsutil on the other platforms: used excludes reclaimable
+	// cache (MemTotal - MemAvailable), free is MemFree.
+	total, available, free := info["MemTotal"], info["MemAvailable"], info["MemFree"]
+	if _, ok := info["MemAvailable"]; !ok {
+		available = free + info["Buffers"] + info["Cached"]
+	}
+
+	labels := map[string]string{"host": "localhost"}
+	database.InsertMetric("memory_used_mb", float64(total-available)/1024, labels)
+	database.InsertMetric("memory_free_mb", float64(free)/1024, labels)
+	return nil
+}
+
+// parseMeminfo reads /proc/meminfo-style "Key:   value kB" lines into a map
+// of kilobyte values.
+func parseMeminfo(r io.Reader) (map[string]uint64, error) {
+	info := make(map[string]uint64)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, rest, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		info[key] = v
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if _, ok := info["MemTotal"]; !ok {
+		return nil, fmt.Errorf("no MemTotal in /proc/meminfo")
+	}
+	return info, nil
+}
+
+// collectSystemFDMetrics records the number of allocated file handles
+// system-wide, the first field of /proc/sys/fs/file-nr.
+func collectSystemFDMetrics(database MetricSink) error {
+	data, err := os.ReadFile("/proc/sys/fs/file-nr")
+	if err != nil {
+		return err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return fmt.Errorf("empty /proc/sys/fs/file-nr")
+	}
+	n, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return err
+	}
+	database.InsertMetric("system_open_fds", float64(n), map[string]string{"host": "localhost"})
+	return nil
+}
+
+// procSample is one reading of a process from /proc/[pid].
+type procSample struct {
+	name    string
+	jiffies uint64 // utime + stime
+	rssKB   uint64
+}
+
+// readProc reads the name and RSS from /proc/[pid]/status and the CPU
+// jiffies from /proc/[pid]/stat.
+func readProc(pid int) (procSample, error) {
+	dir := filepath.Join("/proc", strconv.Itoa(pid))
+
+	stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return procSample{}, err
+	}
+	jiffies, err := parseProcStatJiffies(string(stat))
+	if err != nil {
+		return procSample{}, err
+	}
+
+	f, err := os.Open(filepath.Join(dir, "status"))
+	if err != nil {
+		return procSample{}, err
+	}
+	defer f.Close()
+	name, rssKB, err := parseProcStatus(f)
+	if err != nil {
+		return procSample{}, err
+	}
+
+	return procSample{name: name, jiffies: jiffies, rssKB: rssKB}, nil
+}
+
+// parseProcStatJiffies returns utime + stime from a /proc/[pid]/stat line.
+// The command name in field 2 may contain spaces and parentheses, so fields
+// are counted from the last ')'.
+func parseProcStatJiffies(stat string) (uint64, error) {
+	end := strings.LastIndexByte(stat, ')')
+	if end < 0 {
+		return 0, fmt.Errorf("malformed stat line")
+	}
+	// fields[0] is field 3 (state); utime and stime are fields 14 and 15.
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 13 {
+		return 0, fmt.Errorf("short stat line")
+	}
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return utime + stime, nil
+}
+
+// parseProcStatus returns the Name and VmRSS (in kB) from /proc/[pid]/status.
+// Kernel threads have no VmRSS and report 0.
+func parseProcStatus(r io.Reader) (name string, rssKB uint64, err error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "Name":
+			name = value
+		case "VmRSS":
+			if fields := strings.Fields(value); len(fields) > 0 {
+				rssKB, _ = strconv.ParseUint(fields[0], 10, 64)
+			}
+		}
+	}
+	return name, rssKB, scanner.Err()
+}
+
+func listPIDs() ([]int, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	var pids []int
+	for _, e := range entries {
+		if pid, err := strconv.Atoi(e.Name()); err == nil && e.IsDir() {
+			pids = append(pids, pid)
+		}
+	}
+	return pids, nil
+}
+
+func CollectProcessMetrics(database MetricSink) error {
+	pids, err := listPIDs()
+	if err != nil {
+		return err
+	}
+
+	// Write every process's samples in one request, recording the series so
+	// vanished processes can be marked stale.
+	out := newMetricBatch(database, processSeries)
+
+	// Sample every process's jiffies twice to get CPU usage over the interval.
+	before := make(map[int]uint64, len(pids))
+	for _, pid := range pids {
+		if s, err := readProc(pid); err == nil {
+			before[pid] = s.jiffies
+		}
+	}
+	start := time.Now()
+	time.Sleep(processSampleInterval)
+	elapsed := time.Since(start).Seconds()
+
+	for _, pid := range pids {
+		prev, ok := before[pid]
+		if !ok {
+			continue
+		}
+		s, err := readProc(pid)
+		if err != nil || !keepProcess(s.rssKB*1024) {
+			continue
+		}
+
+		labels := map[string]string{
+			"pid":          strconv.Itoa(pid),
+			"process_name": s.name,
+		}
+		out.InsertMetric("process_memory_mb", float64(s.rssKB)/1024, labels)
+
+		if fds, err := os.ReadDir(filepath.Join("/proc", strconv.Itoa(pid), "fd")); err == nil {
+			out.InsertMetric("process_open_fds", float64(len(fds)), labels)
+		}
+
+		if s.jiffies < prev {
+			continue
+		}
+		// Percent of one core, as on the other platforms.
+		cpuPct := float64(s.jiffies-prev) / clockTicks / elapsed * 100
+		if keepProcessCPU(cpuPct) {
+			out.InsertMetric("process_cpu_pct", cpuPct, labels)
+			out.InsertMetric("process_cpu_pct_normalized", cpuPct/float64(runtime.NumCPU()), labels)
+		}
+	}
+	if err := out.Flush(); err != nil {
+		return err
+	}
+	return processSeries.endCycle(database)
+}
+
+// CollectSrumHistoricalMetrics is a no-op on non-Windows platforms.
+// SRUM is a Windows-only data source.
+func CollectSrumHistoricalMetrics(database MetricSink) error {
+	return nil
+}
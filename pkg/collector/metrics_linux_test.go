@@ -0,0 +1,60 @@
+//go:build linux
+
+package collector
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCPUTimes(t *testing.T) {
+	stat := "cpu  100 10 50 800 40 0 0 0 0 0\ncpu0 50 5 25 400 20 0 0 0 0 0\n"
+	before, err := parseCPUTimes(strings.NewReader(stat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before.busy != 160 || before.total != 1000 {
+		t.Errorf("Expected busy=160 total=1000, got %+v", before)
+	}
+
+	after := cpuTimes{busy: before.busy + 25, total: before.total + 100}
+	if got := cpuBusyPercent(before, after); got != 25 {
+		t.Errorf("Expected 25%%, got %v", got)
+	}
+}
+
+func TestParseMeminfo(t *testing.T) {
+	info, err := parseMeminfo(strings.NewReader("MemTotal:       16384000 kB\nMemFree:         1024000 kB\nMemAvailable:    8192000 kB\nHugePages_Total:       0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info["MemTotal"] != 16384000 || info["MemAvailable"] != 8192000 || info["HugePages_Total"] != 0 {
+		t.Errorf("Unexpected meminfo: %v", info)
+	}
+
+	if _, err := parseMeminfo(strings.NewReader("garbage\n")); err == nil {
+		t.Error("Expected an error without MemTotal")
+	}
+}
+
+func TestParseProcStatJiffies(t *testing.T) {
+	// A command name with spaces and parentheses must not shift the fields.
+	stat := "1234 (Web Content (x)) S 1 1234 1234 0 -1 4194304 100 0 0 0 250 50 0 0 20 0 30 0 1000 123456789 5000"
+	got, err := parseProcStatJiffies(stat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 300 {
+		t.Errorf("Expected utime+stime=300, got %d", got)
+	}
+}
+
+func TestParseProcStatus(t *testing.T) {
+	name, rss, err := parseProcStatus(strings.NewReader("Name:\tfirefox\nState:\tS (sleeping)\nVmRSS:\t  524288 kB\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "firefox" || rss != 524288 {
+		t.Errorf("Expected firefox/524288, got %s/%d", name, rss)
+	}
+}
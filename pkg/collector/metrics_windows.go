@@ -9,15 +9,13 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
 	"time"
 	"unicode/utf16"
 
-	"zenith/pkg/db"
-
 	"github.com/Velocidex/ordereddict"
-	"github.com/shirou/gopsutil/v4/cpu"
 	"github.com/shirou/gopsutil/v4/mem"
 	"github.com/shirou/gopsutil/v4/net"
 	"github.com/shirou/gopsutil/v4/process"
@@ -25,7 +23,7 @@ import (
 	"www.velocidex.com/golang/go-ese/parser"
 )
 
-func CollectMetrics(database *db.VictoriaDB) error {
+func CollectMetrics(database MetricSink) error {
 	type result struct {
 		name string
 		err  error
@@ -33,10 +31,11 @@ func CollectMetrics(database *db.VictoriaDB) error {
 
 	collectors := []struct {
 		name string
-		fn   func(*db.VictoriaDB) error
+		fn   func(MetricSink) error
 	}{
 		{"CPU", collectCPUMetrics},
 		{"Memory", collectMemoryMetrics},
+		{"FD", collectSystemFDMetrics},
 		{"Process", CollectProcessMetrics},
 		{"Network", collectNetworkMetrics},
 		{"ProcessIO", collectProcessIOMetrics},
@@ -54,47 +53,71 @@ func CollectMetrics(database *db.VictoriaDB) error {
 	for range collectors {
 		r := <-results
 		if r.err != nil {
-			fmt.Printf("failed to collect %s metrics: %v\n", r.name, r.err)
+			collectErrors.Printf("failed to collect %s metrics: %v\n", r.name, r.err)
 		}
 	}
 
 	return nil
 }
 
-func collectCPUMetrics(database *db.VictoriaDB) error {
-	percent, err := cpu.Percent(time.Second, false)
+func collectMemoryMetrics(database MetricSink) error {
+	v, err := mem.VirtualMemory()
 	if err != nil {
 		return err
 	}
-	if len(percent) > 0 {
-		labels := map[string]string{"host": "localhost"}
-		return database.InsertMetric("cpu_usage_pct", percent[0], labels)
-	}
+
+	labels := map[string]string{"host": "localhost"}
+	database.InsertMetric("memory_used_mb", float64(v.Used)/1024/1024, labels)
+	database.InsertMetric("memory_free_mb", float64(v.Free)/1024/1024, labels)
 	return nil
 }
 
-func collectMemoryMetrics(database *db.VictoriaDB) error {
-	v, err := mem.VirtualMemory()
+// collectSystemFDMetrics records the total handle count across all
+// processes, Windows' closest equivalent of open file descriptors.
+// Processes whose handle count can't be read are skipped.
+func collectSystemFDMetrics(database MetricSink) error {
+	procs, err := process.Processes()
 	if err != nil {
 		return err
 	}
 
-	labels := map[string]string{"host": "localhost"}
-	database.InsertMetric("memory_used_mb", float64(v.Used)/1024/1024, labels)
-	database.InsertMetric("memory_free_mb", float64(v.Free)/1024/1024, labels)
+	var total int64
+	for _, p := range procs {
+		if n, err := p.NumFDs(); err == nil {
+			total += int64(n)
+		}
+	}
+	database.InsertMetric("system_open_fds", float64(total), map[string]string{"host": "localhost"})
 	return nil
 }
 
-func CollectProcessMetrics(database *db.VictoriaDB) error {
+func CollectProcessMetrics(database MetricSink) error {
 	procs, err := process.Processes()
 	if err != nil {
 		return err
 	}
 
+	// Write every process's samples in one request, recording the series so
+	// vanished processes can be marked stale.
+	out := newMetricBatch(database, processSeries)
+
+	// Sample every process's CPU time (TotalProcessorTime) twice to get
+	// usage over the interval; CPUPercent would average over the process's
+	// whole lifetime.
+	before := make(map[int32]float64, len(procs))
+	for _, p := range procs {
+		if t, err := p.Times(); err == nil {
+			before[p.Pid] = t.User + t.System
+		}
+	}
+	start := time.Now()
+	time.Sleep(processSampleInterval)
+	elapsed := time.Since(start).Seconds()
+
 	for _, p := range procs {
 		// Filter out processes with low memory usage to reduce noise
 		memInfo, err := p.MemoryInfo()
-		if err != nil || memInfo.RSS < 50*1024*1024 { // 50MB
+		if err != nil || !keepProcess(memInfo.RSS) {
 			continue
 		}
 
@@ -107,17 +130,36 @@ func CollectProcessMetrics(database *db.VictoriaDB) error {
 			"pid":          strconv.Itoa(int(p.Pid)),
 			"process_name": name,
 		}
-		database.InsertMetric("process_memory_mb", float64(memInfo.RSS)/1024/1024, labels)
+		out.InsertMetric("process_memory_mb", float64(memInfo.RSS)/1024/1024, labels)
 
-		cpuPct, err := p.CPUPercent()
-		if err == nil && cpuPct > 1.0 {
-			database.InsertMetric("process_cpu_pct", cpuPct, labels)
+		// On Windows this is the process handle count.
+		if fds, err := p.NumFDs(); err == nil {
+			out.InsertMetric("process_open_fds", float64(fds), labels)
+		}
+
+		prev, ok := before[p.Pid]
+		if !ok {
+			continue
+		}
+		t, err := p.Times()
+		if err != nil || t.User+t.System < prev {
+			continue
+		}
+		// Percent of one core, which can exceed 100 on multi-core machines;
+		// also emit a 0-100 share of the whole machine.
+		cpuPct := (t.User + t.System - prev) / elapsed * 100
+		if keepProcessCPU(cpuPct) {
+			out.InsertMetric("process_cpu_pct", cpuPct, labels)
+			out.InsertMetric("process_cpu_pct_normalized", cpuPct/float64(runtime.NumCPU()), labels)
 		}
 	}
-	return nil
+	if err := out.Flush(); err != nil {
+		return err
+	}
+	return processSeries.endCycle(database)
 }
 
-func collectNetworkMetrics(database *db.VictoriaDB) error {
+func collectNetworkMetrics(database MetricSink) error {
 	counters, err := net.IOCounters(true) // per interface
 	if err != nil {
 		return err
@@ -135,7 +177,7 @@ func collectNetworkMetrics(database *db.VictoriaDB) error {
 
 // collectProcessIOMetrics collects per-process disk I/O counters, duration, and
 // user identity using Windows APIs via gopsutil every 5 minutes.
-func collectProcessIOMetrics(database *db.VictoriaDB) error {
+func collectProcessIOMetrics(database MetricSink) error {
 	procs, err := process.Processes()
 	if err != nil {
 		return err
@@ -199,7 +241,7 @@ const (
 	srumAppResourceTable = "{D10CA2FE-6FCF-4F6D-848E-B2E99266FA89}"
 )
 
-func CollectSrumHistoricalMetrics(database *db.VictoriaDB) (err error) {
+func CollectSrumHistoricalMetrics(database MetricSink) (err error) {
 	// Recover from panics in the third-party ESE parser
 	defer func() {
 		if r := recover(); r != nil {
@@ -219,7 +261,7 @@ func CollectSrumHistoricalMetrics(database *db.VictoriaDB) (err error) {
 	psScript := `$vss = (Get-WmiObject -List Win32_ShadowCopy).Create('C:\', 'ClientAccessible'); $shadow = Get-WmiObject Win32_ShadowCopy | Where-Object { $_.ID -eq $vss.ShadowID }; Write-Output ($shadow.DeviceObject + "|||" + $vss.ShadowID)`
 
 	cmd := exec.Command("powershell", "-NoProfile", "-Command", psScript)
-	outputBytes, err := cmd.CombinedOutput()
+	outputBytes, err := collectionCombinedOutput(cmd)
 	output := strings.TrimSpace(string(outputBytes))
 
 	if err != nil {
@@ -243,7 +285,7 @@ func CollectSrumHistoricalMetrics(database *db.VictoriaDB) (err error) {
 			// Schedule cleanup of the specific VSS snapshot we just made
 			defer func(id string) {
 				cleanupScript := fmt.Sprintf(`(Get-WmiObject Win32_ShadowCopy | Where-Object { $_.ID -eq '%s' }).Delete()`, id)
-				exec.Command("powershell", "-NoProfile", "-Command", cleanupScript).Run()
+				runCollectionCommand(exec.Command("powershell", "-NoProfile", "-Command", cleanupScript))
 			}(shadowID)
 
 			vssSrumPath := shadowVolumeRoot + `\Windows\System32\sru\SRUDB.dat`
@@ -321,14 +363,32 @@ func CollectSrumHistoricalMetrics(database *db.VictoriaDB) (err error) {
 	}
 	fmt.Printf("srum debug: mapped %d app IDs, %d user IDs\n", len(appIdMap), len(userIdMap))
 
-	// 5. Read Application Resource Usage Table
+	// 5. Read Application Resource Usage Table, skipping rows written by
+	// earlier cycles. SRUM rows never change once recorded, so emitting them
+	// again would only duplicate the history.
+	cursor := srumCursor{}
+	if srumCursorFile != "" {
+		if cursor, err = loadSrumCursor(srumCursorFile); err != nil {
+			fmt.Printf("srum warning: %v, reading the whole table\n", err)
+		}
+	}
+	next := cursor
+
+	out := newMetricBatch(database, nil)
 	metricsInserted := 0
 	count := 0
 	err = catalog.DumpTable(srumAppResourceTable, func(row *ordereddict.Dict) error {
+		rowID, _ := getInt64FromDict(row, "AutoIncId")
+		ts := srumRowTime(row)
+		if !cursor.isNew(rowID, ts) {
+			return nil
+		}
+
 		count++
 		if count > 5000 {
 			return io.EOF
 		}
+		next.advance(rowID, ts)
 
 		appId, ok := getInt32FromDict(row, "AppId")
 		if !ok {
@@ -360,24 +420,36 @@ func CollectSrumHistoricalMetrics(database *db.VictoriaDB) (err error) {
 			"user_name": userName,
 		}
 
-		database.InsertMetric("srum_app_cycle_time_total", float64(cycleTime), labels)
-		database.InsertMetric("srum_app_bytes_read_total", float64(bytesRead), labels)
-		database.InsertMetric("srum_app_bytes_written_total", float64(bytesWritten), labels)
+		// Stamp each row with the time SRUM recorded it, not collection time,
+		// so ingesting the history doesn't show up as one spike.
+		out.InsertMetricAt("srum_app_cycle_time_total", float64(cycleTime), labels, ts)
+		out.InsertMetricAt("srum_app_bytes_read_total", float64(bytesRead), labels, ts)
+		out.InsertMetricAt("srum_app_bytes_written_total", float64(bytesWritten), labels, ts)
 		if fgCycleTime > 0 {
-			database.InsertMetric("srum_app_foreground_cycle_time_total", float64(fgCycleTime), labels)
+			out.InsertMetricAt("srum_app_foreground_cycle_time_total", float64(fgCycleTime), labels, ts)
 		}
 		if bgCycleTime > 0 {
-			database.InsertMetric("srum_app_background_cycle_time_total", float64(bgCycleTime), labels)
+			out.InsertMetricAt("srum_app_background_cycle_time_total", float64(bgCycleTime), labels, ts)
 		}
 		if durationMs > 0 {
-			database.InsertMetric("srum_app_duration_ms", float64(durationMs), labels)
+			out.InsertMetricAt("srum_app_duration_ms", float64(durationMs), labels, ts)
 		}
 		metricsInserted++
 		return nil
 	})
 
+	if flushErr := out.Flush(); flushErr != nil {
+		return fmt.Errorf("failed to write SRUM metrics: %w", flushErr)
+	}
 	fmt.Printf("srum debug: successfully inserted %d application metrics from %d parsed rows\n", metricsInserted, count)
 
+	// Rows past the 5000-row cap are picked up next cycle.
+	if srumCursorFile != "" && next != cursor {
+		if saveErr := next.save(srumCursorFile); saveErr != nil {
+			fmt.Printf("srum warning: failed to save cursor: %v\n", saveErr)
+		}
+	}
+
 	if err != nil && err != io.EOF {
 		return fmt.Errorf("failed to dump usage table: %w", err)
 	}
@@ -511,3 +583,17 @@ func getInt64FromDict(m *ordereddict.Dict, key string) (int64, bool) {
 		return 0, false
 	}
 }
+
+// srumRowTime returns the TimeStamp SRUM recorded for a row, falling back to
+// the current time when it is missing or implausible.
+func srumRowTime(row *ordereddict.Dict) time.Time {
+	v, ok := row.Get("TimeStamp")
+	if !ok {
+		return time.Now()
+	}
+	ts, ok := v.(time.Time)
+	if !ok || ts.IsZero() || ts.Year() < 2000 || ts.After(time.Now()) {
+		return time.Now()
+	}
+	return ts
+}
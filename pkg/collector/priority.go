@@ -0,0 +1,48 @@
+package collector
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+)
+
+// collectionNice is the unix nice level applied to commands the collectors
+// spawn (log show, PowerShell, ...). Zero leaves their priority unchanged.
+var collectionNice int
+
+// SetCollectionNice sets the nice level for spawned collection commands, so
+// Zenith can run as a low-priority background agent on a loaded machine.
+// On Windows it is mapped to a process priority class instead.
+func SetCollectionNice(n int) {
+	collectionNice = n
+}
+
+// collectionOutput is cmd.Output for collection commands: it runs cmd at the
+// configured priority and returns its stdout.
+func collectionOutput(cmd *exec.Cmd) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := runCollectionCommand(cmd)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitErr.Stderr = stderr.Bytes()
+	}
+	return stdout.Bytes(), err
+}
+
+// collectionCombinedOutput is cmd.CombinedOutput for collection commands.
+func collectionCombinedOutput(cmd *exec.Cmd) ([]byte, error) {
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := runCollectionCommand(cmd)
+	return out.Bytes(), err
+}
+
+func runCollectionCommand(cmd *exec.Cmd) error {
+	if err := startCollectionCommand(cmd); err != nil {
+		return err
+	}
+	return cmd.Wait()
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+
+package collector
+
+import (
+	"log"
+	"os/exec"
+	"syscall"
+)
+
+// startCollectionCommand starts cmd and renices it to collectionNice. The
+// child runs at normal priority for the instant between fork and
+// Setpriority, which is harmless for the short-lived tools we spawn.
+func startCollectionCommand(cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	if collectionNice != 0 {
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, cmd.Process.Pid, collectionNice); err != nil {
+			log.Printf("Warning: failed to set nice %d on %s: %v", collectionNice, cmd.Path, err)
+		}
+	}
+	return nil
+}
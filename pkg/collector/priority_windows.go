@@ -0,0 +1,37 @@
+//go:build windows
+
+package collector
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// Process priority classes for CreateProcess (see winbase.h).
+const (
+	idlePriorityClass        = 0x00000040
+	belowNormalPriorityClass = 0x00004000
+	aboveNormalPriorityClass = 0x00008000
+)
+
+// startCollectionCommand starts cmd in the priority class closest to
+// collectionNice: 1-9 is below normal, 10 and up is idle, and negative
+// values are above normal.
+func startCollectionCommand(cmd *exec.Cmd) error {
+	var class uint32
+	switch {
+	case collectionNice >= 10:
+		class = idlePriorityClass
+	case collectionNice > 0:
+		class = belowNormalPriorityClass
+	case collectionNice < 0:
+		class = aboveNormalPriorityClass
+	}
+	if class != 0 {
+		if cmd.SysProcAttr == nil {
+			cmd.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		cmd.SysProcAttr.CreationFlags |= class
+	}
+	return cmd.Start()
+}
@@ -0,0 +1,79 @@
+package collector
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// processSampleInterval is how long CollectProcessMetrics waits between the
+// two CPU time readings it takes per process on Linux and Windows.
+const processSampleInterval = 500 * time.Millisecond
+
+// CPU sub-sampling: instead of one instantaneous reading per collection
+// cycle, CPU can be sampled cpuSamples times, each over cpuSampleInterval,
+// to catch brief spikes a single reading would miss.
+var (
+	cpuSamples        = 1
+	cpuSampleInterval = time.Second
+)
+
+// SetCPUSampling configures how many CPU readings are taken per collection
+// cycle and how long each one measures. With more than one sample,
+// cpu_usage_pct is the average and _min, _max, and _p95 series are emitted too.
+func SetCPUSampling(count int, interval time.Duration) {
+	if count < 1 {
+		count = 1
+	}
+	if interval <= 0 {
+		interval = time.Second
+	}
+	cpuSamples, cpuSampleInterval = count, interval
+}
+
+func collectCPUMetrics(database MetricSink) error {
+	samples := make([]float64, 0, cpuSamples)
+	for i := 0; i < cpuSamples; i++ {
+		percent, err := sampleCPUPercent(cpuSampleInterval)
+		if err != nil {
+			return err
+		}
+		samples = append(samples, percent)
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+
+	labels := map[string]string{"host": "localhost"}
+	if len(samples) == 1 {
+		return database.InsertMetric("cpu_usage_pct", samples[0], labels)
+	}
+
+	s := summarize(samples)
+	database.InsertMetric("cpu_usage_pct_min", s.min, labels)
+	database.InsertMetric("cpu_usage_pct_max", s.max, labels)
+	database.InsertMetric("cpu_usage_pct_p95", s.p95, labels)
+	return database.InsertMetric("cpu_usage_pct", s.avg, labels)
+}
+
+type sampleSummary struct {
+	min, avg, max, p95 float64
+}
+
+// summarize computes min/avg/max and the nearest-rank 95th percentile.
+func summarize(samples []float64) sampleSummary {
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	rank := int(math.Ceil(0.95*float64(len(sorted)))) - 1
+	return sampleSummary{
+		min: sorted[0],
+		avg: sum / float64(len(sorted)),
+		max: sorted[len(sorted)-1],
+		p95: sorted[rank],
+	}
+}
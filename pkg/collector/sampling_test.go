@@ -0,0 +1,28 @@
+package collector
+
+import "testing"
+
+func TestSummarize(t *testing.T) {
+	samples := make([]float64, 0, 20)
+	for i := 20; i >= 1; i-- {
+		samples = append(samples, float64(i))
+	}
+
+	s := summarize(samples)
+	if s.min != 1 || s.max != 20 || s.avg != 10.5 {
+		t.Errorf("Unexpected min/avg/max: %+v", s)
+	}
+	if s.p95 != 19 {
+		t.Errorf("Expected p95 19, got %v", s.p95)
+	}
+	if samples[0] != 20 {
+		t.Error("summarize must not reorder the caller's samples")
+	}
+}
+
+func TestSummarize_Single(t *testing.T) {
+	s := summarize([]float64{42})
+	if s.min != 42 || s.avg != 42 || s.max != 42 || s.p95 != 42 {
+		t.Errorf("Expected all 42, got %+v", s)
+	}
+}
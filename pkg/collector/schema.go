@@ -0,0 +1,105 @@
+package collector
+
+// Metric describes a metric the collectors emit.
+type Metric struct {
+	Name string
+	// Counter marks cumulative totals that only grow (until the source
+	// resets). Questions about a time window need increase(metric[window])
+	// rather than the raw value.
+	Counter bool
+	// Platforms lists the GOOS values that emit the metric; empty means all.
+	Platforms []string
+	// Unit is what the stored values are measured in ("MB", "bytes", "ms",
+	// "percent", ...). Query thresholds have to be written in it.
+	Unit string
+}
+
+var (
+	windowsOnly = []string{"windows"}
+	darwinOnly  = []string{"darwin"}
+	linuxOnly   = []string{"linux"}
+)
+
+// Metrics lists every metric the collectors can emit, across all platforms.
+// The LLM prompts must advertise exactly this set; see llm.SchemaDrift and
+// the startup self-check in zenith-server.
+var Metrics = []Metric{
+	// System-wide
+	{Name: "cpu_usage_pct", Unit: "percent"},
+	{Name: "cpu_usage_pct_min", Unit: "percent"},
+	{Name: "cpu_usage_pct_max", Unit: "percent"},
+	{Name: "cpu_usage_pct_p95", Unit: "percent"},
+	{Name: "memory_used_mb", Unit: "MB"},
+	{Name: "memory_free_mb", Unit: "MB"},
+	{Name: "system_open_fds", Unit: "count"},
+
+	// Disk I/O (macOS), per device
+	{Name: "disk_read_kb", Platforms: darwinOnly, Unit: "KB/s"},
+	{Name: "disk_write_kb", Platforms: darwinOnly, Unit: "KB/s"},
+
+	// Thermal (macOS, with enable_thermal)
+	{Name: "cpu_temperature_c", Platforms: darwinOnly, Unit: "celsius"},
+	{Name: "fan_rpm", Platforms: darwinOnly, Unit: "rpm"},
+
+	// systemd services (Linux)
+	{Name: "service_failed_count", Platforms: linuxOnly, Unit: "count"},
+	{Name: "service_active", Platforms: linuxOnly, Unit: "boolean"},
+
+	// Log volume, counted from VictoriaLogs
+	{Name: "log_event_count", Unit: "count"},
+
+	// Per-process
+	{Name: "process_cpu_pct", Unit: "percent"},
+	{Name: "process_cpu_pct_normalized", Unit: "percent"},
+	{Name: "process_memory_mb", Unit: "MB"},
+	{Name: "process_open_fds", Unit: "count"},
+
+	// SRUM app (Windows)
+	{Name: "srum_app_cycle_time_total", Counter: true, Platforms: windowsOnly, Unit: "cycles"},
+	{Name: "srum_app_bytes_read_total", Counter: true, Platforms: windowsOnly, Unit: "bytes"},
+	{Name: "srum_app_bytes_written_total", Counter: true, Platforms: windowsOnly, Unit: "bytes"},
+	{Name: "srum_app_duration_ms", Platforms: windowsOnly, Unit: "ms"},
+	{Name: "srum_app_foreground_cycle_time_total", Counter: true, Platforms: windowsOnly, Unit: "cycles"},
+	{Name: "srum_app_background_cycle_time_total", Counter: true, Platforms: windowsOnly, Unit: "cycles"},
+
+	// SRUM network (Windows)
+	{Name: "srum_network_bytes_sent_total", Counter: true, Platforms: windowsOnly, Unit: "bytes"},
+	{Name: "srum_network_bytes_received_total", Counter: true, Platforms: windowsOnly, Unit: "bytes"},
+}
+
+// MetricNames lists the names of Metrics, in order.
+var MetricNames = metricNames(func(Metric) bool { return true })
+
+// CounterNames lists the names of the Metrics marked as counters, in order.
+var CounterNames = metricNames(func(m Metric) bool { return m.Counter })
+
+// LookupMetric returns the schema entry for name.
+func LookupMetric(name string) (Metric, bool) {
+	for _, m := range Metrics {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return Metric{}, false
+}
+
+// MetricUnits maps each metric name to its Unit.
+var MetricUnits = metricUnits()
+
+func metricUnits() map[string]string {
+	units := make(map[string]string, len(Metrics))
+	for _, m := range Metrics {
+		units[m.Name] = m.Unit
+	}
+	return units
+}
+
+func metricNames(keep func(Metric) bool) []string {
+	var names []string
+	for _, m := range Metrics {
+		if keep(m) {
+			names = append(names, m.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,78 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestMetricNames_MatchesCollectors scans the collector sources (for every
+// platform, regardless of build tags) and checks that MetricNames lists
+// exactly the metrics they insert.
+func TestMetricNames_MatchesCollectors(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	insertRe := regexp.MustCompile(`InsertMetric(?:At)?\("([a-z0-9_]+)"`)
+	emitted := make(map[string]bool)
+	for _, f := range files {
+		if strings.HasSuffix(f, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range insertRe.FindAllStringSubmatch(string(src), -1) {
+			emitted[m[1]] = true
+		}
+	}
+
+	listed := make(map[string]bool)
+	for _, name := range MetricNames {
+		listed[name] = true
+		if !emitted[name] {
+			t.Errorf("MetricNames lists %q but no collector inserts it", name)
+		}
+	}
+	for name := range emitted {
+		if !listed[name] {
+			t.Errorf("Collector inserts %q but it is missing from MetricNames", name)
+		}
+	}
+}
+
+// TestCounterNames_Suffix keeps the Prometheus naming convention: counters,
+// and only counters, end in _total.
+func TestCounterNames_Suffix(t *testing.T) {
+	for _, m := range Metrics {
+		if m.Counter != strings.HasSuffix(m.Name, "_total") {
+			t.Errorf("%s: Counter=%v does not match its name", m.Name, m.Counter)
+		}
+	}
+}
+
+func TestMetrics_HaveUnits(t *testing.T) {
+	for _, m := range Metrics {
+		if m.Unit == "" {
+			t.Errorf("Metric %s has no Unit", m.Name)
+		}
+		if strings.HasSuffix(m.Name, "_mb") && m.Unit != "MB" {
+			t.Errorf("Metric %s is named in MB but has Unit %q", m.Name, m.Unit)
+		}
+	}
+}
+
+func TestLookupMetric(t *testing.T) {
+	m, ok := LookupMetric("srum_network_bytes_sent_total")
+	if !ok || !m.Counter || m.Unit != "bytes" {
+		t.Errorf("Unexpected schema entry: %+v (found=%v)", m, ok)
+	}
+	if _, ok := LookupMetric("disk_used_pct"); ok {
+		t.Error("Expected an unknown metric not to be found")
+	}
+}
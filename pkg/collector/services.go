@@ -0,0 +1,57 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// systemdUnit is one entry of `systemctl list-units --output=json`.
+type systemdUnit struct {
+	Unit   string `json:"unit"`
+	Load   string `json:"load"`
+	Active string `json:"active"`
+	Sub    string `json:"sub"`
+}
+
+// parseSystemdUnits decodes `systemctl list-units --output=json` output,
+// dropping units whose unit file is not loaded (not-found, masked), which
+// systemctl lists but which never run.
+func parseSystemdUnits(out []byte) ([]systemdUnit, error) {
+	var units []systemdUnit
+	if err := json.Unmarshal(out, &units); err != nil {
+		return nil, fmt.Errorf("failed to parse systemctl output: %v", err)
+	}
+	loaded := units[:0]
+	for _, u := range units {
+		if u.Unit != "" && u.Load == "loaded" {
+			loaded = append(loaded, u)
+		}
+	}
+	return loaded, nil
+}
+
+// failedUnits counts the units systemd reports as failed.
+func failedUnits(units []systemdUnit) int {
+	n := 0
+	for _, u := range units {
+		if u.Active == "failed" {
+			n++
+		}
+	}
+	return n
+}
+
+// recordServiceMetrics writes service_failed_count and one service_active
+// sample (1 when active) per unit.
+func recordServiceMetrics(database MetricSink, units []systemdUnit) error {
+	out := newMetricBatch(database, nil)
+	out.InsertMetric("service_failed_count", float64(failedUnits(units)), map[string]string{"host": "localhost"})
+	for _, u := range units {
+		active := 0.0
+		if u.Active == "active" {
+			active = 1
+		}
+		out.InsertMetric("service_active", active, map[string]string{"host": "localhost", "unit": u.Unit})
+	}
+	return out.Flush()
+}
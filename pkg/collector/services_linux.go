@@ -0,0 +1,66 @@
+//go:build linux
+
+package collector
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+var (
+	servicesMu  sync.Mutex
+	servicesOff bool
+)
+
+// servicesUnavailable stops service collection for the rest of the run and
+// reports why, once. An init system without systemd won't grow one.
+func servicesUnavailable(reason string) error {
+	servicesMu.Lock()
+	defer servicesMu.Unlock()
+	if servicesOff {
+		return nil
+	}
+	servicesOff = true
+	return fmt.Errorf("service collection disabled: %s", reason)
+}
+
+// collectServiceMetrics records how many systemd services have failed and,
+// per service, whether it is active. Systems not booted with systemd are
+// skipped silently.
+func collectServiceMetrics(database MetricSink) error {
+	servicesMu.Lock()
+	off := servicesOff
+	servicesMu.Unlock()
+	if off {
+		return nil
+	}
+
+	// sd_booted(3): systemd is PID 1 iff this directory exists.
+	if _, err := os.Stat("/run/systemd/system"); err != nil {
+		servicesMu.Lock()
+		servicesOff = true
+		servicesMu.Unlock()
+		return nil
+	}
+
+	cmd := exec.Command("systemctl", "list-units", "--type=service", "--all", "--output=json", "--no-pager")
+	output, err := collectionOutput(cmd)
+	if err != nil {
+		msg := err.Error()
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			msg = strings.TrimSpace(string(exitErr.Stderr))
+		}
+		return servicesUnavailable(fmt.Sprintf("systemctl failed: %s", msg))
+	}
+
+	units, err := parseSystemdUnits(output)
+	if err != nil {
+		// systemd older than v240 has no JSON output.
+		return servicesUnavailable(err.Error())
+	}
+
+	return recordServiceMetrics(database, units)
+}
@@ -0,0 +1,36 @@
+package collector
+
+import "testing"
+
+func TestParseSystemdUnits(t *testing.T) {
+	out := `[{"unit":"nginx.service","load":"loaded","active":"active","sub":"running","description":"nginx"},` +
+		`{"unit":"backup.service","load":"loaded","active":"failed","sub":"failed","description":"Nightly backup"},` +
+		`{"unit":"apt-daily.service","load":"loaded","active":"inactive","sub":"dead","description":"Daily apt"},` +
+		`{"unit":"gone.service","load":"not-found","active":"inactive","sub":"dead","description":"gone.service"}]`
+
+	units, err := parseSystemdUnits([]byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(units) != 3 {
+		t.Fatalf("Expected 3 loaded units, got %+v", units)
+	}
+	if n := failedUnits(units); n != 1 {
+		t.Errorf("Expected 1 failed unit, got %d", n)
+	}
+
+	sink := &recordingSink{}
+	if err := recordServiceMetrics(sink, units); err != nil {
+		t.Fatal(err)
+	}
+	assertSamples(t, sink, []string{
+		`service_failed_count{host="localhost"} 1`,
+		`service_active{host="localhost",unit="nginx.service"} 1`,
+		`service_active{host="localhost",unit="backup.service"} 0`,
+		`service_active{host="localhost",unit="apt-daily.service"} 0`,
+	})
+
+	if _, err := parseSystemdUnits([]byte("UNIT LOAD ACTIVE SUB DESCRIPTION\n")); err == nil {
+		t.Error("Expected an error for non-JSON output")
+	}
+}
@@ -0,0 +1,92 @@
+package collector
+
+import (
+	"strings"
+
+	"zenith/pkg/db"
+)
+
+// MetricSink receives the samples collectors produce. *db.VictoriaDB is the
+// real one; tests pass a fake that records what was written.
+type MetricSink interface {
+	InsertMetric(name string, value float64, labels map[string]string) error
+	InsertMetrics(batch []db.Metric) error
+}
+
+// LogSink receives the log entries collectors produce.
+type LogSink interface {
+	InsertLogs(entries []db.LogEntry) error
+}
+
+// LogCounter counts stored log entries, for CollectLogCounts.
+type LogCounter interface {
+	CountLogs(filter, window, by string) (map[string]float64, error)
+}
+
+// WithLabels returns a MetricSink that adds labels to every sample before
+// passing it to sink. A sample's own label of the same name wins.
+func WithLabels(sink MetricSink, labels map[string]string) MetricSink {
+	if len(labels) == 0 {
+		return sink
+	}
+	return labeledSink{sink: sink, labels: labels}
+}
+
+// WithGaugeLabels is WithLabels for labels that change from one collection
+// to the next, such as a run's run_id. Counters are passed through as they
+// are: a counter split into a new series every run never accumulates, so
+// increase() and rate() over it would only ever see one sample per series.
+func WithGaugeLabels(sink MetricSink, labels map[string]string) MetricSink {
+	if len(labels) == 0 {
+		return sink
+	}
+	return labeledSink{sink: sink, labels: labels, gaugesOnly: true}
+}
+
+type labeledSink struct {
+	sink       MetricSink
+	labels     map[string]string
+	gaugesOnly bool
+}
+
+func (s labeledSink) with(name string, labels map[string]string) map[string]string {
+	if s.gaugesOnly && isCounter(name) {
+		return labels
+	}
+	out := make(map[string]string, len(labels)+len(s.labels))
+	for k, v := range s.labels {
+		out[k] = v
+	}
+	for k, v := range labels {
+		out[k] = v
+	}
+	return out
+}
+
+func (s labeledSink) InsertMetric(name string, value float64, labels map[string]string) error {
+	return s.sink.InsertMetric(name, value, s.with(name, labels))
+}
+
+func (s labeledSink) InsertMetrics(batch []db.Metric) error {
+	labeled := make([]db.Metric, len(batch))
+	for i, m := range batch {
+		m.Labels = s.with(m.Name, m.Labels)
+		labeled[i] = m
+	}
+	return s.sink.InsertMetrics(labeled)
+}
+
+// isCounter reports whether name is a counter: marked as one in the schema,
+// or named like one.
+func isCounter(name string) bool {
+	if m, ok := LookupMetric(name); ok {
+		return m.Counter
+	}
+	return strings.HasSuffix(name, "_total")
+}
+
+var (
+	_ MetricSink = (*db.VictoriaDB)(nil)
+	_ LogSink    = (*db.VictoriaDB)(nil)
+	_ LogCounter = (*db.VictoriaDB)(nil)
+)
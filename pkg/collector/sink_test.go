@@ -0,0 +1,126 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"zenith/pkg/db"
+)
+
+// recordingSink is a MetricSink and LogSink that keeps everything written
+// to it.
+type recordingSink struct {
+	metrics []db.Metric
+	logs    []db.LogEntry
+}
+
+func (s *recordingSink) InsertMetric(name string, value float64, labels map[string]string) error {
+	s.metrics = append(s.metrics, db.Metric{Name: name, Value: value, Labels: labels})
+	return nil
+}
+
+func (s *recordingSink) InsertMetrics(batch []db.Metric) error {
+	s.metrics = append(s.metrics, batch...)
+	return nil
+}
+
+func (s *recordingSink) InsertLogs(entries []db.LogEntry) error {
+	s.logs = append(s.logs, entries...)
+	return nil
+}
+
+// samples renders the recorded metrics as sorted `name{k="v",...} value`
+// lines so tests can compare them against an exact expectation.
+func (s *recordingSink) samples() []string {
+	var out []string
+	for _, m := range s.metrics {
+		keys := make([]string, 0, len(m.Labels))
+		for k := range m.Labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		pairs := make([]string, len(keys))
+		for i, k := range keys {
+			pairs[i] = fmt.Sprintf("%s=%q", k, m.Labels[k])
+		}
+		out = append(out, fmt.Sprintf("%s{%s} %g", m.Name, strings.Join(pairs, ","), m.Value))
+	}
+	sort.Strings(out)
+	return out
+}
+
+func assertSamples(t *testing.T, sink *recordingSink, want []string) {
+	t.Helper()
+	got := sink.samples()
+	sort.Strings(want)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected samples\n got: %q\nwant: %q", got, want)
+	}
+}
+
+type fakeLogCounter struct {
+	counts map[string]float64
+	window string
+	by     string
+}
+
+func (c *fakeLogCounter) CountLogs(filter, window, by string) (map[string]float64, error) {
+	c.window, c.by = window, by
+	return c.counts, nil
+}
+
+func TestCollectLogCounts(t *testing.T) {
+	counter := &fakeLogCounter{counts: map[string]float64{"Error": 4, "Default": 120, "": 2}}
+	sink := &recordingSink{}
+	if err := CollectLogCounts(counter, sink, "10m"); err != nil {
+		t.Fatal(err)
+	}
+	if counter.window != "600s" || counter.by != "messageType" {
+		t.Errorf("Expected a 600s count by messageType, got %q by %q", counter.window, counter.by)
+	}
+	assertSamples(t, sink, []string{
+		`log_event_count{host="localhost",level="Default"} 120`,
+		`log_event_count{host="localhost",level="Error"} 4`,
+		`log_event_count{host="localhost",level="unknown"} 2`,
+	})
+}
+
+func TestWithLabels(t *testing.T) {
+	sink := &recordingSink{}
+	tagged := WithLabels(sink, map[string]string{"run_id": "r1", "host": "default"})
+
+	labels := map[string]string{"host": "localhost"}
+	tagged.InsertMetric("cpu_usage_pct", 12, labels)
+	tagged.InsertMetrics([]db.Metric{{Name: "memory_used_mb", Value: 512}})
+
+	assertSamples(t, sink, []string{
+		`cpu_usage_pct{host="localhost",run_id="r1"} 12`,
+		`memory_used_mb{host="default",run_id="r1"} 512`,
+	})
+	if len(labels) != 1 {
+		t.Errorf("Expected the caller's labels to be left alone, got %v", labels)
+	}
+
+	if WithLabels(sink, nil) != MetricSink(sink) {
+		t.Error("Expected no labels to return the sink unchanged")
+	}
+}
+
+func TestWithGaugeLabels(t *testing.T) {
+	sink := &recordingSink{}
+	tagged := WithGaugeLabels(sink, map[string]string{"run_id": "r1"})
+
+	tagged.InsertMetric("cpu_usage_pct", 12, map[string]string{"host": "localhost"})
+	tagged.InsertMetrics([]db.Metric{
+		{Name: "srum_network_bytes_sent_total", Value: 100, Labels: map[string]string{"app_name": "a"}},
+		{Name: "custom_requests_total", Value: 3},
+	})
+
+	assertSamples(t, sink, []string{
+		`cpu_usage_pct{host="localhost",run_id="r1"} 12`,
+		`srum_network_bytes_sent_total{app_name="a"} 100`,
+		`custom_requests_total{} 3`,
+	})
+}
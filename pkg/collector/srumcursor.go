@@ -0,0 +1,86 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// srumCursorFile, when set, is where CollectSrumHistoricalMetrics remembers
+// the newest SRUM row it has written, so later cycles only emit rows added
+// since. Empty re-reads the whole table every cycle.
+var srumCursorFile string
+
+// SetSrumCursorFile sets the file the SRUM row cursor is persisted in.
+// Empty disables incremental collection.
+func SetSrumCursorFile(path string) {
+	srumCursorFile = path
+}
+
+// srumCursor marks the newest SRUM row already written: its ESE AutoIncId
+// and the TimeStamp SRUM recorded for it.
+type srumCursor struct {
+	LastID        int64     `json:"last_id"`
+	LastTimestamp time.Time `json:"last_timestamp"`
+}
+
+// loadSrumCursor reads the cursor at path. A missing file is an empty
+// cursor, which lets every row through.
+func loadSrumCursor(path string) (srumCursor, error) {
+	var c srumCursor
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return srumCursor{}, fmt.Errorf("invalid SRUM cursor %s: %v", path, err)
+	}
+	return c, nil
+}
+
+// save writes the cursor to path, replacing it atomically so a crash
+// mid-write can't leave a truncated cursor behind.
+func (c srumCursor) save(path string) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// isNew reports whether the row with AutoIncId id (0 if unknown) recorded at
+// ts has not been written yet. A row with a lower id but a newer timestamp
+// means SRUDB was recreated and its ids restarted, so it counts as new.
+func (c srumCursor) isNew(id int64, ts time.Time) bool {
+	if id > 0 && id > c.LastID {
+		return true
+	}
+	return ts.After(c.LastTimestamp)
+}
+
+// advance moves the cursor past a row that has been written.
+func (c *srumCursor) advance(id int64, ts time.Time) {
+	if id > c.LastID {
+		c.LastID = id
+	}
+	if ts.After(c.LastTimestamp) {
+		c.LastTimestamp = ts
+	}
+}
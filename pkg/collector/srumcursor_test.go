@@ -0,0 +1,80 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSrumCursor_IsNew(t *testing.T) {
+	hour := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	c := srumCursor{LastID: 100, LastTimestamp: hour}
+
+	tests := []struct {
+		name string
+		id   int64
+		ts   time.Time
+		want bool
+	}{
+		{"already written", 90, hour.Add(-time.Hour), false},
+		{"the cursor row", 100, hour, false},
+		{"later row in the same flush", 101, hour, true},
+		{"no id, older", 0, hour.Add(-time.Hour), false},
+		{"no id, newer", 0, hour.Add(time.Hour), true},
+		{"ids restarted after SRUDB was recreated", 3, hour.Add(time.Hour), true},
+	}
+	for _, tt := range tests {
+		if got := c.isNew(tt.id, tt.ts); got != tt.want {
+			t.Errorf("%s: isNew(%d, %s) = %v, want %v", tt.name, tt.id, tt.ts, got, tt.want)
+		}
+	}
+
+	if !(srumCursor{}).isNew(1, hour) {
+		t.Error("Expected an empty cursor to let every row through")
+	}
+}
+
+func TestSrumCursor_Advance(t *testing.T) {
+	hour := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	var c srumCursor
+	c.advance(5, hour)
+	c.advance(3, hour.Add(time.Hour))
+	c.advance(4, hour.Add(-time.Hour))
+
+	want := srumCursor{LastID: 5, LastTimestamp: hour.Add(time.Hour)}
+	if c != want {
+		t.Errorf("Expected %+v, got %+v", want, c)
+	}
+}
+
+func TestSrumCursor_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "srum_cursor.json")
+
+	c, err := loadSrumCursor(path)
+	if err != nil {
+		t.Fatalf("Expected a missing cursor file to load as empty, got %v", err)
+	}
+	if c != (srumCursor{}) {
+		t.Errorf("Expected an empty cursor, got %+v", c)
+	}
+
+	want := srumCursor{LastID: 42, LastTimestamp: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)}
+	if err := want.save(path); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadSrumCursor(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.LastTimestamp.Equal(want.LastTimestamp) || got.LastID != want.LastID {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadSrumCursor(path); err == nil {
+		t.Error("Expected a corrupt cursor file to be an error")
+	}
+}
@@ -0,0 +1,83 @@
+package collector
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"zenith/pkg/db"
+)
+
+// emitStaleMarkers enables staleness markers for per-process series.
+var emitStaleMarkers bool
+
+// SetEmitStaleMarkers turns staleness markers on or off. When on, a
+// process_* series written in one collection cycle but not the next gets a
+// Prometheus staleness marker (db.StaleNaN), which VictoriaMetrics treats as
+// the end of the series, so an exited process stops showing up as current
+// instead of lingering in instant queries and last_over_time lookbacks.
+func SetEmitStaleMarkers(on bool) {
+	emitStaleMarkers = on
+}
+
+// seriesTracker remembers which series were written in the previous and
+// current cycle.
+type seriesTracker struct {
+	mu   sync.Mutex
+	prev map[string]trackedSeries
+	cur  map[string]trackedSeries
+}
+
+type trackedSeries struct {
+	name   string
+	labels map[string]string
+}
+
+var processSeries = &seriesTracker{}
+
+// record notes that a series was written this cycle.
+func (t *seriesTracker) record(name string, labels map[string]string) {
+	if !emitStaleMarkers {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.cur == nil {
+		t.cur = make(map[string]trackedSeries)
+	}
+	t.cur[seriesKey(name, labels)] = trackedSeries{name: name, labels: labels}
+}
+
+// endCycle writes a staleness marker for every series seen last cycle but
+// not this one, then starts a new cycle.
+func (t *seriesTracker) endCycle(database MetricSink) error {
+	t.mu.Lock()
+	var gone []db.Metric
+	for key, s := range t.prev {
+		if _, ok := t.cur[key]; !ok {
+			gone = append(gone, db.Metric{Name: s.name, Value: db.StaleNaN(), Labels: s.labels})
+		}
+	}
+	t.prev, t.cur = t.cur, nil
+	t.mu.Unlock()
+
+	if !emitStaleMarkers {
+		return nil
+	}
+	return database.InsertMetrics(gone)
+}
+
+func seriesKey(name string, labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(name)
+	for _, k := range keys {
+		b.WriteString("\x00" + k + "=" + labels[k])
+	}
+	return b.String()
+}
@@ -0,0 +1,42 @@
+package collector
+
+import (
+	"testing"
+
+	"zenith/pkg/db"
+)
+
+func TestSeriesTracker_StaleMarkers(t *testing.T) {
+	SetEmitStaleMarkers(true)
+	defer SetEmitStaleMarkers(false)
+
+	sink := &recordingSink{}
+	tracker := &seriesTracker{}
+
+	out := newMetricBatch(sink, tracker)
+	out.InsertMetric("process_memory_mb", 100, map[string]string{"pid": "1", "process_name": "a"})
+	out.InsertMetric("process_memory_mb", 200, map[string]string{"pid": "2", "process_name": "b"})
+	out.Flush()
+	tracker.endCycle(sink)
+
+	sink.metrics = nil
+	out.InsertMetric("process_memory_mb", 210, map[string]string{"process_name": "b", "pid": "2"})
+	out.Flush()
+	tracker.endCycle(sink)
+
+	var markers []db.Metric
+	for _, m := range sink.metrics {
+		if m.Value != m.Value { // NaN
+			markers = append(markers, m)
+		}
+	}
+	if len(markers) != 1 || markers[0].Labels["pid"] != "1" {
+		t.Fatalf("Expected one stale marker for pid 1, got %+v", markers)
+	}
+	// A plain NaN is skipped by rollups such as last_over_time, which then
+	// return the process's last value; only the staleness marker ends the
+	// series.
+	if !db.IsStaleNaN(markers[0].Value) {
+		t.Errorf("Expected the Prometheus staleness marker, got a plain NaN")
+	}
+}
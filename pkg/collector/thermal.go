@@ -0,0 +1,68 @@
+package collector
+
+import (
+	"bufio"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// thermalEnabled turns on the thermal collector, which needs root.
+var thermalEnabled bool
+
+// SetEnableThermal turns thermal (CPU temperature and fan speed) collection
+// on or off. It is off by default because it needs elevated privileges.
+func SetEnableThermal(on bool) {
+	thermalEnabled = on
+}
+
+// thermalReading is what one powermetrics SMC sample reports.
+type thermalReading struct {
+	CPUTempC   float64
+	HasCPUTemp bool
+	FanRPM     []float64
+}
+
+var (
+	cpuTempPattern = regexp.MustCompile(`(?i)^CPU die temperature:\s*([0-9.]+)\s*C`)
+	fanPattern     = regexp.MustCompile(`(?i)^Fan(?:\s*\d+)?:\s*([0-9.]+)\s*rpm`)
+)
+
+// parsePowermetricsSMC extracts the CPU die temperature and fan speeds from
+// `powermetrics --samplers smc` output. Lines it does not recognize are
+// skipped; Apple Silicon Macs report neither.
+func parsePowermetricsSMC(out string) thermalReading {
+	var r thermalReading
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if m := cpuTempPattern.FindStringSubmatch(line); m != nil {
+			if v, err := strconv.ParseFloat(m[1], 64); err == nil {
+				r.CPUTempC = v
+				r.HasCPUTemp = true
+			}
+		} else if m := fanPattern.FindStringSubmatch(line); m != nil {
+			if v, err := strconv.ParseFloat(m[1], 64); err == nil {
+				r.FanRPM = append(r.FanRPM, v)
+			}
+		}
+	}
+	return r
+}
+
+// recordThermalMetrics writes the CPU temperature (when reported) and one
+// fan_rpm sample per fan.
+func recordThermalMetrics(database MetricSink, r thermalReading) error {
+	labels := map[string]string{"host": "localhost"}
+	if r.HasCPUTemp {
+		if err := database.InsertMetric("cpu_temperature_c", r.CPUTempC, labels); err != nil {
+			return err
+		}
+	}
+	for i, rpm := range r.FanRPM {
+		if err := database.InsertMetric("fan_rpm", rpm, map[string]string{"host": "localhost", "fan": strconv.Itoa(i)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+//go:build darwin
+
+package collector
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+var (
+	thermalMu  sync.Mutex
+	thermalOff bool
+)
+
+// thermalUnavailable stops thermal collection for the rest of the run and
+// reports why, once. The cause (no root, no SMC sensors) won't fix itself.
+func thermalUnavailable(reason string) error {
+	thermalMu.Lock()
+	defer thermalMu.Unlock()
+	if thermalOff {
+		return nil
+	}
+	thermalOff = true
+	return fmt.Errorf("thermal collection disabled: %s", reason)
+}
+
+// collectThermalMetrics records the CPU die temperature and fan speeds from
+// the SMC via powermetrics, which must run as root.
+func collectThermalMetrics(database MetricSink) error {
+	thermalMu.Lock()
+	off := thermalOff
+	thermalMu.Unlock()
+	if !thermalEnabled || off {
+		return nil
+	}
+
+	if os.Geteuid() != 0 {
+		return thermalUnavailable("powermetrics needs root; run zenith-server with sudo or set enable_thermal to false")
+	}
+
+	cmd := exec.Command("powermetrics", "--samplers", "smc", "-i", "1000", "-n", "1")
+	output, err := collectionCombinedOutput(cmd)
+	if err != nil {
+		return thermalUnavailable(fmt.Sprintf("powermetrics failed: %v: %s", err, strings.TrimSpace(string(output))))
+	}
+
+	r := parsePowermetricsSMC(string(output))
+	if !r.HasCPUTemp && len(r.FanRPM) == 0 {
+		return thermalUnavailable("powermetrics reported no SMC temperature or fan readings (Apple Silicon Macs do not expose them)")
+	}
+
+	return recordThermalMetrics(database, r)
+}
@@ -0,0 +1,40 @@
+package collector
+
+import "testing"
+
+func TestParsePowermetricsSMC(t *testing.T) {
+	out := `Machine model: MacBookPro16,1
+*** Sampled system activity (Wed Mar  6 10:00:00 2024 -0800) (1004.12ms elapsed) ***
+
+**** SMC sensors ****
+
+CPU Thermal level: 0
+GPU Thermal level: 0
+IO Thermal level: 0
+Fan: 2161.2 rpm
+CPU die temperature: 61.94 C
+GPU die temperature: 52.00 C
+CPU Plimit: 0.00
+`
+	r := parsePowermetricsSMC(out)
+	if !r.HasCPUTemp || r.CPUTempC != 61.94 {
+		t.Errorf("Expected CPU temperature 61.94, got %v (found=%v)", r.CPUTempC, r.HasCPUTemp)
+	}
+	if len(r.FanRPM) != 1 || r.FanRPM[0] != 2161.2 {
+		t.Errorf("Expected one fan at 2161.2 rpm, got %v", r.FanRPM)
+	}
+
+	sink := &recordingSink{}
+	if err := recordThermalMetrics(sink, r); err != nil {
+		t.Fatal(err)
+	}
+	assertSamples(t, sink, []string{
+		`cpu_temperature_c{host="localhost"} 61.94`,
+		`fan_rpm{fan="0",host="localhost"} 2161.2`,
+	})
+
+	empty := parsePowermetricsSMC("**** Thermal pressure ****\n\nCurrent pressure level: Nominal\n")
+	if empty.HasCPUTemp || len(empty.FanRPM) != 0 {
+		t.Errorf("Expected no readings, got %+v", empty)
+	}
+}
@@ -0,0 +1,29 @@
+package collector
+
+// Processes below processMemThresholdMB of resident memory are not stored
+// at all, and process CPU is only stored above processCPUThreshold percent
+// of one core, to keep idle processes from flooding VictoriaMetrics.
+var (
+	processCPUThreshold   = 1.0
+	processMemThresholdMB = 50.0
+)
+
+// SetProcessThresholds sets the CPU (percent of one core) and memory (MB)
+// a process needs before its samples are stored. Negative values are
+// treated as zero, which stores every process.
+func SetProcessThresholds(cpuPct, memMB float64) {
+	processCPUThreshold = max(cpuPct, 0)
+	processMemThresholdMB = max(memMB, 0)
+}
+
+// keepProcess reports whether a process using rssBytes of memory is large
+// enough to store.
+func keepProcess(rssBytes uint64) bool {
+	return float64(rssBytes)/1024/1024 >= processMemThresholdMB
+}
+
+// keepProcessCPU reports whether a process's CPU percentage is high enough
+// to store.
+func keepProcessCPU(cpuPct float64) bool {
+	return cpuPct > processCPUThreshold
+}
@@ -0,0 +1,27 @@
+package collector
+
+import "testing"
+
+func TestProcessThresholds(t *testing.T) {
+	defer SetProcessThresholds(1.0, 50)
+
+	if keepProcess(40*1024*1024) || !keepProcess(50*1024*1024) {
+		t.Error("Expected the default memory threshold to be 50MB")
+	}
+	if keepProcessCPU(1.0) || !keepProcessCPU(1.5) {
+		t.Error("Expected the default CPU threshold to be 1%")
+	}
+
+	SetProcessThresholds(0.1, 5)
+	if !keepProcess(6*1024*1024) || keepProcess(4*1024*1024) {
+		t.Error("Expected a 5MB memory threshold")
+	}
+	if !keepProcessCPU(0.2) {
+		t.Error("Expected a 0.1% CPU threshold")
+	}
+
+	SetProcessThresholds(-1, -1)
+	if !keepProcess(0) || !keepProcessCPU(0.01) {
+		t.Error("Expected negative thresholds to store every process")
+	}
+}
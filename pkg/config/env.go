@@ -0,0 +1,129 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix starts the name of every environment variable that overrides a
+// config field: ZENITH_ followed by the field's JSON name in upper case, e.g.
+// ZENITH_SERVER_PORT for server_port.
+const EnvPrefix = "ZENITH_"
+
+// ApplyEnv overrides fields from the environment, so env wins over
+// config.json, which wins over the built-in defaults. Strings are taken
+// as-is; numbers and booleans are parsed; []string fields take a
+// comma-separated list or a JSON array, and other fields (relabel) take
+// JSON. ZENITH_METRICS_URL and ZENITH_LOGS_URL set the host and port
+// together. Every invalid value is reported, not just the first.
+func (c *Config) ApplyEnv() error {
+	return c.applyEnv(os.LookupEnv)
+}
+
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	var problems []string
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if tag == "" || tag == "-" {
+			continue
+		}
+		name := EnvPrefix + strings.ToUpper(tag)
+		raw, ok := lookup(name)
+		if !ok {
+			continue
+		}
+		if err := setField(v.Field(i), raw); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+
+	for _, u := range []struct {
+		name string
+		host *string
+		port *int
+	}{
+		{EnvPrefix + "METRICS_URL", &c.MetricsHost, &c.MetricsPort},
+		{EnvPrefix + "LOGS_URL", &c.LogsHost, &c.LogsPort},
+	} {
+		raw, ok := lookup(u.name)
+		if !ok {
+			continue
+		}
+		if err := setHostPort(raw, u.host, u.port); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", u.name, err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid environment overrides:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
+
+// setField parses raw into field according to its type.
+func setField(field reflect.Value, raw string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", raw)
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", raw)
+		}
+		field.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", raw)
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(raw), "[") {
+			var items []string
+			for _, item := range strings.Split(raw, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			field.Set(reflect.ValueOf(items))
+			return nil
+		}
+		fallthrough
+	default:
+		if err := json.Unmarshal([]byte(raw), field.Addr().Interface()); err != nil {
+			return fmt.Errorf("invalid JSON: %v", err)
+		}
+	}
+	return nil
+}
+
+// setHostPort sets host, and port if the URL has one, from a URL such as
+// http://victoria:8428.
+func setHostPort(raw string, host *string, port *int) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return fmt.Errorf("%q is not a URL like http://host:port", raw)
+	}
+	*host = u.Hostname()
+	if p := u.Port(); p != "" {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return fmt.Errorf("invalid port in %q", raw)
+		}
+		*port = n
+	}
+	return nil
+}
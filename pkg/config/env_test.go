@@ -0,0 +1,77 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfig_ApplyEnv(t *testing.T) {
+	env := map[string]string{
+		"ZENITH_SERVER_PORT":         "9090",
+		"ZENITH_LLM_PROVIDER":        "ollama",
+		"ZENITH_OLLAMA_MODEL":        "llama3:8b",
+		"ZENITH_METRICS_URL":         "http://victoria-metrics:18428",
+		"ZENITH_LOGS_URL":            "http://victoria-logs",
+		"ZENITH_TAG_RUN_ID":          "true",
+		"ZENITH_EXPLAIN_TEMPERATURE": "0.5",
+		"ZENITH_EVENT_LOG_CHANNELS":  "System, Security",
+		"ZENITH_METRICS_MIRROR_URLS": `["http://a:8428","http://b:8428"]`,
+		"ZENITH_RELABEL":             `[{"action":"drop","source_labels":["__name__"],"regex":"fan_rpm"}]`,
+	}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+
+	cfg := &Config{ServerPort: 8080, MetricsPort: 8428, LogsHost: "localhost", LogsPort: 9428, LLMProvider: "llamacpp"}
+	if err := cfg.applyEnv(lookup); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ServerPort != 9090 || cfg.LLMProvider != "ollama" || cfg.OllamaModel != "llama3:8b" {
+		t.Errorf("Expected scalar overrides, got port %d provider %q model %q", cfg.ServerPort, cfg.LLMProvider, cfg.OllamaModel)
+	}
+	if cfg.MetricsHost != "victoria-metrics" || cfg.MetricsPort != 18428 {
+		t.Errorf("Expected ZENITH_METRICS_URL to set host and port, got %s:%d", cfg.MetricsHost, cfg.MetricsPort)
+	}
+	if cfg.LogsHost != "victoria-logs" || cfg.LogsPort != 9428 {
+		t.Errorf("Expected ZENITH_LOGS_URL without a port to keep the port, got %s:%d", cfg.LogsHost, cfg.LogsPort)
+	}
+	if !cfg.TagRunID || cfg.ExplainTemperature != 0.5 {
+		t.Errorf("Expected bool and float overrides, got %v %v", cfg.TagRunID, cfg.ExplainTemperature)
+	}
+	if strings.Join(cfg.EventLogChannels, "|") != "System|Security" {
+		t.Errorf("Expected a comma-separated list, got %q", cfg.EventLogChannels)
+	}
+	if len(cfg.MetricsMirrorURLs) != 2 || len(cfg.Relabel) != 1 || cfg.Relabel[0].Action != "drop" {
+		t.Errorf("Expected JSON overrides, got %q and %+v", cfg.MetricsMirrorURLs, cfg.Relabel)
+	}
+
+	env = map[string]string{"ZENITH_SERVER_PORT": "eighty", "ZENITH_TAG_RUN_ID": "maybe", "ZENITH_LOGS_URL": "::"}
+	err := (&Config{}).applyEnv(lookup)
+	if err == nil {
+		t.Fatal("Expected invalid overrides to fail")
+	}
+	for _, name := range []string{"ZENITH_SERVER_PORT", "ZENITH_TAG_RUN_ID", "ZENITH_LOGS_URL"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("Expected the error to mention %s, got:\n%v", name, err)
+		}
+	}
+}
+
+func TestLoadConfig_EnvOverridesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"server_port": 8181, "ollama_model": "from-file"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ZENITH_SERVER_PORT", "9191")
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ServerPort != 9191 || cfg.OllamaModel != "from-file" || cfg.LogsPort != 9428 {
+		t.Errorf("Expected env over file over defaults, got port %d model %q logs port %d", cfg.ServerPort, cfg.OllamaModel, cfg.LogsPort)
+	}
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ResolveSecret returns the secret a config value refers to. "file:///run/secrets/key"
+// reads it from a file (e.g. a mounted Kubernetes secret) with trailing
+// whitespace trimmed, "env://NAME" reads it from an environment variable,
+// and anything else is the secret itself.
+func ResolveSecret(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, "file://"):
+		return ReadSecretFile(strings.TrimPrefix(value, "file://"))
+	case strings.HasPrefix(value, "env://"):
+		name := strings.TrimPrefix(value, "env://")
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return strings.TrimSpace(secret), nil
+	default:
+		return value, nil
+	}
+}
+
+// ReadSecretFile reads a secret from path, dropping the trailing newline
+// and whitespace that editors and secret mounts tend to add.
+func ReadSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %v", err)
+	}
+	secret := strings.TrimRight(string(data), " \t\r\n")
+	if secret == "" {
+		return "", fmt.Errorf("secret file %s is empty", path)
+	}
+	return secret, nil
+}
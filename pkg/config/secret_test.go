@@ -0,0 +1,39 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveSecret(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gemini_key")
+	if err := os.WriteFile(path, []byte("AIza-from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ZENITH_TEST_KEY", "AIza-from-env")
+
+	cases := map[string]string{
+		"AIza-plain":            "AIza-plain",
+		"file://" + path:        "AIza-from-file",
+		"env://ZENITH_TEST_KEY": "AIza-from-env",
+		"":                      "",
+	}
+	for in, want := range cases {
+		got, err := ResolveSecret(in)
+		if err != nil {
+			t.Errorf("ResolveSecret(%q): unexpected error %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ResolveSecret(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	if _, err := ResolveSecret("env://ZENITH_TEST_UNSET_KEY"); err == nil {
+		t.Error("Expected an error for an unset environment variable")
+	}
+	if _, err := ResolveSecret("file://" + filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var durationUnits = map[string]time.Duration{
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  24 * time.Hour,
+	"w":  7 * 24 * time.Hour,
+	"y":  365 * 24 * time.Hour,
+}
+
+var durationPartRe = regexp.MustCompile(`([0-9]+(?:\.[0-9]+)?)(ms|s|m|h|d|w|y)`)
+
+// ParseDuration parses a MetricsQL/LogsQL style duration such as "5m",
+// "7d", or "1h30m". Unlike time.ParseDuration it understands d, w, and y.
+// A bare number is rejected with a hint rather than guessing its unit.
+func ParseDuration(s string) (time.Duration, error) {
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return 0, fmt.Errorf("invalid duration %q: missing unit, did you mean %q?", s, s+"m")
+	}
+	parts := durationPartRe.FindAllStringSubmatchIndex(s, -1)
+	if len(parts) == 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	var total time.Duration
+	pos := 0
+	for _, p := range parts {
+		if p[0] != pos {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		n, err := strconv.ParseFloat(s[p[2]:p[3]], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %v", s, err)
+		}
+		total += time.Duration(n * float64(durationUnits[s[p[4]:p[5]]]))
+		pos = p[1]
+	}
+	if pos != len(s) {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return total, nil
+}
+
+// Range selectors (`[1d]`, subqueries `[7d:5m]`) and LogsQL `_time:` filters.
+var queryWindowRe = regexp.MustCompile(`\[([0-9][0-9a-z.]*)(?::[^\]]*)?\]|_time:([0-9][0-9a-z.]*)`)
+
+// QueryWindow returns the widest time window a MetricsQL or LogsQL query
+// reaches back over. It is a cheap cost estimate: the wider the window, the
+// more data the backend has to scan.
+func QueryWindow(query string) time.Duration {
+	var widest time.Duration
+	for _, m := range queryWindowRe.FindAllStringSubmatch(query, -1) {
+		raw := m[1]
+		if raw == "" {
+			raw = m[2]
+		}
+		if d, err := ParseDuration(raw); err == nil && d > widest {
+			widest = d
+		}
+	}
+	return widest
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"5m":    5 * time.Minute,
+		"7d":    7 * 24 * time.Hour,
+		"1h30m": 90 * time.Minute,
+		"2w":    14 * 24 * time.Hour,
+		"500ms": 500 * time.Millisecond,
+		"300s":  5 * time.Minute,
+	}
+	for in, want := range tests {
+		got, err := ParseDuration(in)
+		if err != nil || got != want {
+			t.Errorf("ParseDuration(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+
+	for _, bad := range []string{"", "5", "m5", "5x", "5m garbage"} {
+		if _, err := ParseDuration(bad); err == nil {
+			t.Errorf("ParseDuration(%q) should fail", bad)
+		}
+	}
+
+	_, err := ParseDuration("5")
+	if err == nil || !strings.Contains(err.Error(), `did you mean "5m"?`) {
+		t.Errorf("Expected a bare number to suggest a unit, got %v", err)
+	}
+}
+
+func TestQueryWindow(t *testing.T) {
+	tests := map[string]time.Duration{
+		"avg(cpu_usage_pct)":                                0,
+		"rate(srum_app_bytes_read_total[5m])":               5 * time.Minute,
+		"max_over_time(cpu_usage_pct[30d:1h]) > avg(x[1h])": 30 * 24 * time.Hour,
+		`processName:"wifid" AND _time:24h`:                 24 * time.Hour,
+		`_time:7d | limit 10`:                               7 * 24 * time.Hour,
+	}
+	for in, want := range tests {
+		if got := QueryWindow(in); got != want {
+			t.Errorf("QueryWindow(%q) = %v; want %v", in, got, want)
+		}
+	}
+}
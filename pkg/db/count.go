@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// CountLogs counts the log entries matching filter over the last window
+// (e.g. "5m"), grouped by the values of the field by. With an empty by the
+// single total is keyed "".
+func (v *VictoriaDB) CountLogs(filter, window, by string) (map[string]float64, error) {
+	return v.CountLogsContext(context.Background(), filter, window, by)
+}
+
+// CountLogsContext is CountLogs with a context that can cancel the query.
+func (v *VictoriaDB) CountLogsContext(ctx context.Context, filter, window, by string) (map[string]float64, error) {
+	if filter == "" {
+		filter = "*"
+	}
+	stats := " | stats count() as count"
+	if by != "" {
+		stats = fmt.Sprintf(" | stats by (%s) count() as count", by)
+	}
+	// QueryLogsResults ANDs its own 24h default, which window narrows.
+	query := WithTimeFilter(filter, window) + stats
+
+	results, err := v.QueryLogsResultsContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]float64, len(results))
+	for _, res := range results {
+		n, err := strconv.ParseFloat(res.Fields["count"], 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected log count %q: %v", res.Fields["count"], err)
+		}
+		key := ""
+		if by != "" {
+			key = res.Fields[by]
+		}
+		counts[key] += n
+	}
+	return counts, nil
+}
@@ -0,0 +1,27 @@
+package db
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVictoriaDB_CountLogs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		want := `(_time:5m) AND _time:24h | stats by (messageType) count() as count`
+		if got := r.URL.Query().Get("query"); got != want {
+			t.Errorf("Expected query %s, got %s", want, got)
+		}
+		w.Write([]byte(`{"messageType":"error","count":"7"}` + "\n" + `{"messageType":"info","count":"120"}` + "\n"))
+	}))
+	defer server.Close()
+
+	v := NewVictoriaDB(server.URL, server.URL)
+	counts, err := v.CountLogs("", "5m", "messageType")
+	if err != nil {
+		t.Fatalf("Failed to count logs: %v", err)
+	}
+	if len(counts) != 2 || counts["error"] != 7 || counts["info"] != 120 {
+		t.Errorf("Unexpected counts: %v", counts)
+	}
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DeleteMetrics deletes the series matching the MetricsQL series selector
+// match from every metrics backend. VictoriaMetrics removes whole series:
+// start and end are passed along but it does not delete partial ranges.
+func (v *VictoriaDB) DeleteMetrics(match string, start, end time.Time) error {
+	return v.DeleteMetricsContext(context.Background(), match, start, end)
+}
+
+// DeleteMetricsContext is DeleteMetrics with a context that can cancel it.
+func (v *VictoriaDB) DeleteMetricsContext(ctx context.Context, match string, start, end time.Time) error {
+	if strings.TrimSpace(match) == "" {
+		return fmt.Errorf("a series selector is required")
+	}
+	params := url.Values{}
+	params.Set("match[]", match)
+	if !start.IsZero() {
+		params.Set("start", start.UTC().Format(time.RFC3339))
+	}
+	if !end.IsZero() {
+		params.Set("end", end.UTC().Format(time.RFC3339))
+	}
+	return v.deleteAll(ctx, "metrics", v.MetricsURLs, "/api/v1/admin/tsdb/delete_series", params, "failed to delete series")
+}
+
+// DeleteLogs deletes the log entries matching the LogsQL filter within
+// [start, end) from every logs backend. A zero start or end leaves that side
+// of the range open.
+func (v *VictoriaDB) DeleteLogs(filter string, start, end time.Time) error {
+	return v.DeleteLogsContext(context.Background(), filter, start, end)
+}
+
+// DeleteLogsContext is DeleteLogs with a context that can cancel it.
+func (v *VictoriaDB) DeleteLogsContext(ctx context.Context, filter string, start, end time.Time) error {
+	if strings.TrimSpace(filter) == "" {
+		return fmt.Errorf("a LogsQL filter is required")
+	}
+	if !start.IsZero() || !end.IsZero() {
+		from, to := "-inf", "now"
+		if !start.IsZero() {
+			from = start.UTC().Format(time.RFC3339)
+		}
+		if !end.IsZero() {
+			to = end.UTC().Format(time.RFC3339)
+		}
+		filter = fmt.Sprintf("(%s) AND _time:[%s, %s)", filter, from, to)
+	}
+	params := url.Values{}
+	params.Set("filter", RenameField(filter, v.LogMsgField, "_msg"))
+	return v.deleteAll(ctx, "logs", v.LogsURLs, "/delete/run_task", params, "failed to delete logs")
+}
+
+// deleteAll sends a delete to every backend regardless of WriteMode: data
+// left behind on a mirror defeats the point of deleting it.
+func (v *VictoriaDB) deleteAll(ctx context.Context, kind string, backends []string, path string, params url.Values, failMsg string) error {
+	if len(backends) == 0 {
+		return fmt.Errorf("no %s backend URL configured", kind)
+	}
+	var errs []error
+	for _, base := range backends {
+		if err := v.post(ctx, base+path, "application/x-www-form-urlencoded", []byte(params.Encode()), failMsg); err != nil {
+			if len(backends) > 1 {
+				err = fmt.Errorf("%s: %v", base, err)
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
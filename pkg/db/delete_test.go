@@ -0,0 +1,63 @@
+package db
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestVictoriaDB_DeleteMetrics(t *testing.T) {
+	var gotMatch string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/admin/tsdb/delete_series" {
+			t.Errorf("Expected path /api/v1/admin/tsdb/delete_series, got %s", r.URL.Path)
+		}
+		r.ParseForm()
+		gotMatch = r.Form.Get("match[]")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	v := NewVictoriaDB(server.URL, server.URL)
+	if err := v.DeleteMetrics(`{__name__="test_metric"}`, time.Time{}, time.Time{}); err != nil {
+		t.Fatalf("Failed to delete series: %v", err)
+	}
+	if gotMatch != `{__name__="test_metric"}` {
+		t.Errorf("Expected match[] to be forwarded, got %q", gotMatch)
+	}
+
+	if err := v.DeleteMetrics("  ", time.Time{}, time.Time{}); err == nil {
+		t.Error("Expected an empty selector to be rejected")
+	}
+}
+
+func TestVictoriaDB_DeleteLogs_AllBackends(t *testing.T) {
+	var filters []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/delete/run_task" {
+			t.Errorf("Expected path /delete/run_task, got %s", r.URL.Path)
+		}
+		r.ParseForm()
+		filters = append(filters, r.Form.Get("filter"))
+	})
+	primary := httptest.NewServer(handler)
+	defer primary.Close()
+	mirror := httptest.NewServer(handler)
+	defer mirror.Close()
+
+	v := NewVictoriaDB(primary.URL, primary.URL+","+mirror.URL)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	if err := v.DeleteLogs(`processName:"test"`, start, end); err != nil {
+		t.Fatalf("Failed to delete logs: %v", err)
+	}
+
+	if len(filters) != 2 {
+		t.Fatalf("Expected delete on both backends, got %d", len(filters))
+	}
+	want := `(processName:"test") AND _time:[2024-01-01T00:00:00Z, 2024-01-01T01:00:00Z)`
+	if filters[0] != want {
+		t.Errorf("Expected filter %q, got %q", want, filters[0])
+	}
+}
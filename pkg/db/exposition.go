@@ -0,0 +1,70 @@
+package db
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// writeSample writes one sample in the Prometheus text exposition format
+// that /api/v1/import/prometheus reads:
+//
+//	metric_name{label1="val1",label2="val2"} value timestamp_ms
+//
+// Label values are escaped by renderLabels, so any string survives the round
+// trip. The value is written with the fewest digits that parse back to it
+// exactly, and as NaN, +Inf or -Inf where it is one. The format has no way
+// to escape the name and label keys; pass them through sanitizeName and
+// sanitizeLabels first.
+func writeSample(w io.Writer, name string, labels map[string]string, value float64, ts time.Time) {
+	fmt.Fprintf(w, "%s%s %s %d\n", name, renderLabels(labels), strconv.FormatFloat(value, 'g', -1, 64), ts.UnixMilli())
+}
+
+// sanitizeName replaces every character the exposition format doesn't allow
+// in a metric name (colon allowed) or label name (colon not allowed) with
+// an underscore, and prefixes one if the name would start with a digit or
+// is empty.
+func sanitizeName(name string, colonOK bool) string {
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		case r == ':' && colonOK:
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+// sanitizeLabels returns labels with every key passed through sanitizeName.
+// Keys that sanitize to the same name keep one of their values.
+func sanitizeLabels(labels map[string]string) map[string]string {
+	clean := true
+	for k := range labels {
+		if sanitizeName(k, false) != k {
+			clean = false
+			break
+		}
+	}
+	if clean {
+		return labels
+	}
+	out := make(map[string]string, len(labels))
+	for k, v := range labels {
+		out[sanitizeName(k, false)] = v
+	}
+	return out
+}
@@ -0,0 +1,229 @@
+package db
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	metricNameRE = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNameRE  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// parseSampleLine is a reference parser for one line of the Prometheus text
+// exposition format, written from the format's spec rather than from
+// writeSample: name, optional {k="v",...} with \\, \" and \n escapes in the
+// values, value, timestamp in milliseconds.
+func parseSampleLine(line string) (string, map[string]string, float64, int64, error) {
+	i := 0
+	for i < len(line) && line[i] != '{' && line[i] != ' ' {
+		i++
+	}
+	name := line[:i]
+	if !metricNameRE.MatchString(name) {
+		return "", nil, 0, 0, fmt.Errorf("invalid metric name %q", name)
+	}
+
+	labels := map[string]string{}
+	if i < len(line) && line[i] == '{' {
+		i++
+		for {
+			if i < len(line) && line[i] == '}' {
+				i++
+				break
+			}
+			start := i
+			for i < len(line) && line[i] != '=' {
+				i++
+			}
+			key := line[start:i]
+			if !labelNameRE.MatchString(key) {
+				return "", nil, 0, 0, fmt.Errorf("invalid label name %q", key)
+			}
+			if !strings.HasPrefix(line[i:], `="`) {
+				return "", nil, 0, 0, fmt.Errorf("expected =\" after label %q", key)
+			}
+			i += 2
+			var val strings.Builder
+			for {
+				if i >= len(line) {
+					return "", nil, 0, 0, fmt.Errorf("unterminated value for label %q", key)
+				}
+				c := line[i]
+				if c == '"' {
+					i++
+					break
+				}
+				if c == '\n' {
+					return "", nil, 0, 0, fmt.Errorf("raw newline in value for label %q", key)
+				}
+				if c == '\\' {
+					if i+1 >= len(line) {
+						return "", nil, 0, 0, fmt.Errorf("dangling escape in label %q", key)
+					}
+					switch line[i+1] {
+					case '\\':
+						val.WriteByte('\\')
+					case '"':
+						val.WriteByte('"')
+					case 'n':
+						val.WriteByte('\n')
+					default:
+						return "", nil, 0, 0, fmt.Errorf("invalid escape \\%c in label %q", line[i+1], key)
+					}
+					i += 2
+					continue
+				}
+				val.WriteByte(c)
+				i++
+			}
+			if _, dup := labels[key]; dup {
+				return "", nil, 0, 0, fmt.Errorf("duplicate label %q", key)
+			}
+			labels[key] = val.String()
+			if i < len(line) && line[i] == ',' {
+				i++
+			}
+		}
+	}
+
+	fields := strings.Split(strings.TrimPrefix(line[i:], " "), " ")
+	if len(fields) != 2 {
+		return "", nil, 0, 0, fmt.Errorf("expected value and timestamp, got %q", line[i:])
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return "", nil, 0, 0, fmt.Errorf("invalid value: %v", err)
+	}
+	ts, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return "", nil, 0, 0, fmt.Errorf("invalid timestamp: %v", err)
+	}
+	return name, labels, value, ts, nil
+}
+
+// randomString draws from characters the format treats specially (space,
+// comma, equals, quotes, backslash, braces, newline), digits, and
+// multi-byte runes.
+func randomString(r *rand.Rand) string {
+	const alphabet = "abcXYZ_:09 ,=\"\\{}\n\t.-/é日🙂"
+	runes := []rune(alphabet)
+	n := r.Intn(12)
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		b.WriteRune(runes[r.Intn(len(runes))])
+	}
+	return b.String()
+}
+
+func TestWriteSample_RoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		name := randomString(r)
+		labels := map[string]string{}
+		for j := r.Intn(5); j > 0; j-- {
+			labels[randomString(r)] = randomString(r)
+		}
+		// Any bit pattern, which covers NaN, infinities, subnormals and
+		// values far outside what a fixed number of decimals can hold.
+		value := math.Float64frombits(r.Uint64())
+		switch i {
+		case 0:
+			value = math.Inf(1)
+		case 1:
+			value = math.Inf(-1)
+		case 2:
+			value = math.NaN()
+		case 3:
+			value = 1e-300
+		case 4:
+			value = 0.1
+		}
+		ts := time.UnixMilli(r.Int63n(1 << 42))
+
+		wantName, wantLabels := sanitizeName(name, true), sanitizeLabels(labels)
+		var buf bytes.Buffer
+		writeSample(&buf, wantName, wantLabels, value, ts)
+		line := buf.String()
+		if strings.Count(line, "\n") != 1 || !strings.HasSuffix(line, "\n") {
+			t.Fatalf("Expected exactly one line for name %q labels %q, got %q", name, labels, line)
+		}
+
+		gotName, gotLabels, gotValue, gotTS, err := parseSampleLine(strings.TrimSuffix(line, "\n"))
+		if err != nil {
+			t.Fatalf("Failed to parse %q (name %q, labels %q): %v", line, name, labels, err)
+		}
+		if gotName != wantName {
+			t.Errorf("Name %q: expected %q back, got %q", name, wantName, gotName)
+		}
+		if metricNameRE.MatchString(name) && gotName != name {
+			t.Errorf("Expected valid name %q to be unchanged, got %q", name, gotName)
+		}
+		if len(wantLabels) == 0 {
+			wantLabels = map[string]string{}
+		}
+		if !reflect.DeepEqual(gotLabels, wantLabels) {
+			t.Errorf("Labels %q: expected %q back, got %q", labels, wantLabels, gotLabels)
+		}
+		if gotValue != value && !(math.IsNaN(gotValue) && math.IsNaN(value)) {
+			t.Errorf("Expected value %v back, got %v", value, gotValue)
+		}
+		if gotTS != ts.UnixMilli() {
+			t.Errorf("Expected timestamp %d back, got %d", ts.UnixMilli(), gotTS)
+		}
+	}
+}
+
+func TestSanitizeName(t *testing.T) {
+	tests := []struct {
+		in      string
+		colonOK bool
+		want    string
+	}{
+		{"cpu_usage_pct", true, "cpu_usage_pct"},
+		{"job:rate5m", true, "job:rate5m"},
+		{"job:rate5m", false, "job_rate5m"},
+		{"process name", false, "process_name"},
+		{"5xx", true, "_5xx"},
+		{"", true, "_"},
+		{"café", false, "caf_"},
+	}
+	for _, tt := range tests {
+		if got := sanitizeName(tt.in, tt.colonOK); got != tt.want {
+			t.Errorf("sanitizeName(%q, %v) = %q, want %q", tt.in, tt.colonOK, got, tt.want)
+		}
+	}
+}
+
+func TestVictoriaDB_InsertMetricEscaping(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	v := NewVictoriaDB(server.URL, server.URL)
+	labels := map[string]string{"process name": "Google Chrome Helper", "path": "C:\\Program Files\n\"x\""}
+	ts := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := v.InsertMetricAt("process memory", 1.5, labels, ts); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `process_memory{path="C:\\Program Files\n\"x\"",process_name="Google Chrome Helper"} 1.5 1709294400000` + "\n"
+	if body != want {
+		t.Errorf("Expected %q, got %q", want, body)
+	}
+}
@@ -0,0 +1,143 @@
+package db
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LatestSamples keeps the most recent sample written for each series, so
+// the collected metrics can be exposed for scraping as well as pushed to
+// VictoriaMetrics.
+type LatestSamples struct {
+	// Counters names the metrics to expose as OpenMetrics counters; every
+	// other metric is a gauge. Counter names must end in _total.
+	Counters map[string]bool
+
+	// MaxAge drops series that haven't been written for this long, such as
+	// processes that exited. Zero keeps them forever.
+	MaxAge time.Duration
+
+	// DropLabels are left off the cached series, such as a run_id that
+	// would otherwise keep every collection run's samples as series of
+	// their own.
+	DropLabels []string
+
+	mu     sync.Mutex
+	series map[string]latestSample
+	now    func() time.Time
+}
+
+type latestSample struct {
+	name   string
+	labels string // rendered `{k="v",...}`, sorted
+	value  float64
+	ts     time.Time
+	seen   time.Time
+}
+
+// NewLatestSamples returns an empty cache that exposes the named metrics as
+// counters.
+func NewLatestSamples(counters []string) *LatestSamples {
+	l := &LatestSamples{Counters: make(map[string]bool), series: make(map[string]latestSample), now: time.Now}
+	for _, name := range counters {
+		l.Counters[name] = true
+	}
+	return l
+}
+
+// record stores a sample unless the cache already holds a newer one for
+// the series, which happens when historical (SRUM) rows arrive out of
+// order. A NaN is a staleness marker and removes the series.
+func (l *LatestSamples) record(name string, labels map[string]string, value float64, ts time.Time) {
+	if l == nil {
+		return
+	}
+	if len(l.DropLabels) > 0 {
+		kept := make(map[string]string, len(labels))
+		for k, v := range labels {
+			kept[k] = v
+		}
+		for _, k := range l.DropLabels {
+			delete(kept, k)
+		}
+		labels = kept
+	}
+	rendered := renderLabels(labels)
+	key := name + rendered
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if math.IsNaN(value) {
+		delete(l.series, key)
+		return
+	}
+	if prev, ok := l.series[key]; ok && prev.ts.After(ts) {
+		return
+	}
+	l.series[key] = latestSample{name: name, labels: rendered, value: value, ts: ts, seen: l.now()}
+}
+
+// WriteOpenMetrics renders the cached samples in the OpenMetrics text
+// format. Samples carry no timestamp, so the scraper stamps them.
+func (l *LatestSamples) WriteOpenMetrics(w io.Writer) error {
+	l.mu.Lock()
+	families := make(map[string][]latestSample)
+	for key, s := range l.series {
+		if l.MaxAge > 0 && l.now().Sub(s.seen) > l.MaxAge {
+			delete(l.series, key)
+			continue
+		}
+		families[s.name] = append(families[s.name], s)
+	}
+	l.mu.Unlock()
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		samples := families[name]
+		sort.Slice(samples, func(i, j int) bool { return samples[i].labels < samples[j].labels })
+
+		if l.Counters[name] && strings.HasSuffix(name, "_total") {
+			fmt.Fprintf(&b, "# TYPE %s counter\n", strings.TrimSuffix(name, "_total"))
+		} else {
+			fmt.Fprintf(&b, "# TYPE %s gauge\n", name)
+		}
+		for _, s := range samples {
+			fmt.Fprintf(&b, "%s%s %g\n", s.name, s.labels, s.value)
+		}
+	}
+	b.WriteString("# EOF\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// renderLabels renders labels as a sorted `{k="v",...}` suffix, escaped for
+// the text formats.
+func renderLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, k, escaper.Replace(labels[k])))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
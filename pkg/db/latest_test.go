@@ -0,0 +1,77 @@
+package db
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLatestSamples_WriteOpenMetrics(t *testing.T) {
+	l := NewLatestSamples([]string{"srum_app_bytes_read_total"})
+	now := time.Now()
+	l.record("cpu_usage_pct", map[string]string{"host": "localhost"}, 12.5, now)
+	l.record("cpu_usage_pct", map[string]string{"host": "localhost"}, 40, now.Add(time.Minute))
+	l.record("srum_app_bytes_read_total", map[string]string{"app_name": "a\"b"}, 100, now)
+	// An older historical row must not replace a newer sample.
+	l.record("srum_app_bytes_read_total", map[string]string{"app_name": "a\"b"}, 50, now.Add(-time.Hour))
+	l.record("process_memory_mb", map[string]string{"process_name": "gone"}, 10, now)
+	l.record("process_memory_mb", map[string]string{"process_name": "gone"}, math.NaN(), now)
+
+	var b strings.Builder
+	if err := l.WriteOpenMetrics(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := "# TYPE cpu_usage_pct gauge\n" +
+		"cpu_usage_pct{host=\"localhost\"} 40\n" +
+		"# TYPE srum_app_bytes_read counter\n" +
+		"srum_app_bytes_read_total{app_name=\"a\\\"b\"} 100\n" +
+		"# EOF\n"
+	if b.String() != want {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", b.String(), want)
+	}
+
+	l.MaxAge = time.Hour
+	l.now = func() time.Time { return now.Add(2 * time.Hour) }
+	b.Reset()
+	l.WriteOpenMetrics(&b)
+	if b.String() != "# EOF\n" {
+		t.Errorf("Expected series not written for MaxAge to expire, got:\n%s", b.String())
+	}
+}
+
+func TestLatestSamples_DropLabels(t *testing.T) {
+	l := NewLatestSamples(nil)
+	l.DropLabels = []string{"run_id"}
+	now := time.Now()
+	l.record("cpu_usage_pct", map[string]string{"host": "localhost", "run_id": "r1"}, 12, now)
+	l.record("cpu_usage_pct", map[string]string{"host": "localhost", "run_id": "r2"}, 30, now.Add(time.Minute))
+
+	var b strings.Builder
+	l.WriteOpenMetrics(&b)
+	want := "# TYPE cpu_usage_pct gauge\ncpu_usage_pct{host=\"localhost\"} 30\n# EOF\n"
+	if b.String() != want {
+		t.Errorf("Expected one series without run_id, got:\n%s", b.String())
+	}
+}
+
+func TestVictoriaDB_InsertMetricRecordsLatest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	v := NewVictoriaDB(server.URL, server.URL)
+	v.Latest = NewLatestSamples(nil)
+	if err := v.InsertMetric("memory_used_mb", 512, map[string]string{"host": "localhost"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	v.Latest.WriteOpenMetrics(&b)
+	if !strings.Contains(b.String(), `memory_used_mb{host="localhost"} 512`) {
+		t.Errorf("Expected the written sample to be cached, got:\n%s", b.String())
+	}
+}
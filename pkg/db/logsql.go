@@ -0,0 +1,130 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// QuoteLogsQL returns s as a double-quoted LogsQL string literal, escaping
+// backslashes and quotes so user-supplied text cannot break out of the filter.
+func QuoteLogsQL(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// ExactFilter builds an exact-match LogsQL filter: field:"value".
+func ExactFilter(field, value string) string {
+	return field + ":" + QuoteLogsQL(value)
+}
+
+// RegexFilter builds a LogsQL regular-expression filter: field:~"pattern".
+func RegexFilter(field, pattern string) string {
+	return field + ":~" + QuoteLogsQL(pattern)
+}
+
+// SubstringFilter builds a case-insensitive substring match on field. The
+// needle is regex-escaped, so "C++ Helper" matches literally.
+func SubstringFilter(field, needle string) string {
+	return RegexFilter(field, "(?i)"+regexp.QuoteMeta(needle))
+}
+
+// ProcessNameFilter matches log entries whose processName contains name,
+// ignoring case. LogsQL field matches are otherwise exact, so a question
+// about "chrome" would never match processName "Google Chrome".
+func ProcessNameFilter(name string) string {
+	return SubstringFilter("processName", name)
+}
+
+// RenameField replaces every unquoted occurrence of the field name from with
+// to, in filters (`from:value`) and pipes (`fields from`) alike. Quoted
+// strings are left alone, so a search for the literal text is unaffected.
+func RenameField(query, from, to string) string {
+	if from == "" || from == to {
+		return query
+	}
+	isIdent := func(c byte) bool {
+		return c == '_' || c == '.' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+	}
+
+	var b strings.Builder
+	inQuote := byte(0)
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case inQuote != 0 && c == '\\' && i+1 < len(query):
+			b.WriteByte(c)
+			i++
+			c = query[i]
+		case inQuote != 0 && c == inQuote:
+			inQuote = 0
+		case inQuote == 0 && (c == '"' || c == '\'' || c == '`'):
+			inQuote = c
+		case inQuote == 0 && strings.HasPrefix(query[i:], from) &&
+			(i == 0 || !isIdent(query[i-1])) &&
+			(i+len(from) == len(query) || !isIdent(query[i+len(from)])):
+			b.WriteString(to)
+			i += len(from) - 1
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// SplitPipes splits a LogsQL query into its filter expression and the
+// trailing pipe chain (including the leading "|"), ignoring pipe characters
+// inside quoted strings.
+func SplitPipes(query string) (filter, pipes string) {
+	inQuote := byte(0)
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case inQuote != 0 && c == '\\':
+			i++ // skip the escaped character
+		case inQuote != 0 && c == inQuote:
+			inQuote = 0
+		case inQuote == 0 && (c == '"' || c == '\'' || c == '`'):
+			inQuote = c
+		case inQuote == 0 && c == '|':
+			return strings.TrimSpace(query[:i]), strings.TrimSpace(query[i:])
+		}
+	}
+	return strings.TrimSpace(query), ""
+}
+
+// HasLimit reports whether the query's pipe chain already contains a
+// `limit` (or its `head` alias) pipe.
+func HasLimit(query string) bool {
+	_, pipes := SplitPipes(query)
+	for _, pipe := range strings.Split(pipes, "|") {
+		fields := strings.Fields(pipe)
+		if len(fields) > 0 && (strings.EqualFold(fields[0], "limit") || strings.EqualFold(fields[0], "head")) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithLimit appends a `| limit n` pipe unless the query already limits its
+// results. A non-positive n leaves the query unchanged.
+func WithLimit(query string, n int) string {
+	if n <= 0 || HasLimit(query) {
+		return query
+	}
+	return fmt.Sprintf("%s | limit %d", strings.TrimSpace(query), n)
+}
+
+// WithTimeFilter ANDs a `_time:<window>` filter onto the query's filter
+// expression, keeping any pipes after it.
+func WithTimeFilter(query, window string) string {
+	filter, pipes := SplitPipes(query)
+	if filter == "" || filter == "*" {
+		filter = "_time:" + window
+	} else {
+		filter = fmt.Sprintf("(%s) AND _time:%s", filter, window)
+	}
+	if pipes != "" {
+		return filter + " " + pipes
+	}
+	return filter
+}
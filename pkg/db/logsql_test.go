@@ -0,0 +1,38 @@
+package db
+
+import "testing"
+
+func TestLogsQLBuilder(t *testing.T) {
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"exact", ExactFilter("processName", "wifid"), `processName:"wifid"`},
+		{"exact escapes quotes", ExactFilter("eventMessage", `say "hi"`), `eventMessage:"say \"hi\""`},
+		{"regex", RegexFilter("processName", "^kernel"), `processName:~"^kernel"`},
+		{"process substring", ProcessNameFilter("chrome"), `processName:~"(?i)chrome"`},
+		{"process metacharacters", ProcessNameFilter("C++ Helper (GPU)"), `processName:~"(?i)C\\+\\+ Helper \\(GPU\\)"`},
+		{"backslash path", ProcessNameFilter(`C:\Windows`), `processName:~"(?i)C:\\\\Windows"`},
+	}
+
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, tt.got)
+		}
+	}
+}
+
+func TestRenameField(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{`eventMessage:"error"`, `_msg:"error"`},
+		{`processName:kernel AND eventMessage:~"(?i)fail" | fields _time, eventMessage`, `processName:kernel AND _msg:~"(?i)fail" | fields _time, _msg`},
+		{`"eventMessage:" AND myeventMessage:x AND eventMessages:y`, `"eventMessage:" AND myeventMessage:x AND eventMessages:y`},
+		{`processName:"a\"eventMessage"`, `processName:"a\"eventMessage"`},
+	}
+	for _, tt := range tests {
+		if got := RenameField(tt.in, "eventMessage", "_msg"); got != tt.want {
+			t.Errorf("RenameField(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
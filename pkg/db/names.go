@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+)
+
+// StoredMetricNames lists every metric name VictoriaMetrics holds data for,
+// sorted.
+func (v *VictoriaDB) StoredMetricNames() ([]string, error) {
+	return v.StoredMetricNamesContext(context.Background())
+}
+
+// StoredMetricNamesContext is StoredMetricNames with a context that can
+// cancel the request.
+func (v *VictoriaDB) StoredMetricNamesContext(ctx context.Context) ([]string, error) {
+	return v.LabelValuesContext(ctx, "__name__")
+}
+
+// LabelValues returns every value of label across the stored series,
+// sorted, e.g. the hosts reporting into a shared VictoriaMetrics.
+func (v *VictoriaDB) LabelValues(label string) ([]string, error) {
+	return v.LabelValuesContext(context.Background(), label)
+}
+
+// LabelValuesContext is LabelValues with a context that can cancel the
+// request.
+func (v *VictoriaDB) LabelValuesContext(ctx context.Context, label string) ([]string, error) {
+	resp, err := v.getFirst(ctx, v.MetricsURLs, "/api/v1/label/"+url.PathEscape(label)+"/values")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := decodedBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(body)
+		return nil, fmt.Errorf("victoria metrics label values failed (%d): %s", resp.StatusCode, string(msg))
+	}
+
+	var result struct {
+		Status string   `json:"status"`
+		Data   []string `json:"data"`
+	}
+	if err := json.NewDecoder(body).Decode(&result); err != nil {
+		return nil, err
+	}
+	sort.Strings(result.Data)
+	return result.Data, nil
+}
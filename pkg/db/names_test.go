@@ -0,0 +1,46 @@
+package db
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestVictoriaDB_StoredMetricNames(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/label/__name__/values" {
+			t.Errorf("Expected path /api/v1/label/__name__/values, got %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"status":"success","data":["memory_used_mb","cpu_usage_pct"]}`))
+	}))
+	defer server.Close()
+
+	v := NewVictoriaDB(server.URL, server.URL)
+	names, err := v.StoredMetricNames()
+	if err != nil {
+		t.Fatalf("Failed to list metric names: %v", err)
+	}
+	if !slices.Equal(names, []string{"cpu_usage_pct", "memory_used_mb"}) {
+		t.Errorf("Expected sorted names, got %v", names)
+	}
+}
+
+func TestVictoriaDB_LabelValues(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/label/host/values" {
+			t.Errorf("Expected path /api/v1/label/host/values, got %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"status":"success","data":["web-2","db-1","web-1"]}`))
+	}))
+	defer server.Close()
+
+	v := NewVictoriaDB(server.URL, server.URL)
+	hosts, err := v.LabelValues("host")
+	if err != nil {
+		t.Fatalf("Failed to list label values: %v", err)
+	}
+	if !slices.Equal(hosts, []string{"db-1", "web-1", "web-2"}) {
+		t.Errorf("Expected sorted hosts, got %v", hosts)
+	}
+}
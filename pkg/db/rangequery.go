@@ -0,0 +1,208 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeSeries is one series returned by a MetricsQL range query.
+type TimeSeries struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Points []Point           `json:"points"`
+}
+
+// Point is one sample of a TimeSeries.
+type Point struct {
+	Timestamp time.Time
+	Value     float64
+
+	// raw is the value exactly as VictoriaMetrics rendered it.
+	raw string
+}
+
+// MarshalJSON encodes a point as a [unix_seconds, value] pair, the shape
+// VictoriaMetrics itself returns, with non-finite values as null.
+func (p Point) MarshalJSON() ([]byte, error) {
+	ts := float64(p.Timestamp.UnixMilli()) / 1000
+	if math.IsNaN(p.Value) || math.IsInf(p.Value, 0) {
+		return json.Marshal([]interface{}{ts, nil})
+	}
+	return json.Marshal([]interface{}{ts, p.Value})
+}
+
+// rangePoints is how many points RangeStep aims for across a window: enough
+// to show a trend without flooding the LLM's context.
+const rangePoints = 60
+
+// RangeStep picks the step for a range query over window so it returns
+// about 60 points, rounded up to a whole minute. Metrics are written every
+// collection cycle, so a finer step would only repeat samples.
+func RangeStep(window time.Duration) time.Duration {
+	step := (window/rangePoints + time.Minute - 1).Truncate(time.Minute)
+	return max(step, time.Minute)
+}
+
+// QueryMetricsRange runs a MetricsQL range query from start to end, one
+// point every step, and returns the series.
+func (v *VictoriaDB) QueryMetricsRange(query string, start, end time.Time, step time.Duration) ([]TimeSeries, error) {
+	return v.QueryMetricsRangeContext(context.Background(), query, start, end, step)
+}
+
+// QueryMetricsRangeContext is QueryMetricsRange with a context that can
+// cancel the query.
+func (v *VictoriaDB) QueryMetricsRangeContext(ctx context.Context, query string, start, end time.Time, step time.Duration) ([]TimeSeries, error) {
+	if step <= 0 {
+		return nil, fmt.Errorf("range query step must be positive, got %s", step)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("range query end %s is before start %s", end.Format(time.RFC3339), start.Format(time.RFC3339))
+	}
+
+	q := url.Values{}
+	q.Set("query", query)
+	q.Set("start", strconv.FormatInt(start.Unix(), 10))
+	q.Set("end", strconv.FormatInt(end.Unix(), 10))
+	q.Set("step", fmt.Sprintf("%ds", int64(math.Ceil(step.Seconds()))))
+	if v.QueryTimeout > 0 {
+		q.Set("timeout", v.QueryTimeout.String())
+	}
+
+	resp, err := v.getFirst(ctx, v.MetricsURLs, "/api/v1/query_range?"+q.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := decodedBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(body)
+		return nil, queryError("victoria metrics range query failed", resp.StatusCode, msg)
+	}
+
+	var result struct {
+		Status string `json:"status"`
+		Data   struct {
+			ResultType string `json:"resultType"`
+			Result     []struct {
+				Metric map[string]string `json:"metric"`
+				Values [][]interface{}   `json:"values"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+
+	if err := json.NewDecoder(body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	series := make([]TimeSeries, 0, len(result.Data.Result))
+	for _, res := range result.Data.Result {
+		ts := TimeSeries{Name: res.Metric["__name__"], Points: make([]Point, 0, len(res.Values))}
+		for k, val := range res.Metric {
+			if k != "__name__" {
+				if ts.Labels == nil {
+					ts.Labels = make(map[string]string)
+				}
+				ts.Labels[k] = val
+			}
+		}
+
+		// Each value is a [timestamp, "value"] pair
+		for _, pair := range res.Values {
+			if len(pair) < 2 {
+				continue
+			}
+			var p Point
+			if sec, ok := pair[0].(float64); ok {
+				whole, frac := math.Modf(sec)
+				p.Timestamp = time.Unix(int64(whole), int64(frac*1e9)).UTC()
+			}
+			p.raw = fmt.Sprintf("%v", pair[1])
+			p.Value, _ = strconv.ParseFloat(p.raw, 64)
+			ts.Points = append(ts.Points, p)
+		}
+		series = append(series, ts)
+	}
+
+	return series, nil
+}
+
+// formatSeriesLimit and formatPointsLimit cap what FormatRangeResults sends
+// to the LLM. A query without aggregation (say, per process memory over a
+// week) can return thousands of series, which would overflow the model's
+// context long before it helped the explanation.
+const (
+	formatSeriesLimit = 20
+	formatPointsLimit = 120
+)
+
+// FormatRangeResults renders range query series for the LLM: per series, a
+// header with the span and the min, max, first and last values, then the
+// points as `time value` pairs so a trend can be read off directly. Only the
+// first formatSeriesLimit series are shown, and a longer series lists every
+// nth point, always including the last; the header still covers them all.
+func FormatRangeResults(series []TimeSeries) string {
+	var out bytes.Buffer
+	for i, s := range series {
+		if i == formatSeriesLimit {
+			fmt.Fprintf(&out, "... %d more series omitted\n", len(series)-i)
+			break
+		}
+		name := s.Name
+		if name == "" {
+			name = "result"
+		}
+		if len(s.Points) == 0 {
+			fmt.Fprintf(&out, "%s%s: no points\n", name, renderLabels(s.Labels))
+			continue
+		}
+
+		first, last := s.Points[0], s.Points[len(s.Points)-1]
+		lo, hi := math.Inf(1), math.Inf(-1)
+		for _, p := range s.Points {
+			if !math.IsNaN(p.Value) {
+				lo, hi = math.Min(lo, p.Value), math.Max(hi, p.Value)
+			}
+		}
+		minMax := "no numeric values"
+		if lo <= hi {
+			minMax = fmt.Sprintf("min %g, max %g", lo, hi)
+		}
+		fmt.Fprintf(&out, "%s%s: %d points from %s to %s; %s, first %s, last %s\n",
+			name, renderLabels(s.Labels), len(s.Points),
+			first.Timestamp.Format(time.RFC3339), last.Timestamp.Format(time.RFC3339),
+			minMax, first.raw, last.raw)
+
+		// Times of day are enough within a day; longer spans need the date.
+		layout := "15:04"
+		if last.Timestamp.Sub(first.Timestamp) >= 24*time.Hour {
+			layout = "01-02 15:04"
+		}
+		stride := (len(s.Points) + formatPointsLimit - 1) / formatPointsLimit
+		if stride > 1 {
+			fmt.Fprintf(&out, "  (showing 1 of every %d points)\n", stride)
+		}
+		parts := make([]string, 0, formatPointsLimit+1)
+		for i, p := range s.Points {
+			if i%stride == 0 || i == len(s.Points)-1 {
+				parts = append(parts, p.Timestamp.Format(layout)+" "+p.raw)
+			}
+		}
+		fmt.Fprintf(&out, "  %s\n", strings.Join(parts, ", "))
+	}
+	return out.String()
+}
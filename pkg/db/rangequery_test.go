@@ -0,0 +1,137 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVictoriaDB_QueryMetricsRange(t *testing.T) {
+	mockResponse := `{"status":"success","data":{"resultType":"matrix","result":[` +
+		`{"metric":{"__name__":"cpu_usage_pct","host":"mac"},"values":[[1700000000,"10"],[1700000300,"12.5"],[1700000600,"NaN"]]},` +
+		`{"metric":{},"values":[]}]}}`
+
+	var params map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query_range" {
+			t.Errorf("Expected /api/v1/query_range, got %s", r.URL.Path)
+		}
+		params = map[string]string{}
+		for k := range r.URL.Query() {
+			params[k] = r.URL.Query().Get(k)
+		}
+		w.Write([]byte(mockResponse))
+	}))
+	defer server.Close()
+
+	v := NewVictoriaDB(server.URL, server.URL)
+	start := time.Unix(1700000000, 0)
+	series, err := v.QueryMetricsRange("cpu_usage_pct", start, start.Add(10*time.Minute), 5*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to run range query: %v", err)
+	}
+
+	want := map[string]string{"query": "cpu_usage_pct", "start": "1700000000", "end": "1700000600", "step": "300s"}
+	for k, val := range want {
+		if params[k] != val {
+			t.Errorf("Expected %s=%s, got %q", k, val, params[k])
+		}
+	}
+
+	if len(series) != 2 {
+		t.Fatalf("Expected 2 series, got %d", len(series))
+	}
+	s := series[0]
+	if s.Name != "cpu_usage_pct" || s.Labels["host"] != "mac" || len(s.Points) != 3 {
+		t.Fatalf("Unexpected first series: %+v", s)
+	}
+	if s.Points[1].Value != 12.5 || s.Points[1].Timestamp.Unix() != 1700000300 {
+		t.Errorf("Unexpected second point: %+v", s.Points[1])
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("Failed to marshal series: %v", err)
+	}
+	if !strings.Contains(string(data), `"points":[[1700000000,10],[1700000300,12.5],[1700000600,null]]`) {
+		t.Errorf("Expected [timestamp, value] points with NaN as null, got %s", data)
+	}
+
+	text := FormatRangeResults(series)
+	for _, want := range []string{
+		`cpu_usage_pct{host="mac"}: 3 points from 2023-11-14T22:13:20Z to 2023-11-14T22:23:20Z; min 10, max 12.5, first 10, last NaN`,
+		"22:13 10, 22:18 12.5, 22:23 NaN",
+		"result: no points",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected formatted results to contain %q, got:\n%s", want, text)
+		}
+	}
+}
+
+func TestVictoriaDB_QueryMetricsRangeRejectsBadRange(t *testing.T) {
+	v := NewVictoriaDB("http://127.0.0.1:0", "http://127.0.0.1:0")
+	now := time.Now()
+	if _, err := v.QueryMetricsRange("up", now, now.Add(time.Hour), 0); err == nil {
+		t.Error("Expected an error for a zero step")
+	}
+	if _, err := v.QueryMetricsRange("up", now, now.Add(-time.Hour), time.Minute); err == nil {
+		t.Error("Expected an error for end before start")
+	}
+}
+
+func TestRangeStep(t *testing.T) {
+	tests := []struct {
+		window time.Duration
+		want   time.Duration
+	}{
+		{10 * time.Minute, time.Minute},
+		{time.Hour, time.Minute},
+		{6 * time.Hour, 6 * time.Minute},
+		{24 * time.Hour, 24 * time.Minute},
+		{61 * time.Minute, 2 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := RangeStep(tt.window); got != tt.want {
+			t.Errorf("RangeStep(%s) = %s, want %s", tt.window, got, tt.want)
+		}
+	}
+}
+
+func TestFormatRangeResults_Caps(t *testing.T) {
+	start := time.Unix(1700000000, 0).UTC()
+	series := make([]TimeSeries, formatSeriesLimit+5)
+	for i := range series {
+		series[i].Name = "process_memory_mb"
+		series[i].Labels = map[string]string{"pid": strconv.Itoa(i)}
+	}
+	for i := 0; i < 1000; i++ {
+		raw := strconv.Itoa(i)
+		series[0].Points = append(series[0].Points, Point{Timestamp: start.Add(time.Duration(i) * time.Minute), Value: float64(i), raw: raw})
+	}
+
+	text := FormatRangeResults(series)
+	if !strings.Contains(text, "... 5 more series omitted") {
+		t.Errorf("Expected the extra series to be counted, not listed, got:\n%s", text)
+	}
+	if strings.Contains(text, fmt.Sprintf(`pid="%d"`, formatSeriesLimit)) {
+		t.Errorf("Expected series past the limit to be left out")
+	}
+
+	lines := strings.Split(text, "\n")
+	if !strings.Contains(lines[0], "1000 points") || !strings.Contains(lines[0], "min 0, max 999") {
+		t.Errorf("Expected the header to cover every point, got %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "showing 1 of every 9 points") {
+		t.Errorf("Expected a note on the thinned points, got %q", lines[1])
+	}
+	points := strings.Split(lines[2], ", ")
+	if len(points) > formatPointsLimit+1 || !strings.HasSuffix(points[len(points)-1], " 999") {
+		t.Errorf("Expected at most %d points ending with the last one, got %d ending %q", formatPointsLimit+1, len(points), points[len(points)-1])
+	}
+}
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MetricNameLabel is the pseudo-label relabel rules use to match or rewrite
+// the metric name, as in Prometheus.
+const MetricNameLabel = "__name__"
+
+// RelabelRule rewrites or filters a metric before it is written, modelled on
+// Prometheus relabel_configs.
+//
+//   - keep:    drop the metric unless Regex matches the source value
+//   - drop:    drop the metric if Regex matches the source value
+//   - replace: if Regex matches, set TargetLabel to Replacement (with $1-style
+//     expansion); an empty result removes TargetLabel
+//
+// The source value is SourceLabels joined with ";" and defaults to the metric
+// name. Regex is anchored at both ends.
+type RelabelRule struct {
+	Action       string   `json:"action"`
+	SourceLabels []string `json:"source_labels,omitempty"`
+	Regex        string   `json:"regex"`
+	TargetLabel  string   `json:"target_label,omitempty"`
+	Replacement  string   `json:"replacement,omitempty"`
+}
+
+type compiledRule struct {
+	RelabelRule
+	re *regexp.Regexp
+}
+
+// Relabeler applies a compiled list of relabel rules in order.
+type Relabeler struct {
+	rules []compiledRule
+}
+
+// NewRelabeler validates and compiles rules.
+func NewRelabeler(rules []RelabelRule) (*Relabeler, error) {
+	r := &Relabeler{}
+	for i, rule := range rules {
+		switch rule.Action {
+		case "keep", "drop":
+		case "replace":
+			if rule.TargetLabel == "" {
+				return nil, fmt.Errorf("relabel rule %d: replace requires target_label", i)
+			}
+		default:
+			return nil, fmt.Errorf("relabel rule %d: unknown action %q (expected keep, drop, or replace)", i, rule.Action)
+		}
+		if len(rule.SourceLabels) == 0 {
+			rule.SourceLabels = []string{MetricNameLabel}
+		}
+		re, err := regexp.Compile("^(?:" + rule.Regex + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("relabel rule %d: invalid regex: %v", i, err)
+		}
+		r.rules = append(r.rules, compiledRule{RelabelRule: rule, re: re})
+	}
+	return r, nil
+}
+
+// Apply runs the rules against a metric. It returns the possibly renamed
+// metric and labels, and false if the metric should be dropped. The input
+// labels map is not modified.
+func (r *Relabeler) Apply(name string, labels map[string]string) (string, map[string]string, bool) {
+	if r == nil || len(r.rules) == 0 {
+		return name, labels, true
+	}
+
+	out := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		out[k] = v
+	}
+	out[MetricNameLabel] = name
+
+	for _, rule := range r.rules {
+		values := make([]string, len(rule.SourceLabels))
+		for i, l := range rule.SourceLabels {
+			values[i] = out[l]
+		}
+		source := strings.Join(values, ";")
+
+		switch rule.Action {
+		case "keep":
+			if !rule.re.MatchString(source) {
+				return "", nil, false
+			}
+		case "drop":
+			if rule.re.MatchString(source) {
+				return "", nil, false
+			}
+		case "replace":
+			match := rule.re.FindStringSubmatchIndex(source)
+			if match == nil {
+				continue
+			}
+			value := string(rule.re.ExpandString(nil, rule.Replacement, source, match))
+			if value == "" {
+				delete(out, rule.TargetLabel)
+			} else {
+				out[rule.TargetLabel] = value
+			}
+		}
+	}
+
+	name = out[MetricNameLabel]
+	delete(out, MetricNameLabel)
+	if name == "" {
+		return "", nil, false
+	}
+	return name, out, true
+}
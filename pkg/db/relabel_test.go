@@ -0,0 +1,93 @@
+package db
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRelabeler_Keep(t *testing.T) {
+	r, err := NewRelabeler([]RelabelRule{{Action: "keep", Regex: "cpu_.*|memory_.*"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := r.Apply("cpu_usage_pct", nil); !ok {
+		t.Error("Expected cpu_usage_pct to be kept")
+	}
+	if _, _, ok := r.Apply("process_cpu_pct", nil); ok {
+		t.Error("Expected process_cpu_pct to be dropped by keep rule")
+	}
+}
+
+func TestRelabeler_Drop(t *testing.T) {
+	r, err := NewRelabeler([]RelabelRule{{Action: "drop", SourceLabels: []string{"process_name"}, Regex: "(?i).*helper.*"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := r.Apply("process_cpu_pct", map[string]string{"process_name": "Chrome Helper"}); ok {
+		t.Error("Expected helper process to be dropped")
+	}
+	if _, _, ok := r.Apply("process_cpu_pct", map[string]string{"process_name": "Chrome"}); !ok {
+		t.Error("Expected Chrome to be kept")
+	}
+}
+
+func TestRelabeler_Replace(t *testing.T) {
+	r, err := NewRelabeler([]RelabelRule{
+		// Rename the metric
+		{Action: "replace", Regex: "process_(.*)", TargetLabel: MetricNameLabel, Replacement: "proc_$1"},
+		// Rename a label: copy it, then clear the original
+		{Action: "replace", SourceLabels: []string{"host"}, Regex: "(.+)", TargetLabel: "instance", Replacement: "$1"},
+		{Action: "replace", SourceLabels: []string{"host"}, Regex: ".*", TargetLabel: "host"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	labels := map[string]string{"host": "localhost", "process_name": "zsh"}
+	name, out, ok := r.Apply("process_memory_mb", labels)
+	if !ok {
+		t.Fatal("Expected metric to be kept")
+	}
+	if name != "proc_memory_mb" {
+		t.Errorf("Expected proc_memory_mb, got %s", name)
+	}
+	if out["instance"] != "localhost" {
+		t.Errorf("Expected instance=localhost, got %v", out)
+	}
+	if _, exists := out["host"]; exists {
+		t.Errorf("Expected host label to be removed, got %v", out)
+	}
+	if labels["host"] != "localhost" {
+		t.Error("Apply must not modify the caller's labels")
+	}
+}
+
+func TestNewRelabeler_Invalid(t *testing.T) {
+	for _, rule := range []RelabelRule{
+		{Action: "hashmod", Regex: ".*"},
+		{Action: "drop", Regex: "("},
+		{Action: "replace", Regex: ".*"},
+	} {
+		if _, err := NewRelabeler([]RelabelRule{rule}); err == nil {
+			t.Errorf("Expected error for %+v", rule)
+		}
+	}
+}
+
+func TestVictoriaDB_InsertMetricRelabel(t *testing.T) {
+	var writes int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writes++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	v := NewVictoriaDB(server.URL, server.URL)
+	v.Relabeler, _ = NewRelabeler([]RelabelRule{{Action: "drop", Regex: "srum_.*"}})
+	v.InsertMetric("srum_app_duration_ms", 1, nil)
+	v.InsertMetric("cpu_usage_pct", 1, nil)
+	if writes != 1 {
+		t.Errorf("Expected only the kept metric to be written, got %d writes", writes)
+	}
+}
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// staleNaNBits is the bit pattern of Prometheus' staleness marker, a NaN
+// that VictoriaMetrics recognizes and treats as "the series ended here", so
+// rollups such as last_over_time stop returning the previous value. An
+// ordinary NaN is just a missing sample and is skipped over instead.
+const staleNaNBits = 0x7ff0000000000002
+
+// StaleNaN returns the staleness marker value. Write it with InsertMetrics
+// to end a series.
+func StaleNaN() float64 {
+	return math.Float64frombits(staleNaNBits)
+}
+
+// IsStaleNaN reports whether v is the staleness marker, as opposed to any
+// other NaN.
+func IsStaleNaN(v float64) bool {
+	return math.Float64bits(v) == staleNaNBits
+}
+
+// remoteWriteContentType marks a payload for /api/v1/write, which post
+// sends with the snappy and protocol-version headers remote write expects.
+const remoteWriteContentType = "application/x-protobuf"
+
+// staleSample is one staleness marker waiting to be written.
+type staleSample struct {
+	name   string
+	labels map[string]string
+	ts     time.Time
+}
+
+// encodeRemoteWrite renders samples as a snappy-compressed Prometheus
+// remote write request. The text import parses "NaN" into an ordinary NaN,
+// so staleness markers have to go through this binary format, which keeps
+// the value's bits.
+func encodeRemoteWrite(samples []staleSample) []byte {
+	var req []byte
+	for _, s := range samples {
+		var series []byte
+		for _, l := range sortedLabels(s.name, s.labels) {
+			var label []byte
+			label = protowire.AppendTag(label, 1, protowire.BytesType)
+			label = protowire.AppendString(label, l[0])
+			label = protowire.AppendTag(label, 2, protowire.BytesType)
+			label = protowire.AppendString(label, l[1])
+			series = protowire.AppendTag(series, 1, protowire.BytesType)
+			series = protowire.AppendBytes(series, label)
+		}
+
+		var sample []byte
+		sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+		sample = protowire.AppendFixed64(sample, staleNaNBits)
+		sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+		sample = protowire.AppendVarint(sample, uint64(s.ts.UnixMilli()))
+		series = protowire.AppendTag(series, 2, protowire.BytesType)
+		series = protowire.AppendBytes(series, sample)
+
+		req = protowire.AppendTag(req, 1, protowire.BytesType)
+		req = protowire.AppendBytes(req, series)
+	}
+	return snappyEncode(req)
+}
+
+// sortedLabels returns the name as __name__ plus labels, sorted by label
+// name as remote write requires.
+func sortedLabels(name string, labels map[string]string) [][2]string {
+	out := make([][2]string, 0, len(labels)+1)
+	out = append(out, [2]string{"__name__", name})
+	for k, v := range labels {
+		out = append(out, [2]string{k, v})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i][0] < out[j][0] })
+	return out
+}
+
+// snappyEncode writes src as a snappy block made only of literals. It
+// compresses nothing, but every snappy decoder reads it, and staleness
+// marker batches are small.
+func snappyEncode(src []byte) []byte {
+	dst := binary.AppendUvarint(nil, uint64(len(src)))
+	for len(src) > 0 {
+		n := min(len(src), 1<<16)
+		switch m := n - 1; {
+		case m < 60:
+			dst = append(dst, byte(m)<<2)
+		case m < 1<<8:
+			dst = append(dst, 60<<2, byte(m))
+		default:
+			dst = append(dst, 61<<2, byte(m), byte(m>>8))
+		}
+		dst = append(dst, src[:n]...)
+		src = src[n:]
+	}
+	return dst
+}
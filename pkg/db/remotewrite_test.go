@@ -0,0 +1,170 @@
+package db
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// decodeSnappyLiterals reverses snappyEncode. It only understands literal
+// elements, which is all snappyEncode writes.
+func decodeSnappyLiterals(t *testing.T, src []byte) []byte {
+	t.Helper()
+	n, k := binary.Uvarint(src)
+	src = src[k:]
+	var out []byte
+	for len(src) > 0 {
+		tag := src[0]
+		if tag&3 != 0 {
+			t.Fatalf("Unexpected non-literal snappy element %#x", tag)
+		}
+		length := int(tag>>2) + 1
+		src = src[1:]
+		switch tag >> 2 {
+		case 60:
+			length, src = int(src[0])+1, src[1:]
+		case 61:
+			length, src = int(src[0])|int(src[1])<<8+1, src[2:]
+		}
+		out, src = append(out, src[:length]...), src[length:]
+	}
+	if uint64(len(out)) != n {
+		t.Fatalf("Snappy header says %d bytes, decoded %d", n, len(out))
+	}
+	return out
+}
+
+// field returns the fields of a protobuf message, keyed by number, with
+// fixed64 and varint values in raw and nested bytes in msg.
+type field struct {
+	num protowire.Number
+	raw uint64
+	msg []byte
+}
+
+func parseMessage(t *testing.T, b []byte) []field {
+	t.Helper()
+	var fields []field
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			t.Fatalf("Bad protobuf tag: %v", protowire.ParseError(n))
+		}
+		b = b[n:]
+		f := field{num: num}
+		switch typ {
+		case protowire.BytesType:
+			f.msg, n = protowire.ConsumeBytes(b)
+		case protowire.Fixed64Type:
+			f.raw, n = protowire.ConsumeFixed64(b)
+		case protowire.VarintType:
+			f.raw, n = protowire.ConsumeVarint(b)
+		default:
+			t.Fatalf("Unexpected wire type %d", typ)
+		}
+		if n < 0 {
+			t.Fatalf("Bad protobuf field: %v", protowire.ParseError(n))
+		}
+		b = b[n:]
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func TestSnappyEncode_Format(t *testing.T) {
+	// Varint length, then a literal tag of (len-1)<<2, per the snappy spec.
+	if got := snappyEncode([]byte("hello")); !bytes.Equal(got, []byte("\x05\x10hello")) {
+		t.Errorf("Unexpected encoding of \"hello\": %q", got)
+	}
+	for _, n := range []int{0, 59, 60, 61, 255, 256, 257, 1 << 16, 1<<16 + 1, 200000} {
+		src := bytes.Repeat([]byte{'x'}, n)
+		if got := decodeSnappyLiterals(t, snappyEncode(src)); !bytes.Equal(got, src) {
+			t.Errorf("Round trip of %d bytes failed", n)
+		}
+	}
+}
+
+func TestVictoriaDB_InsertMetricsStaleMarker(t *testing.T) {
+	var textBody string
+	var writeBody []byte
+	var writeHeader http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/api/v1/import/prometheus":
+			textBody = string(body)
+		case "/api/v1/write":
+			writeBody, writeHeader = body, r.Header
+		default:
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	v := NewVictoriaDB(server.URL, server.URL)
+	v.Latest = NewLatestSamples(nil)
+	ts := time.UnixMilli(1700000000123)
+	err := v.InsertMetrics([]Metric{
+		{Name: "process_memory_mb", Value: 100, Labels: map[string]string{"pid": "1", "process_name": "a"}, Timestamp: ts},
+		{Name: "process_memory_mb", Value: StaleNaN(), Labels: map[string]string{"pid": "2", "process_name": "b"}, Timestamp: ts},
+	})
+	if err != nil {
+		t.Fatalf("Failed to insert metrics: %v", err)
+	}
+
+	// The text format can only say "NaN", which loses the marker's bits.
+	if strings.Contains(textBody, "NaN") || !strings.Contains(textBody, `pid="1"`) {
+		t.Errorf("Expected only the ordinary sample in the text import, got %q", textBody)
+	}
+	if writeHeader.Get("Content-Encoding") != "snappy" || writeHeader.Get("Content-Type") != "application/x-protobuf" {
+		t.Errorf("Unexpected remote write headers: %v", writeHeader)
+	}
+
+	req := parseMessage(t, decodeSnappyLiterals(t, writeBody))
+	if len(req) != 1 || req[0].num != 1 {
+		t.Fatalf("Expected one time series, got %+v", req)
+	}
+	labels := map[string]string{}
+	var value, stamp uint64
+	for _, f := range parseMessage(t, req[0].msg) {
+		switch f.num {
+		case 1:
+			l := parseMessage(t, f.msg)
+			labels[string(l[0].msg)] = string(l[1].msg)
+		case 2:
+			s := parseMessage(t, f.msg)
+			value, stamp = s[0].raw, s[1].raw
+		}
+	}
+	if labels["__name__"] != "process_memory_mb" || labels["pid"] != "2" || labels["process_name"] != "b" {
+		t.Errorf("Unexpected labels: %v", labels)
+	}
+	if value != staleNaNBits {
+		t.Errorf("Expected the staleness marker bits %#x, got %#x (%v)", uint64(staleNaNBits), value, math.Float64frombits(value))
+	}
+	if stamp != 1700000000123 {
+		t.Errorf("Expected timestamp 1700000000123, got %d", stamp)
+	}
+
+	// The marked series drops out of the scrape endpoint too.
+	var out bytes.Buffer
+	v.Latest.WriteOpenMetrics(&out)
+	if strings.Contains(out.String(), `pid="2"`) || !strings.Contains(out.String(), `pid="1"`) {
+		t.Errorf("Expected only pid 1 to be exposed, got:\n%s", out.String())
+	}
+}
+
+func TestIsStaleNaN(t *testing.T) {
+	if !IsStaleNaN(StaleNaN()) || IsStaleNaN(math.NaN()) || IsStaleNaN(0) {
+		t.Error("IsStaleNaN must match only the staleness marker")
+	}
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// MetricResult is one sample of an instant MetricsQL query.
+type MetricResult struct {
+	Name      string            `json:"name"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+	Value     float64           `json:"value"`
+
+	// raw is the value exactly as VictoriaMetrics rendered it, kept so the
+	// LLM-facing text matches what the database returned.
+	raw string
+}
+
+// MarshalJSON encodes non-finite values, which encoding/json rejects, as null.
+func (m MetricResult) MarshalJSON() ([]byte, error) {
+	type alias MetricResult
+	out := struct {
+		alias
+		Value *float64 `json:"value"`
+	}{alias: alias(m)}
+	if !math.IsNaN(m.Value) && !math.IsInf(m.Value, 0) {
+		out.Value = &m.Value
+	}
+	return json.Marshal(out)
+}
+
+// LogResult is one entry returned by a LogsQL query. Fields holds every
+// field VictoriaLogs returned, including _time and _msg.
+type LogResult struct {
+	Time   string            `json:"time"`
+	Fields map[string]string `json:"fields"`
+}
+
+// FormatMetricResults renders metric samples in the compact, LLM-readable
+// form `name{label="value", ...}: value`, one per line.
+func FormatMetricResults(results []MetricResult) string {
+	var out bytes.Buffer
+	for _, res := range results {
+		// Build a label description; omit __name__ since we print query context elsewhere
+		var labelParts []string
+		for k, v := range res.Labels {
+			labelParts = append(labelParts, fmt.Sprintf("%s=%q", k, v))
+		}
+		name := res.Name
+		if name == "" {
+			name = "result"
+		}
+		if len(labelParts) > 0 {
+			fmt.Fprintf(&out, "%s{%s}: %s\n", name, strings.Join(labelParts, ", "), res.raw)
+		} else {
+			fmt.Fprintf(&out, "%s: %s\n", name, res.raw)
+		}
+	}
+	return out.String()
+}
+
+// FormatLogResults renders log entries as NDJSON, one entry per line.
+func FormatLogResults(results []LogResult) string {
+	var out bytes.Buffer
+	for _, res := range results {
+		entryStr, _ := json.Marshal(res.Fields)
+		out.Write(entryStr)
+		out.WriteByte('\n')
+	}
+	return out.String()
+}
@@ -2,15 +2,26 @@ package db
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
+
+	"zenith/pkg/telemetry"
 )
 
+// LogEntry is the one log record shape every collector writes to
+// VictoriaLogs. Each platform converts its raw records (log show, journald,
+// Windows Event Log) to it, so the stored field names, which queries and
+// prompts rely on, are the same everywhere.
 type LogEntry struct {
 	Timestamp    string `json:"timestamp"`
 	ProcessID    int    `json:"processID"`
@@ -21,75 +32,232 @@ type LogEntry struct {
 	EventMessage string `json:"eventMessage"`
 }
 
+// ErrBadQuery marks a query the backend rejected or gave up on (syntax
+// error, unknown function, server-side timeout), as opposed to the backend
+// being unreachable. Retrying with a different or simpler query may help.
+var ErrBadQuery = errors.New("bad query")
+
+// WriteMode decides when a write fanned out to several backends succeeds.
+type WriteMode string
+
+const (
+	// WriteAny succeeds if at least one backend accepted the write.
+	WriteAny WriteMode = "any"
+	// WriteAll succeeds only if every backend accepted the write.
+	WriteAll WriteMode = "all"
+)
+
 type VictoriaDB struct {
-	MetricsURL string
-	LogsURL    string
-	Client     *http.Client
+	// MetricsURLs and LogsURLs list every backend. Writes are mirrored to all
+	// of them; queries read from the first one that answers.
+	MetricsURLs []string
+	LogsURLs    []string
+	Client      *http.Client
+
+	// WriteMode controls whether a mirrored write needs any or all backends
+	// to succeed. The zero value behaves like WriteAny.
+	WriteMode WriteMode
+
+	// QueryTimeout is passed to VictoriaMetrics as the `timeout` parameter so
+	// a pathological query is cancelled server-side. Zero leaves the server
+	// default. Keep it below Client.Timeout so the server gives up first.
+	QueryTimeout time.Duration
+
+	// Relabeler rewrites or drops metrics before they are written.
+	// Nil writes every metric unchanged.
+	Relabeler *Relabeler
+
+	// LogLimit caps the number of log entries QueryLogs returns when the
+	// query has no limit pipe of its own. Zero means unlimited.
+	LogLimit int
+
+	// WriteRetries is how many times a write is retried after a connection
+	// error or a 5xx response, waiting WriteRetryDelay, then twice that, and
+	// so on. 4xx responses (malformed data) are never retried.
+	WriteRetries    int
+	WriteRetryDelay time.Duration
+
+	// Credentials sent with every request, for backends behind vmauth or a
+	// reverse proxy. BearerToken takes precedence over basic auth.
+	BasicAuthUser string
+	BasicAuthPass string
+	BearerToken   string
+
+	// LogTimeField and LogMsgField name the log entry fields VictoriaLogs
+	// should use as _time and _msg, and LogStreamFields the fields that
+	// identify a log stream. Empty leaves the VictoriaLogs default. Since
+	// VictoriaLogs stores the message field as _msg, queries and results
+	// are translated so callers can keep using LogMsgField's name. That
+	// hides entries written before LogMsgField was set, which still carry
+	// the field under its own name, so NewVictoriaDB leaves it empty.
+	LogTimeField    string
+	LogMsgField     string
+	LogStreamFields []string
+
+	// Latest, if set, keeps the most recent sample of every series written,
+	// for exposing the collected metrics to a scraper.
+	Latest *LatestSamples
 }
 
+// NewVictoriaDB creates a client for the given VictoriaMetrics and
+// VictoriaLogs instances. Each argument may hold several comma-separated URLs
+// to mirror writes across backends.
 func NewVictoriaDB(metricsURL, logsURL string) *VictoriaDB {
 	return &VictoriaDB{
-		MetricsURL: metricsURL,
-		LogsURL:    logsURL,
-		Client:     &http.Client{Timeout: 10 * time.Second},
+		MetricsURLs: splitURLs(metricsURL),
+		LogsURLs:    splitURLs(logsURL),
+		Client:      &http.Client{Timeout: 10 * time.Second},
+		WriteMode:   WriteAny,
+
+		// Rides out a backend that is still starting: 0.5+1+2s.
+		WriteRetries:    3,
+		WriteRetryDelay: 500 * time.Millisecond,
+
+		LogTimeField: "timestamp",
+	}
+}
+
+// NewVictoriaDBWithAuth is NewVictoriaDB for backends that require basic
+// auth (user and pass) or a bearer token. Leave the unused ones empty.
+func NewVictoriaDBWithAuth(metricsURL, logsURL, user, pass, token string) *VictoriaDB {
+	v := NewVictoriaDB(metricsURL, logsURL)
+	v.BasicAuthUser = user
+	v.BasicAuthPass = pass
+	v.BearerToken = token
+	return v
+}
+
+func splitURLs(s string) []string {
+	var urls []string
+	for _, u := range strings.Split(s, ",") {
+		if u = strings.TrimRight(strings.TrimSpace(u), "/"); u != "" {
+			urls = append(urls, u)
+		}
 	}
+	return urls
 }
 
 func (v *VictoriaDB) InsertMetric(name string, value float64, labels map[string]string) error {
+	return v.InsertMetricContext(context.Background(), name, value, labels)
+}
+
+// InsertMetricContext is InsertMetric with a context that can cancel the write.
+func (v *VictoriaDB) InsertMetricContext(ctx context.Context, name string, value float64, labels map[string]string) error {
+	return v.InsertMetricAtContext(ctx, name, value, labels, time.Now())
+}
+
+// InsertMetricAt writes a sample stamped with ts instead of the current time,
+// for backfilling data that carries its own event time.
+func (v *VictoriaDB) InsertMetricAt(name string, value float64, labels map[string]string, ts time.Time) error {
+	return v.InsertMetricAtContext(context.Background(), name, value, labels, ts)
+}
+
+// InsertMetricAtContext is InsertMetricAt with a context that can cancel the write.
+func (v *VictoriaDB) InsertMetricAtContext(ctx context.Context, name string, value float64, labels map[string]string, ts time.Time) error {
+	return v.InsertMetricsContext(ctx, []Metric{{Name: name, Value: value, Labels: labels, Timestamp: ts}})
+}
+
+// Metric is one sample for InsertMetrics. A zero Timestamp means now.
+type Metric struct {
+	Name      string
+	Value     float64
+	Labels    map[string]string
+	Timestamp time.Time
+}
+
+// InsertMetrics writes a batch of samples to VictoriaMetrics in a single
+// request.
+func (v *VictoriaDB) InsertMetrics(batch []Metric) error {
+	return v.InsertMetricsContext(context.Background(), batch)
+}
+
+// InsertMetricsContext is InsertMetrics with a context that can cancel the write.
+func (v *VictoriaDB) InsertMetricsContext(ctx context.Context, batch []Metric) error {
 	// Use Prometheus exposition format via /api/v1/import/prometheus.
 	// This stores the metric with exactly the name given, no suffix or doubling.
-	// Format: metric_name{label1="val1",label2="val2"} value timestamp_ms
+	now := time.Now()
 
-	var labelParts []string
-	for k, val := range labels {
-		// Escape backslashes and double-quotes inside label values
-		escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(val)
-		labelParts = append(labelParts, fmt.Sprintf(`%s="%s"`, k, escaped))
-	}
+	var buf bytes.Buffer
+	var stale []staleSample
+	for _, m := range batch {
+		name, labels, keep := v.Relabeler.Apply(m.Name, m.Labels)
+		if !keep {
+			continue
+		}
+		name, labels = sanitizeName(name, true), sanitizeLabels(labels)
 
-	var line string
-	if len(labelParts) > 0 {
-		line = fmt.Sprintf("%s{%s} %f %d\n", name, strings.Join(labelParts, ","), value, time.Now().UnixMilli())
-	} else {
-		line = fmt.Sprintf("%s %f %d\n", name, value, time.Now().UnixMilli())
-	}
+		ts := m.Timestamp
+		if ts.IsZero() {
+			ts = now
+		}
+		v.Latest.record(name, labels, m.Value, ts)
 
-	resp, err := v.Client.Post(v.MetricsURL+"/api/v1/import/prometheus", "text/plain", bytes.NewBufferString(line))
-	if err != nil {
-		return err
+		if IsStaleNaN(m.Value) {
+			stale = append(stale, staleSample{name: name, labels: labels, ts: ts})
+			continue
+		}
+		writeSample(&buf, name, labels, m.Value, ts)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("victoria metrics write failed (%d): %s", resp.StatusCode, string(body))
+	if buf.Len() > 0 {
+		if err := v.write(ctx, "metrics", v.MetricsURLs, "/api/v1/import/prometheus", "text/plain", buf.Bytes(), "victoria metrics write failed"); err != nil {
+			return err
+		}
+	}
+	if len(stale) > 0 {
+		return v.write(ctx, "metrics", v.MetricsURLs, "/api/v1/write", remoteWriteContentType, encodeRemoteWrite(stale), "victoria metrics staleness marker write failed")
 	}
-
 	return nil
 }
 
+// QueryMetrics runs an instant MetricsQL query and returns the samples
+// formatted for the LLM.
 func (v *VictoriaDB) QueryMetrics(query string) (string, error) {
-	u, err := url.Parse(v.MetricsURL + "/api/v1/query")
+	return v.QueryMetricsContext(context.Background(), query)
+}
+
+// QueryMetricsContext is QueryMetrics with a context that can cancel the query.
+func (v *VictoriaDB) QueryMetricsContext(ctx context.Context, query string) (string, error) {
+	results, err := v.QueryMetricsResultsContext(ctx, query)
 	if err != nil {
 		return "", err
 	}
-	q := u.Query()
+	return FormatMetricResults(results), nil
+}
+
+// QueryMetricsResults runs an instant MetricsQL query and returns the typed samples.
+func (v *VictoriaDB) QueryMetricsResults(query string) ([]MetricResult, error) {
+	return v.QueryMetricsResultsContext(context.Background(), query)
+}
+
+// QueryMetricsResultsContext is QueryMetricsResults with a context that can
+// cancel the query.
+func (v *VictoriaDB) QueryMetricsResultsContext(ctx context.Context, query string) ([]MetricResult, error) {
+	q := url.Values{}
 	q.Set("query", query)
 	// step=4200 extends the lookback window to 70 minutes so metrics written
 	// every 5 minutes (CPU/memory) and SRUM data written hourly are both
 	// always found between collection cycles.
 	q.Set("step", "4200")
-	u.RawQuery = q.Encode()
+	if v.QueryTimeout > 0 {
+		q.Set("timeout", v.QueryTimeout.String())
+	}
 
-	resp, err := v.Client.Get(u.String())
+	resp, err := v.getFirst(ctx, v.MetricsURLs, "/api/v1/query?"+q.Encode())
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
+	body, err := decodedBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("victoria metrics query failed (%d): %s", resp.StatusCode, string(body))
+		msg, _ := io.ReadAll(body)
+		return nil, queryError("victoria metrics query failed", resp.StatusCode, msg)
 	}
 
 	var result struct {
@@ -103,42 +271,44 @@ func (v *VictoriaDB) QueryMetrics(query string) (string, error) {
 		} `json:"data"`
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
+	if err := json.NewDecoder(body).Decode(&result); err != nil {
+		return nil, err
 	}
 
-	// Format results in a clean, LLM-readable way
-	var out bytes.Buffer
+	results := make([]MetricResult, 0, len(result.Data.Result))
 	for _, res := range result.Data.Result {
-		// Extract the numeric value (index 1 of the [timestamp, value] pair)
-		val := ""
-		if len(res.Value) >= 2 {
-			val = fmt.Sprintf("%v", res.Value[1])
-		}
-
-		// Build a label description; omit __name__ since we print query context elsewhere
-		var labelParts []string
-		for k, v := range res.Metric {
+		mr := MetricResult{Name: res.Metric["__name__"]}
+		for k, val := range res.Metric {
 			if k != "__name__" {
-				labelParts = append(labelParts, fmt.Sprintf("%s=%q", k, v))
+				if mr.Labels == nil {
+					mr.Labels = make(map[string]string)
+				}
+				mr.Labels[k] = val
 			}
 		}
-		name := res.Metric["__name__"]
-		if name == "" {
-			name = "result"
-		}
-		if len(labelParts) > 0 {
-			fmt.Fprintf(&out, "%s{%s}: %s\n", name, strings.Join(labelParts, ", "), val)
-		} else {
-			fmt.Fprintf(&out, "%s: %s\n", name, val)
+
+		// Value is a [timestamp, "value"] pair
+		if len(res.Value) >= 2 {
+			if ts, ok := res.Value[0].(float64); ok {
+				sec, frac := math.Modf(ts)
+				mr.Timestamp = time.Unix(int64(sec), int64(frac*1e9)).UTC()
+			}
+			mr.raw = fmt.Sprintf("%v", res.Value[1])
+			mr.Value, _ = strconv.ParseFloat(mr.raw, 64)
 		}
+		results = append(results, mr)
 	}
 
-	return out.String(), nil
+	return results, nil
 }
 
 // InsertLog inserts a log entry into VictoriaLogs.
 func (v *VictoriaDB) InsertLog(entry interface{}) error {
+	return v.InsertLogContext(context.Background(), entry)
+}
+
+// InsertLogContext is InsertLog with a context that can cancel the write.
+func (v *VictoriaDB) InsertLogContext(ctx context.Context, entry interface{}) error {
 	data, err := json.Marshal(entry)
 	if err != nil {
 		return err
@@ -146,22 +316,16 @@ func (v *VictoriaDB) InsertLog(entry interface{}) error {
 	data = append(data, '\n')
 
 	// VictoriaLogs endpoint for JSON line insertion
-	resp, err := v.Client.Post(v.LogsURL+"/insert/jsonline", "application/json", bytes.NewBuffer(data))
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("victoria logs write failed (%d): %s", resp.StatusCode, string(body))
-	}
-
-	return nil
+	return v.write(ctx, "logs", v.LogsURLs, v.logsInsertPath(), "application/json", data, "victoria logs write failed")
 }
 
 // InsertLogs inserts multiple log entries into VictoriaLogs in a single batch.
 func (v *VictoriaDB) InsertLogs(entries []LogEntry) error {
+	return v.InsertLogsContext(context.Background(), entries)
+}
+
+// InsertLogsContext is InsertLogs with a context that can cancel the write.
+func (v *VictoriaDB) InsertLogsContext(ctx context.Context, entries []LogEntry) error {
 	var buf bytes.Buffer
 	for _, entry := range entries {
 		data, err := json.Marshal(entry)
@@ -176,65 +340,293 @@ func (v *VictoriaDB) InsertLogs(entries []LogEntry) error {
 		return nil
 	}
 
-	resp, err := v.Client.Post(v.LogsURL+"/insert/jsonline", "application/json", &buf)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+	return v.write(ctx, "logs", v.LogsURLs, v.logsInsertPath(), "application/json", buf.Bytes(), "victoria logs batch write failed")
+}
 
-	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("victoria logs batch write failed (%d): %s", resp.StatusCode, string(body))
+// logsInsertPath is the jsonline insert endpoint with the field mapping
+// parameters.
+func (v *VictoriaDB) logsInsertPath() string {
+	params := url.Values{}
+	if v.LogTimeField != "" {
+		params.Set("_time_field", v.LogTimeField)
 	}
-
-	return nil
+	if v.LogMsgField != "" {
+		params.Set("_msg_field", v.LogMsgField)
+	}
+	if len(v.LogStreamFields) > 0 {
+		params.Set("_stream_fields", strings.Join(v.LogStreamFields, ","))
+	}
+	if len(params) == 0 {
+		return "/insert/jsonline"
+	}
+	return "/insert/jsonline?" + params.Encode()
 }
 
+// QueryLogs runs a LogsQL query and returns the entries formatted for the LLM.
 func (v *VictoriaDB) QueryLogs(query string) (string, error) {
-	u, err := url.Parse(v.LogsURL + "/select/logsql/query")
+	return v.QueryLogsContext(context.Background(), query)
+}
+
+// QueryLogsContext is QueryLogs with a context that can cancel the query.
+func (v *VictoriaDB) QueryLogsContext(ctx context.Context, query string) (string, error) {
+	results, err := v.QueryLogsResultsContext(ctx, query)
 	if err != nil {
 		return "", err
 	}
-	q := u.Query()
+	return FormatLogResults(results), nil
+}
+
+// QueryLogsResults runs a LogsQL query over the last 24 hours and returns the
+// typed entries.
+func (v *VictoriaDB) QueryLogsResults(query string) ([]LogResult, error) {
+	return v.QueryLogsResultsContext(context.Background(), query)
+}
+
+// QueryLogsResultsContext is QueryLogsResults with a context that can cancel
+// the query.
+func (v *VictoriaDB) QueryLogsResultsContext(ctx context.Context, query string) ([]LogResult, error) {
+	var results []LogResult
+	err := v.StreamLogsContext(ctx, query, func(entry LogResult) error {
+		results = append(results, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// StreamLogsContext runs a LogsQL query over the last 24 hours like
+// QueryLogsResultsContext, but hands each entry to fn as it is decoded
+// instead of collecting them, so a large result never sits in memory. An
+// error from fn stops the query and is returned.
+func (v *VictoriaDB) StreamLogsContext(ctx context.Context, query string, fn func(LogResult) error) error {
+	q := url.Values{}
 
 	// VictoriaLogs defaults to the last 5 minutes if no time filter is provided.
 	// Since we actively strip LLM time filters, we must append a solid 24h default.
-	if query != "" && query != "*" {
-		query = fmt.Sprintf("(%s) AND _time:24h", query)
-	} else {
-		query = "_time:24h"
-	}
+	query = WithTimeFilter(query, "24h")
+	query = WithLimit(query, v.LogLimit)
+	query = RenameField(query, v.LogMsgField, "_msg")
 
 	q.Set("query", query)
-	u.RawQuery = q.Encode()
 
-	resp, err := v.Client.Get(u.String())
+	resp, err := v.getFirst(ctx, v.LogsURLs, "/select/logsql/query?"+q.Encode())
 	if err != nil {
-		return "", err
+		return err
 	}
 	defer resp.Body.Close()
 
+	body, err := decodedBody(resp)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("victoria logs query failed (%d): %s", resp.StatusCode, string(body))
+		msg, _ := io.ReadAll(body)
+		return queryError("victoria logs query failed", resp.StatusCode, msg)
 	}
 
-	// VictoriaLogs returns NDJSON. We'll read it line by line and format for LLM.
-	var out bytes.Buffer
-	decoder := json.NewDecoder(resp.Body)
+	// VictoriaLogs returns NDJSON. We'll read it line by line.
+	decoder := json.NewDecoder(body)
 	for {
 		var logEntry map[string]interface{}
 		if err := decoder.Decode(&logEntry); err == io.EOF {
 			break
 		} else if err != nil {
-			return "", err
+			return err
+		}
+
+		fields := make(map[string]string, len(logEntry))
+		for k, val := range logEntry {
+			if str, ok := val.(string); ok {
+				fields[k] = str
+			} else {
+				fields[k] = fmt.Sprintf("%v", val)
+			}
+		}
+		if msg, ok := fields["_msg"]; ok && v.LogMsgField != "" {
+			fields[v.LogMsgField] = msg
+		}
+		if err := fn(LogResult{Time: fields["_time"], Fields: fields}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// get issues a GET request asking the backend for a gzip-compressed response.
+// Large LogsQL result sets compress very well, so this noticeably cuts
+// transfer time. Because the header is set explicitly, net/http no longer
+// decompresses on our behalf; callers must read through decodedBody.
+func (v *VictoriaDB) get(ctx context.Context, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept-Encoding", "gzip")
+	v.authorize(req)
+	return v.Client.Do(req)
+}
+
+// authorize adds the configured credentials, if any, to req.
+func (v *VictoriaDB) authorize(req *http.Request) {
+	switch {
+	case v.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+v.BearerToken)
+	case v.BasicAuthUser != "":
+		req.SetBasicAuth(v.BasicAuthUser, v.BasicAuthPass)
+	}
+}
+
+// queryError builds the error for a failed query response, wrapping
+// ErrBadQuery when the backend rejected the query itself (400 or 422) or
+// timed out evaluating it. Other 4xx responses, such as 401, 404 or 429,
+// are about the request's credentials, URL or rate, which a different
+// query won't fix.
+func queryError(prefix string, status int, body []byte) error {
+	msg := string(body)
+	lower := strings.ToLower(msg)
+	rejected := status == http.StatusBadRequest || status == http.StatusUnprocessableEntity
+	timedOut := status >= 500 && (strings.Contains(lower, "timeout") || strings.Contains(lower, "deadline exceeded"))
+	if rejected || timedOut {
+		return fmt.Errorf("%w: %s (%d): %s", ErrBadQuery, prefix, status, msg)
+	}
+	return fmt.Errorf("%s (%d): %s", prefix, status, msg)
+}
+
+// getFirst sends the GET to each backend in order and returns the first
+// response that isn't a connection failure or a 5xx, so queries keep working
+// while a mirror is down.
+func (v *VictoriaDB) getFirst(ctx context.Context, backends []string, pathAndQuery string) (*http.Response, error) {
+	if len(backends) == 0 {
+		return nil, fmt.Errorf("no backend URL configured")
+	}
+
+	var lastErr error
+	for i, base := range backends {
+		resp, err := v.get(ctx, base+pathAndQuery)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			lastErr = err
+			continue
 		}
+		if resp.StatusCode >= 500 && i < len(backends)-1 {
+			resp.Body.Close()
+			lastErr = fmt.Errorf("%s returned %d", base, resp.StatusCode)
+			continue
+		}
+		return resp, nil
+	}
+	return nil, lastErr
+}
+
+// backendWrites counts write attempts per backend so mirror health shows up
+// on the server's /metrics endpoint.
+var backendWrites = telemetry.Default.Counter("zenith_backend_writes_total", "Writes to each VictoriaMetrics/VictoriaLogs backend by result.")
+
+// write POSTs payload to every backend and applies WriteMode to decide
+// whether the write as a whole succeeded.
+func (v *VictoriaDB) write(ctx context.Context, kind string, backends []string, path, contentType string, payload []byte, failMsg string) error {
+	if len(backends) == 0 {
+		return fmt.Errorf("no %s backend URL configured", kind)
+	}
+
+	var errs []error
+	for _, base := range backends {
+		err := v.postWithRetry(ctx, base+path, contentType, payload, failMsg)
+		result := "success"
+		if err != nil {
+			result = "error"
+			if len(backends) > 1 {
+				err = fmt.Errorf("%s: %v", base, err)
+			}
+			errs = append(errs, err)
+		}
+		backendWrites.Inc(map[string]string{"kind": kind, "backend": base, "result": result})
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	if v.WriteMode != WriteAll && len(errs) < len(backends) {
+		return nil
+	}
+	return errors.Join(errs...)
+}
+
+// statusError is a request the backend answered with an unexpected status.
+type statusError struct {
+	status int
+	msg    string
+}
+
+func (e *statusError) Error() string { return e.msg }
+
+// retryableWrite reports whether a failed write may succeed if repeated:
+// the backend was unreachable or failed internally, rather than rejecting
+// the payload.
+func retryableWrite(err error) bool {
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.status >= 500
+	}
+	return true
+}
+
+// postWithRetry is post with WriteRetries retries and exponential backoff.
+func (v *VictoriaDB) postWithRetry(ctx context.Context, rawURL, contentType string, payload []byte, failMsg string) error {
+	delay := v.WriteRetryDelay
+	err := v.post(ctx, rawURL, contentType, payload, failMsg)
+	for attempt := 0; attempt < v.WriteRetries && err != nil && retryableWrite(err); attempt++ {
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+		err = v.post(ctx, rawURL, contentType, payload, failMsg)
+	}
+	return err
+}
+
+func (v *VictoriaDB) post(ctx context.Context, rawURL, contentType string, payload []byte, failMsg string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rawURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if contentType == remoteWriteContentType {
+		req.Header.Set("Content-Encoding", "snappy")
+		req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	}
+	v.authorize(req)
+	resp, err := v.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
 
-		// Format entry for LLM context
-		entryStr, _ := json.Marshal(logEntry)
-		out.Write(entryStr)
-		out.WriteByte('\n')
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return &statusError{status: resp.StatusCode, msg: fmt.Sprintf("%s (%d): %s", failMsg, resp.StatusCode, string(body))}
 	}
+	return nil
+}
 
-	return out.String(), nil
+// decodedBody returns a reader over the response payload, transparently
+// gunzipping it when the server answered with Content-Encoding: gzip.
+func decodedBody(resp *http.Response) (io.ReadCloser, error) {
+	if !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return io.NopCloser(resp.Body), nil
+	}
+	zr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode gzip response: %v", err)
+	}
+	return zr, nil
 }
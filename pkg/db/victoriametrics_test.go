@@ -1,17 +1,22 @@
 package db
 
 import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestVictoriaDB_InsertMetric(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/write" {
-			t.Errorf("Expected path /write, got %s", r.URL.Path)
+		if r.URL.Path != "/api/v1/import/prometheus" {
+			t.Errorf("Expected path /api/v1/import/prometheus, got %s", r.URL.Path)
 		}
 		w.WriteHeader(http.StatusNoContent)
 	}))
@@ -25,11 +30,74 @@ func TestVictoriaDB_InsertMetric(t *testing.T) {
 	}
 }
 
+func TestVictoriaDB_InsertMetricAt(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	v := NewVictoriaDB(server.URL, server.URL)
+	ts := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := v.InsertMetricAt("test_metric", 1.5, nil, ts); err != nil {
+		t.Fatalf("Failed to insert metric: %v", err)
+	}
+
+	want := "test_metric 1.5 1709294400000\n"
+	if body != want {
+		t.Errorf("Expected %q, got %q", want, body)
+	}
+}
+
+func TestVictoriaDB_InsertMetrics_SingleRequest(t *testing.T) {
+	requests := 0
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	v := NewVictoriaDB(server.URL, server.URL)
+	ts := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	err := v.InsertMetrics([]Metric{
+		{Name: "a", Value: 1, Timestamp: ts},
+		{Name: "b", Value: 2, Labels: map[string]string{"pid": "7"}, Timestamp: ts},
+		{Name: "c", Value: 3},
+	})
+	if err != nil {
+		t.Fatalf("Failed to insert metrics: %v", err)
+	}
+
+	if requests != 1 {
+		t.Fatalf("Expected 1 request, got %d", requests)
+	}
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %q", body)
+	}
+	if lines[1] != `b{pid="7"} 2 1709294400000` {
+		t.Errorf("Unexpected line %q", lines[1])
+	}
+
+	if err := v.InsertMetrics(nil); err != nil || requests != 1 {
+		t.Errorf("Expected an empty batch to skip the request, got %d requests (err %v)", requests, err)
+	}
+}
+
 func TestVictoriaDB_InsertLogs(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/insert/jsonline" {
 			t.Errorf("Expected path /insert/jsonline, got %s", r.URL.Path)
 		}
+		q := r.URL.Query()
+		if q.Get("_time_field") != "timestamp" || q.Has("_msg_field") || q.Get("_stream_fields") != "processName" {
+			t.Errorf("Expected the field mapping params, got %s", r.URL.RawQuery)
+		}
 
 		body, _ := io.ReadAll(r.Body)
 		if !strings.Contains(string(body), "test-process") {
@@ -41,6 +109,7 @@ func TestVictoriaDB_InsertLogs(t *testing.T) {
 	defer server.Close()
 
 	v := NewVictoriaDB(server.URL, server.URL)
+	v.LogStreamFields = []string{"processName"}
 	entries := []LogEntry{
 		{
 			Timestamp:    "2024-01-01T00:00:01Z",
@@ -61,8 +130,9 @@ func TestVictoriaDB_QueryLogs(t *testing.T) {
 		if r.URL.Path != "/select/logsql/query" {
 			t.Errorf("Expected path /select/logsql/query, got %s", r.URL.Path)
 		}
-		if r.URL.Query().Get("query") != "processName:\"wifid\"" {
-			t.Errorf("Expected query param processName:\"wifid\", got %s", r.URL.Query().Get("query"))
+		want := `(processName:"wifid") AND _time:24h`
+		if r.URL.Query().Get("query") != want {
+			t.Errorf("Expected query param %s, got %s", want, r.URL.Query().Get("query"))
 		}
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(mockResponse))
@@ -79,3 +149,403 @@ func TestVictoriaDB_QueryLogs(t *testing.T) {
 		t.Fatalf("Expected results to contain wifid, got: %s", res)
 	}
 }
+
+func TestVictoriaDB_QueryLogsGzip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			t.Errorf("Expected Accept-Encoding gzip, got %q", r.Header.Get("Accept-Encoding"))
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(http.StatusOK)
+		zw := gzip.NewWriter(w)
+		zw.Write([]byte(`{"processName":"kernel_task","eventMessage":"compressed"}` + "\n"))
+		zw.Close()
+	}))
+	defer server.Close()
+
+	v := NewVictoriaDB(server.URL, server.URL)
+	res, err := v.QueryLogs("processName:\"kernel_task\"")
+	if err != nil {
+		t.Fatalf("Failed to query gzipped logs: %v", err)
+	}
+
+	if !strings.Contains(res, "compressed") {
+		t.Fatalf("Expected decompressed results to contain compressed, got: %s", res)
+	}
+}
+
+func TestVictoriaDB_QueryMetricsGzip(t *testing.T) {
+	mockResponse := `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"__name__":"cpu_usage_pct"},"value":[1700000000,"12.5"]}]}}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(http.StatusOK)
+		zw := gzip.NewWriter(w)
+		zw.Write([]byte(mockResponse))
+		zw.Close()
+	}))
+	defer server.Close()
+
+	v := NewVictoriaDB(server.URL, server.URL)
+	res, err := v.QueryMetrics("cpu_usage_pct")
+	if err != nil {
+		t.Fatalf("Failed to query gzipped metrics: %v", err)
+	}
+
+	if !strings.Contains(res, "cpu_usage_pct: 12.5") {
+		t.Fatalf("Expected decompressed metric value, got: %s", res)
+	}
+}
+
+func TestVictoriaDB_QueryLogsDefaultLimit(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`processName:"wifid"`, `(processName:"wifid") AND _time:24h | limit 50`},
+		{`* | filter eventMessage:"error" | limit 10`, `_time:24h | filter eventMessage:"error" | limit 10`},
+		{`eventMessage:"a | limit 5"`, `(eventMessage:"a | limit 5") AND _time:24h | limit 50`},
+	}
+
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if got := r.URL.Query().Get("query"); got != tt.want {
+				t.Errorf("Expected query %s, got %s", tt.want, got)
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		v := NewVictoriaDB(server.URL, server.URL)
+		v.LogLimit = 50
+		if _, err := v.QueryLogs(tt.query); err != nil {
+			t.Errorf("Failed to query logs %q: %v", tt.query, err)
+		}
+		server.Close()
+	}
+}
+
+func TestVictoriaDB_QueryMetricsResults(t *testing.T) {
+	mockResponse := `{"status":"success","data":{"resultType":"vector","result":[` +
+		`{"metric":{"__name__":"process_memory_mb","process_name":"Safari","pid":"42"},"value":[1700000000.5,"512.25"]},` +
+		`{"metric":{},"value":[1700000000,"NaN"]}]}}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(mockResponse))
+	}))
+	defer server.Close()
+
+	v := NewVictoriaDB(server.URL, server.URL)
+	results, err := v.QueryMetricsResults("process_memory_mb")
+	if err != nil {
+		t.Fatalf("Failed to query metrics: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+
+	first := results[0]
+	if first.Name != "process_memory_mb" || first.Labels["process_name"] != "Safari" || first.Value != 512.25 {
+		t.Errorf("Unexpected first result: %+v", first)
+	}
+	if _, ok := first.Labels["__name__"]; ok {
+		t.Error("Expected __name__ to be lifted out of Labels")
+	}
+	if first.Timestamp.UnixMilli() != 1700000000500 {
+		t.Errorf("Expected timestamp 1700000000500ms, got %d", first.Timestamp.UnixMilli())
+	}
+
+	// NaN cannot be represented in JSON, so it must encode as null rather than fail
+	data, err := json.Marshal(results)
+	if err != nil {
+		t.Fatalf("Failed to marshal results: %v", err)
+	}
+	if !strings.Contains(string(data), `"value":null`) {
+		t.Errorf("Expected NaN to marshal as null, got %s", data)
+	}
+}
+
+func TestVictoriaDB_MirroredWrites(t *testing.T) {
+	var hits int
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ok.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "disk full", http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	v := NewVictoriaDB(ok.URL+","+down.URL, ok.URL+", "+down.URL)
+	v.WriteRetries = 0
+	if len(v.MetricsURLs) != 2 || len(v.LogsURLs) != 2 {
+		t.Fatalf("Expected two backends each, got %v and %v", v.MetricsURLs, v.LogsURLs)
+	}
+
+	if err := v.InsertMetric("test_metric", 1, nil); err != nil {
+		t.Errorf("Expected write to succeed in any mode, got %v", err)
+	}
+	if err := v.InsertLogs([]LogEntry{{ProcessName: "test"}}); err != nil {
+		t.Errorf("Expected batch write to succeed in any mode, got %v", err)
+	}
+	if hits != 2 {
+		t.Errorf("Expected the healthy backend to receive both writes, got %d", hits)
+	}
+
+	v.WriteMode = WriteAll
+	err := v.InsertMetric("test_metric", 1, nil)
+	if err == nil || !strings.Contains(err.Error(), down.URL) {
+		t.Errorf("Expected all mode to report the failing backend, got %v", err)
+	}
+}
+
+func TestVictoriaDB_WriteRetry(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			http.Error(w, "starting up", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	v := NewVictoriaDB(server.URL, server.URL)
+	v.WriteRetryDelay = time.Millisecond
+	if err := v.InsertMetric("test_metric", 1, nil); err != nil {
+		t.Fatalf("Expected write to succeed after retries, got %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestVictoriaDB_WriteNoRetryOn4xx(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		http.Error(w, "cannot parse line", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	v := NewVictoriaDB(server.URL, server.URL)
+	v.WriteRetryDelay = time.Millisecond
+	if err := v.InsertLogs([]LogEntry{{ProcessName: "test"}}); err == nil {
+		t.Fatal("Expected a 400 to fail the write")
+	}
+	if attempts != 1 {
+		t.Errorf("Expected a 400 not to be retried, got %d attempts", attempts)
+	}
+}
+
+func TestVictoriaDB_QueryContextCanceled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	v := NewVictoriaDB(server.URL, server.URL)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	_, err := v.QueryMetricsContext(ctx, "cpu_usage_pct")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("Expected the query to stop when canceled, took %s", time.Since(start))
+	}
+}
+
+func TestVictoriaDB_Auth(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("Authorization"))
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	v := NewVictoriaDBWithAuth(server.URL, server.URL, "zenith", "s3cret", "")
+	v.InsertMetric("test_metric", 1, nil)
+	v.QueryMetrics("test_metric")
+
+	v = NewVictoriaDBWithAuth(server.URL, server.URL, "", "", "tok")
+	v.InsertLogs([]LogEntry{{ProcessName: "test"}})
+
+	want := []string{"Basic emVuaXRoOnMzY3JldA==", "Basic emVuaXRoOnMzY3JldA==", "Bearer tok"}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d requests, got %v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Request %d: expected Authorization %q, got %q", i, want[i], got[i])
+		}
+	}
+}
+
+func TestVictoriaDB_QueryFailover(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{"__name__":"cpu_usage_pct"},"value":[1700000000,"12.5"]}]}}`))
+	}))
+	defer ok.Close()
+
+	v := NewVictoriaDB(down.URL+","+ok.URL, "")
+	results, err := v.QueryMetricsResults("cpu_usage_pct")
+	if err != nil {
+		t.Fatalf("Expected query to fail over to the healthy backend, got %v", err)
+	}
+	if len(results) != 1 || results[0].Value != 12.5 {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+}
+
+func TestVictoriaDB_QueryTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("timeout"); got != "5s" {
+			t.Errorf("Expected timeout=5s, got %q", got)
+		}
+		http.Error(w, "cannot execute query: timeout exceeded", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	v := NewVictoriaDB(server.URL, server.URL)
+	v.QueryTimeout = 5 * time.Second
+	_, err := v.QueryMetrics("sum_over_time(process_cpu_pct[1y])")
+	if !errors.Is(err, ErrBadQuery) {
+		t.Fatalf("Expected ErrBadQuery for a server-side timeout, got %v", err)
+	}
+}
+
+func TestVictoriaDB_QueryOutageIsNotBadQuery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "storage unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	v := NewVictoriaDB(server.URL, server.URL)
+	if _, err := v.QueryMetrics("avg(cpu_usage_pct)"); err == nil || errors.Is(err, ErrBadQuery) {
+		t.Fatalf("Expected a non-ErrBadQuery error for an outage, got %v", err)
+	}
+}
+
+func TestQueryError_BadQuery(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		bad    bool
+	}{
+		{400, "unknown function", true},
+		{422, "cannot parse query", true},
+		{503, "cannot execute query: timeout exceeded", true},
+		{504, "context deadline exceeded", true},
+		{401, "unauthorized", false},
+		{403, "forbidden", false},
+		{404, "not found", false},
+		{429, "too many requests, timeout in 5s", false},
+		{500, "internal error", false},
+		{503, "storage unavailable", false},
+	}
+	for _, tt := range tests {
+		err := queryError("query failed", tt.status, []byte(tt.body))
+		if got := errors.Is(err, ErrBadQuery); got != tt.bad {
+			t.Errorf("queryError(%d, %q): ErrBadQuery = %v, want %v", tt.status, tt.body, got, tt.bad)
+		}
+	}
+}
+
+func TestVictoriaDB_QueryLogsMsgField(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if q := r.URL.Query().Get("query"); !strings.HasPrefix(q, `(_msg:"disk full")`) {
+			t.Errorf("Expected eventMessage to be queried as _msg, got %s", q)
+		}
+		w.Write([]byte(`{"_time":"2024-01-01T00:00:01Z","_msg":"disk full","processName":"kernel"}` + "\n"))
+	}))
+	defer server.Close()
+
+	v := NewVictoriaDB(server.URL, server.URL)
+	v.LogMsgField = "eventMessage"
+	results, err := v.QueryLogsResults(`eventMessage:"disk full"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Fields["eventMessage"] != "disk full" {
+		t.Errorf("Expected _msg to be returned as eventMessage, got %+v", results)
+	}
+}
+
+func TestVictoriaDB_StreamLogs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, name := range []string{"wifid", "kernel", "sshd"} {
+			w.Write([]byte(`{"processName":"` + name + `"}` + "\n"))
+		}
+	}))
+	defer server.Close()
+
+	v := NewVictoriaDB(server.URL, server.URL)
+	var names []string
+	err := v.StreamLogsContext(context.Background(), "*", func(entry LogResult) error {
+		names = append(names, entry.Fields["processName"])
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(names, ",") != "wifid,kernel,sshd" {
+		t.Errorf("Expected entries in order, got %v", names)
+	}
+
+	stop := errors.New("stop")
+	names = nil
+	err = v.StreamLogsContext(context.Background(), "*", func(entry LogResult) error {
+		names = append(names, entry.Fields["processName"])
+		return stop
+	})
+	if !errors.Is(err, stop) || len(names) != 1 {
+		t.Errorf("Expected the callback error to stop the stream after one entry, got %v after %v", err, names)
+	}
+}
+
+func TestLogEntry_Fields(t *testing.T) {
+	data, err := json.Marshal(LogEntry{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"timestamp", "processID", "processName", "subsystem", "category", "messageType", "eventMessage"} {
+		if _, ok := fields[name]; !ok {
+			t.Errorf("Expected LogEntry to marshal a %q field, got %s", name, data)
+		}
+	}
+	if len(fields) != 7 {
+		t.Errorf("Expected exactly 7 fields, got %s", data)
+	}
+
+	// VictoriaLogs is told which field holds _time; it must be a field
+	// LogEntry actually writes. The message field is only mapped on request.
+	v := NewVictoriaDB("http://localhost:8428", "http://localhost:9428")
+	if _, ok := fields[v.LogTimeField]; !ok {
+		t.Errorf("Default field mapping names %q, which LogEntry doesn't write", v.LogTimeField)
+	}
+	if v.LogMsgField != "" {
+		t.Errorf("Expected the message field mapping to be off by default, got %q", v.LogMsgField)
+	}
+}
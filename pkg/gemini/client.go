@@ -7,13 +7,21 @@ import (
 	"strings"
 
 	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
+
+	"zenith/pkg/collector"
+	"zenith/pkg/llm"
 )
 
+// ModelName is the Gemini model Zenith uses.
+const ModelName = "gemini-3-flash-preview"
+
 type Client struct {
-	Ctx    context.Context
-	Model  *genai.GenerativeModel
-	Client *genai.Client
+	Ctx          context.Context
+	Model        *genai.GenerativeModel
+	Client       *genai.Client
+	Temperatures llm.Temperatures
 }
 
 func NewClient(ctx context.Context, apiKey string) (*Client, error) {
@@ -22,7 +30,7 @@ func NewClient(ctx context.Context, apiKey string) (*Client, error) {
 		return nil, err
 	}
 
-	model := client.GenerativeModel("gemini-3-flash-preview")
+	model := client.GenerativeModel(ModelName)
 
 	// System instruction to act as a system analyst
 	model.SystemInstruction = &genai.Content{
@@ -30,33 +38,57 @@ func NewClient(ctx context.Context, apiKey string) (*Client, error) {
 			genai.Text("You are Zenith, an AI agent focused on system analysis. " +
 				"You have access to two databases:\n" +
 				"1. VictoriaMetrics (Metrics): Use this for numerical data over time (CPU, RAM, Disk I/O, Network). " +
-				"Metrics: 'cpu_usage_pct', 'memory_used_mb', 'process_cpu_pct', 'process_memory_mb', " +
+				"Metrics: 'cpu_usage_pct', 'memory_used_mb', 'process_cpu_pct', 'process_cpu_pct_normalized', 'process_memory_mb', " +
 				"'srum_network_bytes_sent_total', 'srum_network_bytes_received_total', 'srum_app_cycle_time_total', 'srum_app_bytes_read_total', 'srum_app_bytes_written_total'. " +
 				"Query this using MetricsQL (PromQL-compatible).\n" +
 				"2. VictoriaLogs (Logs): Use this for event logs (Windows Event Log, console messages). " +
 				"Query using LogsQL (Syntax: `field:value` or `field:\"value\"`). Fields: 'processName', 'subsystem', 'category', 'messageType', 'eventMessage'. " +
 				"NEVER use square brackets `[]`, NEVER use comparison operators like `>`, `<`, `>=`, `<=`, and NEVER use time filters (e.g., `timestamp`, `now`, `-1d`) in LogsQL filters. All time filtering is handled by the server.\n\n" +
 				"Your goal is to translate natural language questions into EXACTLY ONE appropriate query, " +
-				"prefixed with either 'METRIC:' or 'LOG:'. " +
+				"prefixed with 'METRIC:', 'RANGE:', or 'LOG:'. " +
 				"Do NOT return multiple lines or multiple queries. " +
 				"Be extremely concise, focus on the data, and avoid conversational filler."),
 		},
 	}
 
 	return &Client{
-		Ctx:    ctx,
-		Model:  model,
-		Client: client,
+		Ctx:          ctx,
+		Model:        model,
+		Client:       client,
+		Temperatures: llm.DefaultTemperatures,
 	}, nil
 }
 
-func (c *Client) GenerateSQL(userQuery string) (string, error) {
-	prompt := fmt.Sprintf("Based on the following user query, provide ONLY ONE database query prefixed with 'METRIC:' or 'LOG:'.\n\n"+
+// withTemperature returns a copy of the model that samples at temperature,
+// leaving the shared model untouched for concurrent calls.
+func (c *Client) withTemperature(temperature float32) *genai.GenerativeModel {
+	model := *c.Model
+	model.SetTemperature(temperature)
+	return &model
+}
+
+// SchemaPrompt returns the query-generation prompt without a user query,
+// so the metric names it advertises can be checked against the collectors.
+func (c *Client) SchemaPrompt() string {
+	return sqlPrompt("")
+}
+
+func sqlPrompt(userQuery string) string {
+	return fmt.Sprintf("Based on the following user query, provide ONLY ONE database query prefixed with 'METRIC:', 'RANGE:', or 'LOG:'.\n\n"+
 		"Metrics (VictoriaMetrics - MetricsQL):\n"+
-		"- System-wide (NO label filter needed): cpu_usage_pct, memory_used_mb\n"+
-		"- Per-process (use label `process_name`): process_cpu_pct, process_memory_mb\n"+
+		"- System-wide (NO label filter needed): cpu_usage_pct, memory_used_mb, memory_free_mb, system_open_fds\n"+
+		"- Disk I/O (macOS only; label `disk`, e.g. `disk0`): disk_read_kb, disk_write_kb, the KB per second read and written per disk, averaged over the collection interval. Sum over `disk` for the machine total\n"+
+		"- Thermal (macOS only, when enabled; NO label filter needed): cpu_temperature_c (CPU die temperature in Celsius), fan_rpm (label `fan` per fan). High temperature with high fan_rpm under load suggests thermal throttling\n"+
+		"- Services (Linux with systemd): service_failed_count (NO label filter needed; number of failed services), service_active (label `unit`, e.g. `nginx.service`; 1 if running, 0 otherwise). For \"are my services healthy?\" check service_failed_count, then service_active == 0 to name them\n"+
+		"- Log volume (label `level`, the log messageType): log_event_count is how many log entries arrived per collection cycle. For trends such as \"is my error rate climbing?\" query log_event_count{level=~\"(?i)error|fault|critical\"} over time rather than counting logs\n"+
+		"- CPU spread within a collection cycle (only when sub-sampling is enabled): cpu_usage_pct_min, cpu_usage_pct_max, cpu_usage_pct_p95. Use cpu_usage_pct_max for questions about spikes\n"+
+		"- Per-process (use label `process_name`): process_cpu_pct, process_cpu_pct_normalized, process_memory_mb, process_open_fds\n"+
+		"- process_open_fds is open file descriptors (handles on Windows); a steadily rising value suggests a leak\n"+
+		"- process_cpu_pct is percent of ONE core and can exceed 100 on multi-core systems; process_cpu_pct_normalized is the 0-100 share of total machine CPU\n"+
 		"- SRUM app (use labels `app_name`, `user_name`): srum_app_cycle_time_total, srum_app_bytes_read_total, srum_app_bytes_written_total, srum_app_duration_ms, srum_app_foreground_cycle_time_total, srum_app_background_cycle_time_total\n"+
-		"- SRUM network (NO label needed): srum_network_bytes_sent_total, srum_network_bytes_received_total\n\n"+
+		"- SRUM network (NO label needed): srum_network_bytes_sent_total, srum_network_bytes_received_total\n"+
+		"- %s\n"+
+		"- %s\n\n"+
 		"Logs (VictoriaLogs - LogsQL):\n"+
 		"- Fields: processName, subsystem, category, messageType, eventMessage\n"+
 		"- Syntax: `field:value` or `field:\"exact string\"`\n\n"+
@@ -69,21 +101,33 @@ func (c *Client) GenerateSQL(userQuery string) (string, error) {
 		"6. NEVER compare metrics to strings. To check for existence, use `metric_name > 0`.\n"+
 		"7. MetricsQL uses lowercase logical operators: `and`, `or`, `unless`.\n"+
 		"8. MetricsQL NEVER uses SQL syntax like `ORDER BY` or `LIMIT`. To rank results, use `topk(n, metric)`.\n"+
-		"9. LogsQL uses `:` for equality (NEVER `=` or `==`).\n"+
-		"10. LogsQL NEVER uses comparison operators like `>`, `<`, `>=`, `<=`. Use `:` for all filters.\n"+
-		"11. LogsQL NEVER uses time-related keywords in the query string (e.g., `timestamp`, `@timestamp`, `now`, `24h`, `1d`).\n"+
-		"12. NEVER use square brackets `[]` for filters or grouping in LogsQL.\n"+
-		"13. For arithmetic, do NOT repeat the prefix.\n\n"+
+		"9. Metrics carry a `host` label naming the machine that reported them. Only group by it when the user asks about hosts, machines, or the fleet: compare with `sort_desc(avg by (host) (avg_over_time(cpu_usage_pct[1h])))`, and for \"which host is busiest\" use `topk(1, avg by (host) (avg_over_time(cpu_usage_pct[1h])))`.\n"+
+		"10. LogsQL uses `:` for equality (NEVER `=` or `==`).\n"+
+		"11. LogsQL NEVER uses comparison operators like `>`, `<`, `>=`, `<=`. Use `:` for all filters.\n"+
+		"12. LogsQL NEVER uses time-related keywords in the query string (e.g., `timestamp`, `@timestamp`, `now`, `24h`, `1d`).\n"+
+		"13. NEVER use square brackets `[]` for filters or grouping in LogsQL.\n"+
+		"14. For arithmetic, do NOT repeat the prefix.\n"+
+		"15. To find logs by process or app name, use a case-insensitive regex substring match `processName:~\"(?i)name\"`. An exact `processName:\"chrome\"` will NOT match `Google Chrome`.\n"+
+		"16. For trends or history over a period (\"CPU over the last hour\", \"how has memory changed today\"), use a RANGE: query: the window, a space, then MetricsQL, e.g. `RANGE:1h avg(cpu_usage_pct)`. It returns the values across the window rather than one current value. Use METRIC: for current values and rankings.\n\n"+
 		"Example 'System performance': `METRIC:avg(cpu_usage_pct)`\n"+
 		"Example 'Memory': `METRIC:avg(memory_used_mb)`\n"+
 		"Example 'Process CPU': `METRIC:topk(5, process_cpu_pct)`\n"+
+		"Example 'Which process is leaking file descriptors': `METRIC:topk(5, delta(process_open_fds[1h]))`\n"+
 		"Example 'Any SRUM data': `METRIC:srum_app_bytes_read_total > 0`\n"+
 		"Example 'Most disk IO apps': `METRIC:topk(10, srum_app_bytes_written_total)`\n"+
 		"Example 'Most CPU apps (SRUM)': `METRIC:topk(10, srum_app_cycle_time_total)`\n"+
-		"Example LogsQL: `LOG:eventMessage:\"error\" AND processName:\"wifid\"`\n\n"+
-		"Query: %s\n\nResponse:", userQuery)
+		"Example 'Network bytes sent in the last hour': `METRIC:sum(increase(srum_network_bytes_sent_total[1h]))`\n"+
+		"Example 'Compare memory across all hosts': `METRIC:sort_desc(avg by (host) (avg_over_time(memory_used_mb[1h])))`\n"+
+		"Example 'CPU over the last hour': `RANGE:1h avg(cpu_usage_pct)`\n"+
+		"Example LogsQL: `LOG:eventMessage:\"error\" AND processName:\"wifid\"`\n"+
+		"Example 'Logs from chrome': `LOG:processName:~\"(?i)chrome\"`\n\n"+
+		"Query: %s\n\nResponse:", llm.CounterGuidance(collector.CounterNames), llm.UnitGuidance(collector.MetricUnits), userQuery)
+}
 
-	resp, err := c.Model.GenerateContent(c.Ctx, genai.Text(prompt))
+func (c *Client) GenerateSQL(userQuery string) (string, error) {
+	prompt := sqlPrompt(userQuery)
+
+	resp, err := c.withTemperature(c.Temperatures.GenerateSQL).GenerateContent(c.Ctx, genai.Text(prompt))
 	if err != nil {
 		return "", err
 	}
@@ -109,7 +153,7 @@ func cleanSQL(s string) string {
 	s = strings.TrimSuffix(s, "```")
 	s = strings.TrimSpace(s)
 
-	// If multiple lines, pick the first one that starts with METRIC or LOG
+	// If multiple lines, pick the first one that starts with METRIC, RANGE or LOG
 	// or the first non-empty line.
 	var selected string
 	lines := strings.Split(s, "\n")
@@ -119,7 +163,7 @@ func cleanSQL(s string) string {
 			continue
 		}
 		upper := strings.ToUpper(trimmed)
-		if strings.HasPrefix(upper, "METRIC:") || strings.HasPrefix(upper, "LOG:") {
+		if strings.HasPrefix(upper, "METRIC:") || strings.HasPrefix(upper, "RANGE:") || strings.HasPrefix(upper, "LOG:") {
 			selected = trimmed
 			break
 		}
@@ -140,16 +184,19 @@ func cleanSQL(s string) string {
 		return s
 	}
 
-	// Globally remove all instances of METRIC: and LOG: from the selected line
+	// Globally remove all instances of METRIC:, RANGE: and LOG: from the selected line
 	// to handle hallucinations like "METRIC:m1 + METRIC:m2"
 	upperSelected := strings.ToUpper(selected)
 	hasLog := strings.HasPrefix(upperSelected, "LOG:")
+	hasRange := strings.HasPrefix(upperSelected, "RANGE:")
 
 	res := selected
 	// Case-insensitive removal of all prefixes
 	reMetric := strings.NewReplacer("METRIC:", "", "metric:", "", "Metric:", "")
+	reRange := strings.NewReplacer("RANGE:", "", "range:", "", "Range:", "")
 	reLog := strings.NewReplacer("LOG:", "", "log:", "", "Log:", "")
 	res = reMetric.Replace(res)
+	res = reRange.Replace(res)
 	res = reLog.Replace(res)
 	res = strings.TrimSpace(res)
 
@@ -182,23 +229,14 @@ func cleanSQL(s string) string {
 	if hasLog {
 		return "LOG:" + res
 	}
+	if hasRange {
+		return "RANGE:" + res
+	}
 	return "METRIC:" + res
 }
 
-func (c *Client) ExplainResults(userQuery, sql, results string) (string, error) {
-	prompt := fmt.Sprintf("Analyze the database results below to answer the user's question.\n\n"+
-		"Rules:\n"+
-		"1. If the results are 'NO_DATA_FOUND' or empty, say 'No data found for this query'.\n"+
-		"2. If results contain metrics with value 0, explain that those apps/processes showed no activity for that metric - do NOT say 'no data found'.\n"+
-		"3. Do NOT invent application names, process IDs, or numerical values.\n"+
-		"4. Do NOT use placeholder names like 'Application X' or 'Process 123'.\n"+
-		"5. Be extremely concise. If all values are 0, say so clearly.\n\n"+
-		"User Query: %s\n"+
-		"SQL/Query Executed: %s\n"+
-		"Database Results: %s\n\n"+
-		"Explanation:", userQuery, sql, results)
-
-	resp, err := c.Model.GenerateContent(c.Ctx, genai.Text(prompt))
+func (c *Client) ExplainResults(userQuery, sql, results, language string) (string, error) {
+	resp, err := c.withTemperature(c.Temperatures.Explain).GenerateContent(c.Ctx, genai.Text(explainPrompt(userQuery, sql, results, language)))
 	if err != nil {
 		return "", err
 	}
@@ -213,18 +251,66 @@ func (c *Client) ExplainResults(userQuery, sql, results string) (string, error)
 	return explanation, nil
 }
 
-func (c *Client) GenerateRecommendations(systemData string) (string, error) {
+// ExplainResultsStream is ExplainResults, passing each chunk of text to
+// onToken as Gemini produces it.
+func (c *Client) ExplainResultsStream(userQuery, sql, results, language string, onToken func(string)) (string, error) {
+	iter := c.withTemperature(c.Temperatures.Explain).GenerateContentStream(c.Ctx, genai.Text(explainPrompt(userQuery, sql, results, language)))
+
+	var explanation strings.Builder
+	for {
+		resp, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+			continue
+		}
+		for _, part := range resp.Candidates[0].Content.Parts {
+			if text, ok := part.(genai.Text); ok && text != "" {
+				explanation.WriteString(string(text))
+				if onToken != nil {
+					onToken(string(text))
+				}
+			}
+		}
+	}
+	return explanation.String(), nil
+}
+
+func explainPrompt(userQuery, sql, results, language string) string {
+	return fmt.Sprintf("Analyze the database results below to answer the user's question.\n\n"+
+		"Rules:\n"+
+		"1. If the results are 'NO_DATA_FOUND' or empty, say 'No data found for this query'.\n"+
+		"2. If results contain metrics with value 0, explain that those apps/processes showed no activity for that metric - do NOT say 'no data found'.\n"+
+		"3. Do NOT invent application names, process IDs, or numerical values.\n"+
+		"4. Do NOT use placeholder names like 'Application X' or 'Process 123'.\n"+
+		"5. Be extremely concise. If all values are 0, say so clearly.\n"+
+		"6. process_cpu_pct is per-core and may exceed 100%% on multi-core systems (e.g. 250%% = 2.5 cores busy). This is normal; do NOT call it an error.\n"+
+		"7. %s\n\n"+
+		"User Query: %s\n"+
+		"SQL/Query Executed: %s\n"+
+		"Database Results: %s\n\n"+
+		"Explanation:", llm.LanguageDirective(language), userQuery, sql, results)
+}
+
+func (c *Client) GenerateRecommendations(systemData, language string) (string, error) {
 	prompt := fmt.Sprintf("You are Zenith, an AI expert in system performance.\n"+
-		"Based on the following recent system data, provide 3-5 concrete recommendations for performance improvement.\n"+
-		"Be extremely concise, focus on actionable advice, and avoid conversational filler.\n\n"+
-		"System Data:\n%s\n\nRecommendations:", systemData)
+		"Based on the following recent system data, provide 3-5 concrete performance tuning recommendations.\n"+
+		"Format them as a bulleted list ordered by priority, most impactful first, one line per bullet.\n"+
+		"Be extremely concise, focus on actionable advice, and avoid conversational filler.\n"+
+		"%s\n\n"+
+		"System Data:\n%s\n\nRecommendations:", llm.LanguageDirective(language), systemData)
 
-	resp, err := c.Model.GenerateContent(c.Ctx, genai.Text(prompt))
+	resp, err := c.withTemperature(c.Temperatures.Recommend).GenerateContent(c.Ctx, genai.Text(prompt))
 	if err != nil {
 		return "", err
 	}
 
-	if len(resp.Candidates) == 0 {
+	// A candidate blocked by safety filters comes back without content.
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
 		return "", fmt.Errorf("no response from Gemini")
 	}
 
@@ -1,6 +1,7 @@
 package llamacpp
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
@@ -8,11 +9,15 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"zenith/pkg/collector"
+	"zenith/pkg/llm"
 )
 
 type Client struct {
-	BaseURL string
-	Client  *http.Client
+	BaseURL      string
+	Client       *http.Client
+	Temperatures llm.Temperatures
 }
 
 type ChatMessage struct {
@@ -21,8 +26,9 @@ type ChatMessage struct {
 }
 
 type ChatRequest struct {
-	Messages []ChatMessage `json:"messages"`
-	Stream   bool          `json:"stream"`
+	Messages    []ChatMessage `json:"messages"`
+	Stream      bool          `json:"stream"`
+	Temperature float32       `json:"temperature"`
 }
 
 type ChatResponse struct {
@@ -34,23 +40,53 @@ type ChatResponse struct {
 	} `json:"error,omitempty"`
 }
 
+// ChatChunk is one server-sent event of a streamed chat completion.
+type ChatChunk struct {
+	Choices []struct {
+		Delta ChatMessage `json:"delta"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
 func NewClient(baseURL string) *Client {
 	return &Client{
-		BaseURL: baseURL,
-		Client:  &http.Client{Timeout: 300 * time.Second},
+		BaseURL:      baseURL,
+		Client:       &http.Client{Timeout: 300 * time.Second},
+		Temperatures: llm.DefaultTemperatures,
+	}
+}
+
+// Ping checks that llama-server is up and has finished loading its model.
+func (c *Client) Ping() error {
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(c.BaseURL + "/health")
+	if err != nil {
+		return fmt.Errorf("failed to connect to llama.cpp at %s: %v", c.BaseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("llama.cpp not ready (%d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
 	}
+	return nil
 }
 
-func (c *Client) generate(prompt string, systemPrompt string) (string, error) {
+func chatMessages(prompt, systemPrompt string) []ChatMessage {
 	messages := []ChatMessage{}
 	if systemPrompt != "" {
 		messages = append(messages, ChatMessage{Role: "system", Content: systemPrompt})
 	}
-	messages = append(messages, ChatMessage{Role: "user", Content: prompt})
+	return append(messages, ChatMessage{Role: "user", Content: prompt})
+}
 
+func (c *Client) generate(prompt string, systemPrompt string, temperature float32) (string, error) {
 	reqBody := ChatRequest{
-		Messages: messages,
-		Stream:   false,
+		Messages:    chatMessages(prompt, systemPrompt),
+		Stream:      false,
+		Temperature: temperature,
 	}
 
 	data, err := json.Marshal(reqBody)
@@ -85,32 +121,113 @@ func (c *Client) generate(prompt string, systemPrompt string) (string, error) {
 	return chatResp.Choices[0].Message.Content, nil
 }
 
+// sqlSystemPrompt describes the databases and query rules for GenerateSQL.
+var sqlSystemPrompt = "You are Zenith, an AI expert in system performance. " +
+	"You have access to two databases:\n" +
+	"1. VictoriaMetrics (Metrics): Query using MetricsQL (PromQL-compatible). Metrics: 'cpu_usage_pct', 'cpu_usage_pct_min', 'cpu_usage_pct_max', 'cpu_usage_pct_p95', 'memory_used_mb', 'memory_free_mb', 'system_open_fds', 'disk_read_kb', 'disk_write_kb', 'cpu_temperature_c', 'fan_rpm', 'service_failed_count', 'service_active', 'log_event_count', 'process_cpu_pct', 'process_cpu_pct_normalized', 'process_memory_mb', 'process_open_fds', 'srum_network_bytes_sent_total', 'srum_network_bytes_received_total', 'srum_app_cycle_time_total', 'srum_app_bytes_read_total', 'srum_app_bytes_written_total', 'srum_app_duration_ms', 'srum_app_foreground_cycle_time_total', 'srum_app_background_cycle_time_total'.\n" +
+	"2. VictoriaLogs (Logs): Query using LogsQL (Syntax: `field:value`). Fields: processName, subsystem, category, messageType, eventMessage. NEVER use square brackets `[]`, NEVER use comparison operators like `>`, `<`, `>=`, `<=`, and NEVER use time filters (e.g., `timestamp`, `now`, `-1d`) in LogsQL filters.\n\n" +
+	"Based on the user query, provide EXACTLY ONE database query prefixed with 'METRIC:', 'RANGE:', or 'LOG:'. Do NOT include explanation or markdown.\n\n" +
+	"Rules for Queries:\n" +
+	"- Return ONLY ONE line. Multi-line responses will fail.\n" +
+	"- NEVER combine metrics and logs in the same query. Choose ONE.\n" +
+	"- For trends or history over a period (\"CPU over the last hour\", \"how has memory changed today\"), use a RANGE: query: the window, a space, then MetricsQL, e.g. `RANGE:1h avg(cpu_usage_pct)`. It returns the values across the window rather than one current value. Use METRIC: for current values and rankings.\n" +
+	"- SRUM data (network, disk, cycle time) is exclusively stored as METRICS, never as LOGS.\n" +
+	"- For SRUM app metrics, use the label `app_name`.\n" +
+	"- For process metrics, use the label `process_name`.\n" +
+	"- cpu_usage_pct_min/_max/_p95 are the CPU spread within a collection cycle (only when sub-sampling is enabled). Use cpu_usage_pct_max for questions about spikes.\n" +
+	"- disk_read_kb and disk_write_kb (label `disk`, e.g. `disk0`) are macOS per-disk read and write rates in KB per second; sum over `disk` for the machine total.\n" +
+	"- cpu_temperature_c and fan_rpm (label `fan`) are macOS-only thermal readings; high temperature with high fan speed under load suggests thermal throttling.\n" +
+	"- service_failed_count is the number of failed systemd services (Linux); service_active{unit=\"nginx.service\"} is 1 while a service runs. For service health, check service_failed_count, then `service_active == 0` to name the failed ones.\n" +
+	"- log_event_count (label `level`) is the number of log entries per collection cycle; use it for log volume or error-rate trends, e.g. `log_event_count{level=~\"(?i)error|fault\"}`.\n" +
+	"- process_open_fds is open file descriptors (handles on Windows); for leaks, rank by growth, e.g. `topk(5, delta(process_open_fds[1h]))`.\n" +
+	"- process_cpu_pct is percent of ONE core and can exceed 100 on multi-core systems; process_cpu_pct_normalized is the 0-100 share of total machine CPU.\n" +
+	"- MetricsQL regex uses `=~`, e.g., `process_memory_mb{process_name=~\"(?i)ollama\"}`.\n" +
+	"- MetricsQL NEVER uses SQL syntax like `ORDER BY` or `LIMIT`. To rank results, use `topk(n, metric)`.\n" +
+	"- Metrics carry a `host` label naming the machine that reported them. Only group by it when the user asks about hosts, machines, or the fleet: compare with `sort_desc(avg by (host) (avg_over_time(cpu_usage_pct[1h])))`, and for \"which host is busiest\" use `topk(1, avg by (host) (avg_over_time(cpu_usage_pct[1h])))`.\n" +
+	"- LogsQL uses `:` for equality, NEVER `=`, `==`, or a bare `~` (e.g. `processName:\"wifid\"`).\n" +
+	"- To find logs by process or app name, use a case-insensitive regex substring match `processName:~\"(?i)name\"`. An exact `processName:\"chrome\"` will NOT match `Google Chrome`.\n" +
+	"- LogsQL NEVER uses comparison operators like `>`, `<`, `>=`, `<=`. Use `:` for all filters.\n" +
+	"- LogsQL NEVER uses time-related keywords (e.g., `timestamp`, `@timestamp`, `now`, `24h`, `1d`).\n" +
+	"- LogsQL uses `AND`/`OR` for logic, NEVER `,` or `|`.\n" +
+	"- NEVER use square brackets `[]` for filters or grouping in LogsQL.\n" +
+	"- " + llm.CounterGuidance(collector.CounterNames) + "\n" +
+	"- " + llm.UnitGuidance(collector.MetricUnits) + "\n" +
+	"- For arithmetic, do NOT repeat the prefix, e.g., `METRIC:sum(m1) + sum(m2)`.\n\n" +
+	"Example range query: `RANGE:1h avg(cpu_usage_pct)`\n" +
+	"Example MetricsQL: `avg(cpu_usage_pct)`, `srum_network_bytes_sent_total > 0`, `sum(increase(srum_network_bytes_sent_total[1h]))`, `sort_desc(avg by (host) (avg_over_time(memory_used_mb[1h])))`\n" +
+	"Example LogsQL: `eventMessage:\"error\" AND processName:\"wifid\"`, `processName:~\"(?i)chrome\"`"
+
+// generateStream is generate with Stream set: llama-server answers with
+// server-sent events carrying one delta each, ending with `data: [DONE]`.
+// onToken is called with each delta, and the full text is returned.
+func (c *Client) generateStream(prompt, systemPrompt string, temperature float32, onToken func(string)) (string, error) {
+	reqBody := ChatRequest{
+		Messages:    chatMessages(prompt, systemPrompt),
+		Stream:      true,
+		Temperature: temperature,
+	}
+
+	data, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.Client.Post(c.BaseURL+"/v1/chat/completions", "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to llama.cpp: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("llama.cpp API error: %s", string(body))
+	}
+
+	var full strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		payload, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if payload == "[DONE]" {
+			break
+		}
+		var chunk ChatChunk
+		if err := json.Unmarshal([]byte(payload), &chunk); err != nil {
+			return "", err
+		}
+		if chunk.Error != nil && chunk.Error.Message != "" {
+			return "", fmt.Errorf("llama.cpp error: %s", chunk.Error.Message)
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content != "" {
+				full.WriteString(choice.Delta.Content)
+				if onToken != nil {
+					onToken(choice.Delta.Content)
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return full.String(), nil
+}
+
+// SchemaPrompt returns the query-generation system prompt so the metric
+// names it advertises can be checked against the collectors.
+func (c *Client) SchemaPrompt() string {
+	return sqlSystemPrompt
+}
+
 func (c *Client) GenerateSQL(userQuery string) (string, error) {
-	systemPrompt := "You are Zenith, an AI expert in system performance. " +
-		"You have access to two databases:\n" +
-		"1. VictoriaMetrics (Metrics): Query using MetricsQL (PromQL-compatible). Metrics: 'cpu_usage_pct', 'memory_used_mb', 'process_cpu_pct', 'process_memory_mb', 'srum_network_bytes_sent_total', 'srum_network_bytes_received_total', 'srum_app_cycle_time_total', 'srum_app_bytes_read_total', 'srum_app_bytes_written_total'.\n" +
-		"2. VictoriaLogs (Logs): Query using LogsQL (Syntax: `field:value`). Fields: processName, subsystem, category, messageType, eventMessage. NEVER use square brackets `[]`, NEVER use comparison operators like `>`, `<`, `>=`, `<=`, and NEVER use time filters (e.g., `timestamp`, `now`, `-1d`) in LogsQL filters.\n\n" +
-		"Based on the user query, provide EXACTLY ONE database query prefixed with 'METRIC:' or 'LOG:'. Do NOT include explanation or markdown.\n\n" +
-		"Rules for Queries:\n" +
-		"- Return ONLY ONE line. Multi-line responses will fail.\n" +
-		"- NEVER combine metrics and logs in the same query. Choose ONE.\n" +
-		"- SRUM data (network, disk, cycle time) is exclusively stored as METRICS, never as LOGS.\n" +
-		"- For SRUM app metrics, use the label `app_name`.\n" +
-		"- For process metrics, use the label `process_name`.\n" +
-		"- MetricsQL regex uses `=~`, e.g., `process_memory_mb{process_name=~\"(?i)ollama\"}`.\n" +
-		"- MetricsQL NEVER uses SQL syntax like `ORDER BY` or `LIMIT`. To rank results, use `topk(n, metric)`.\n" +
-		"- LogsQL uses `:` for equality, NEVER `=`, `==`, or `~` (e.g. `processName:\"wifid\"`).\n" +
-		"- LogsQL NEVER uses comparison operators like `>`, `<`, `>=`, `<=`. Use `:` for all filters.\n" +
-		"- LogsQL NEVER uses time-related keywords (e.g., `timestamp`, `@timestamp`, `now`, `24h`, `1d`).\n" +
-		"- LogsQL uses `AND`/`OR` for logic, NEVER `,` or `|`.\n" +
-		"- NEVER use square brackets `[]` for filters or grouping in LogsQL.\n" +
-		"- For arithmetic, do NOT repeat the prefix, e.g., `METRIC:sum(m1) + sum(m2)`.\n\n" +
-		"Example MetricsQL: `avg(cpu_usage_pct)`, `srum_network_bytes_sent_total > 0`\n" +
-		"Example LogsQL: `eventMessage:\"error\" AND processName:\"wifid\"`"
+	systemPrompt := sqlSystemPrompt
 
 	prompt := fmt.Sprintf("Query: %s\n\nResponse:", userQuery)
 
-	resp, err := c.generate(prompt, systemPrompt)
+	resp, err := c.generate(prompt, systemPrompt, c.Temperatures.GenerateSQL)
 	if err != nil {
 		return "", err
 	}
@@ -118,28 +235,43 @@ func (c *Client) GenerateSQL(userQuery string) (string, error) {
 	return cleanSQL(resp), nil
 }
 
-func (c *Client) ExplainResults(userQuery, sql, results string) (string, error) {
-	systemPrompt := "You are Zenith, an AI expert in system performance. " +
+func (c *Client) ExplainResults(userQuery, sql, results, language string) (string, error) {
+	prompt, systemPrompt := explainPrompts(userQuery, sql, results, language)
+	return c.generate(prompt, systemPrompt, c.Temperatures.Explain)
+}
+
+// ExplainResultsStream is ExplainResults, passing each token to onToken as
+// the model produces it.
+func (c *Client) ExplainResultsStream(userQuery, sql, results, language string, onToken func(string)) (string, error) {
+	prompt, systemPrompt := explainPrompts(userQuery, sql, results, language)
+	return c.generateStream(prompt, systemPrompt, c.Temperatures.Explain, onToken)
+}
+
+func explainPrompts(userQuery, sql, results, language string) (prompt, systemPrompt string) {
+	systemPrompt = "You are Zenith, an AI expert in system performance. " +
 		"Analyze the database results below to answer the user's question. " +
 		"Rules:\n" +
 		"1. If the results are 'NO_DATA_FOUND' or empty, you MUST say 'No data found for this query'.\n" +
 		"2. Do NOT invent names, PIDs, or values.\n" +
 		"3. Do NOT use placeholders like 'Application X'.\n" +
-		"4. Be extremely concise."
-
-	prompt := fmt.Sprintf("User Query: %s\nSQL Executed: %s\nDatabase Results: %s\n\nAnalysis:", userQuery, sql, results)
+		"4. Be extremely concise.\n" +
+		"5. process_cpu_pct is per-core and may exceed 100% on multi-core systems (e.g. 250% = 2.5 cores busy). This is normal; do NOT call it an error.\n" +
+		"6. " + llm.LanguageDirective(language)
 
-	return c.generate(prompt, systemPrompt)
+	prompt = fmt.Sprintf("User Query: %s\nSQL Executed: %s\nDatabase Results: %s\n\nAnalysis:", userQuery, sql, results)
+	return prompt, systemPrompt
 }
 
-func (c *Client) GenerateRecommendations(systemData string) (string, error) {
+func (c *Client) GenerateRecommendations(systemData, language string) (string, error) {
 	systemPrompt := "You are Zenith, an AI expert in system performance. " +
 		"Based on the following recent system data, provide 3-5 concrete recommendations for performance improvement. " +
-		"Be extremely concise, focus on actionable advice, and avoid conversational filler."
+		"Format them as a short bulleted list ordered by priority, most impactful first. " +
+		"Be extremely concise, focus on actionable advice, and avoid conversational filler. " +
+		llm.LanguageDirective(language)
 
 	prompt := fmt.Sprintf("System Data:\n%s\n\nRecommendations:", systemData)
 
-	return c.generate(prompt, systemPrompt)
+	return c.generate(prompt, systemPrompt, c.Temperatures.Recommend)
 }
 
 func cleanSQL(s string) string {
@@ -179,7 +311,7 @@ func cleanSQL(s string) string {
 			continue
 		}
 		upper := strings.ToUpper(trimmed)
-		if strings.HasPrefix(upper, "METRIC:") || strings.HasPrefix(upper, "LOG:") {
+		if strings.HasPrefix(upper, "METRIC:") || strings.HasPrefix(upper, "RANGE:") || strings.HasPrefix(upper, "LOG:") {
 			selected = trimmed
 			break
 		}
@@ -200,14 +332,17 @@ func cleanSQL(s string) string {
 		return s
 	}
 
-	// Globally remove all instances of METRIC: and LOG: from the selected line
+	// Globally remove all instances of METRIC:, RANGE: and LOG: from the selected line
 	upperSelected := strings.ToUpper(selected)
 	hasLog := strings.HasPrefix(upperSelected, "LOG:")
+	hasRange := strings.HasPrefix(upperSelected, "RANGE:")
 
 	res := selected
 	reMetric := strings.NewReplacer("METRIC:", "", "metric:", "", "Metric:", "")
+	reRange := strings.NewReplacer("RANGE:", "", "range:", "", "Range:", "")
 	reLog := strings.NewReplacer("LOG:", "", "log:", "", "Log:", "")
 	res = reMetric.Replace(res)
+	res = reRange.Replace(res)
 	res = reLog.Replace(res)
 	res = strings.TrimSpace(res)
 
@@ -240,5 +375,8 @@ func cleanSQL(s string) string {
 	if hasLog {
 		return "LOG:" + res
 	}
+	if hasRange {
+		return "RANGE:" + res
+	}
 	return "METRIC:" + res
 }
@@ -0,0 +1,172 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrProviderUnavailable is returned while the circuit breaker is open and
+// calls are being fast-failed instead of reaching the LLM backend.
+var ErrProviderUnavailable = errors.New("LLM temporarily unavailable")
+
+// BreakerState is the state of a circuit breaker.
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// Breaker wraps a Provider with a circuit breaker. After Threshold consecutive
+// failures it opens and fast-fails every call for Cooldown, then lets a single
+// trial call through (half-open) to decide whether to close again.
+type Breaker struct {
+	provider  Provider
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	trial    bool
+	now      func() time.Time
+}
+
+// NewBreaker wraps provider with a circuit breaker.
+func NewBreaker(provider Provider, threshold int, cooldown time.Duration) *Breaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &Breaker{
+		provider:  provider,
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// State reports the current breaker state, transitioning from open to
+// half-open once the cooldown has elapsed.
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refresh()
+	return b.state
+}
+
+// ConsecutiveFailures reports the number of failures since the last success.
+func (b *Breaker) ConsecutiveFailures() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures
+}
+
+func (b *Breaker) refresh() {
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.cooldown {
+		b.state = BreakerHalfOpen
+		b.trial = false
+	}
+}
+
+func (b *Breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refresh()
+
+	switch b.state {
+	case BreakerOpen:
+		return ErrProviderUnavailable
+	case BreakerHalfOpen:
+		// Only one trial call may probe the backend at a time.
+		if b.trial {
+			return ErrProviderUnavailable
+		}
+		b.trial = true
+	}
+	return nil
+}
+
+func (b *Breaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if errors.Is(err, context.Canceled) {
+		// The caller gave up, which says nothing about the backend; just
+		// let the next call be the trial.
+		b.trial = false
+		return
+	}
+	if err == nil {
+		b.state = BreakerClosed
+		b.failures = 0
+		b.trial = false
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.state = BreakerOpen
+		b.openedAt = b.now()
+		b.trial = false
+	}
+}
+
+func (b *Breaker) call(fn func() (string, error)) (string, error) {
+	if err := b.allow(); err != nil {
+		return "", err
+	}
+	// A panic counts as a failure before it propagates; otherwise a
+	// half-open breaker would wait forever on a trial call that never ends.
+	defer func() {
+		if p := recover(); p != nil {
+			b.record(fmt.Errorf("LLM provider panicked: %v", p))
+			panic(p)
+		}
+	}()
+	res, err := fn()
+	b.record(err)
+	return res, err
+}
+
+// Ping checks the wrapped provider's backend if it supports it. It bypasses
+// the breaker and does not count towards its failures.
+func (b *Breaker) Ping() error {
+	if p, ok := b.provider.(Pinger); ok {
+		return p.Ping()
+	}
+	return nil
+}
+
+func (b *Breaker) GenerateSQL(userQuery string) (string, error) {
+	return b.call(func() (string, error) { return b.provider.GenerateSQL(userQuery) })
+}
+
+func (b *Breaker) ExplainResults(userQuery, sql, results, language string) (string, error) {
+	return b.call(func() (string, error) { return b.provider.ExplainResults(userQuery, sql, results, language) })
+}
+
+func (b *Breaker) ExplainResultsStream(userQuery, sql, results, language string, onToken func(string)) (string, error) {
+	return b.call(func() (string, error) {
+		return ExplainStream(b.provider, userQuery, sql, results, language, onToken)
+	})
+}
+
+func (b *Breaker) GenerateRecommendations(systemData, language string) (string, error) {
+	return b.call(func() (string, error) { return b.provider.GenerateRecommendations(systemData, language) })
+}
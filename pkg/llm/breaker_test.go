@@ -0,0 +1,145 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type fakeProvider struct {
+	err   error
+	calls int
+}
+
+func (f *fakeProvider) GenerateSQL(userQuery string) (string, error) {
+	f.calls++
+	return "METRIC:avg(cpu_usage_pct)", f.err
+}
+
+func (f *fakeProvider) ExplainResults(userQuery, sql, results, language string) (string, error) {
+	f.calls++
+	return "explanation", f.err
+}
+
+func (f *fakeProvider) GenerateRecommendations(systemData, language string) (string, error) {
+	f.calls++
+	return "recommendations", f.err
+}
+
+func TestBreaker_OpensAfterThreshold(t *testing.T) {
+	fake := &fakeProvider{err: errors.New("connection refused")}
+	b := NewBreaker(fake, 3, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		if _, err := b.GenerateSQL("cpu"); err == nil {
+			t.Fatalf("Expected backend error on attempt %d", i+1)
+		}
+	}
+	if b.State() != BreakerOpen {
+		t.Fatalf("Expected breaker to be open, got %s", b.State())
+	}
+
+	if _, err := b.GenerateSQL("cpu"); !errors.Is(err, ErrProviderUnavailable) {
+		t.Fatalf("Expected ErrProviderUnavailable while open, got %v", err)
+	}
+	if fake.calls != 3 {
+		t.Fatalf("Expected open breaker to skip the backend, got %d calls", fake.calls)
+	}
+}
+
+func TestBreaker_HalfOpenRecovery(t *testing.T) {
+	fake := &fakeProvider{err: errors.New("timeout")}
+	b := NewBreaker(fake, 1, time.Minute)
+	now := time.Now()
+	b.now = func() time.Time { return now }
+
+	b.ExplainResults("q", "sql", "res", "")
+	if b.State() != BreakerOpen {
+		t.Fatalf("Expected breaker to be open, got %s", b.State())
+	}
+
+	now = now.Add(2 * time.Minute)
+	if b.State() != BreakerHalfOpen {
+		t.Fatalf("Expected breaker to be half-open after cooldown, got %s", b.State())
+	}
+
+	// A failed trial call re-opens the breaker immediately.
+	b.ExplainResults("q", "sql", "res", "")
+	if b.State() != BreakerOpen {
+		t.Fatalf("Expected failed trial to re-open breaker, got %s", b.State())
+	}
+
+	now = now.Add(2 * time.Minute)
+	fake.err = nil
+	if _, err := b.GenerateRecommendations("data", ""); err != nil {
+		t.Fatalf("Expected trial call to succeed, got %v", err)
+	}
+	if b.State() != BreakerClosed || b.ConsecutiveFailures() != 0 {
+		t.Fatalf("Expected breaker to close after success, got %s (%d failures)", b.State(), b.ConsecutiveFailures())
+	}
+}
+
+// panickingProvider panics on every call.
+type panickingProvider struct{ fakeProvider }
+
+func (p *panickingProvider) GenerateSQL(string) (string, error) {
+	panic("provider bug")
+}
+
+func TestBreaker_PanicCountsAsFailure(t *testing.T) {
+	fake := &fakeProvider{err: errors.New("timeout")}
+	b := NewBreaker(fake, 1, time.Minute)
+	now := time.Now()
+	b.now = func() time.Time { return now }
+	b.ExplainResults("q", "sql", "res", "")
+	now = now.Add(2 * time.Minute)
+
+	// The half-open trial panics: the panic still reaches the caller, and
+	// the breaker re-opens instead of waiting on the trial forever.
+	b.provider = &panickingProvider{}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected the provider's panic to propagate")
+			}
+		}()
+		b.GenerateSQL("cpu")
+	}()
+	if b.State() != BreakerOpen {
+		t.Fatalf("Expected a panicking trial to re-open the breaker, got %s", b.State())
+	}
+
+	now = now.Add(2 * time.Minute)
+	b.provider, fake.err = fake, nil
+	if _, err := b.GenerateSQL("cpu"); err != nil || b.State() != BreakerClosed {
+		t.Errorf("Expected the breaker to recover after the cooldown, got %v (%s)", err, b.State())
+	}
+}
+
+func TestBreaker_CancelIsNotAFailure(t *testing.T) {
+	fake := &fakeProvider{err: fmt.Errorf("request aborted: %w", context.Canceled)}
+	b := NewBreaker(fake, 1, time.Minute)
+
+	b.GenerateSQL("cpu")
+	if b.State() != BreakerClosed || b.ConsecutiveFailures() != 0 {
+		t.Fatalf("Expected a cancelled call not to count, got %s (%d failures)", b.State(), b.ConsecutiveFailures())
+	}
+
+	// A cancelled trial frees the slot for the next one.
+	now := time.Now()
+	b.now = func() time.Time { return now }
+	fake.err = errors.New("timeout")
+	b.GenerateSQL("cpu")
+	now = now.Add(2 * time.Minute)
+	fake.err = fmt.Errorf("request aborted: %w", context.Canceled)
+	b.GenerateSQL("cpu")
+	if b.State() != BreakerHalfOpen {
+		t.Fatalf("Expected a cancelled trial to leave the breaker half-open, got %s", b.State())
+	}
+	fake.err = nil
+	if _, err := b.GenerateSQL("cpu"); err != nil || b.State() != BreakerClosed {
+		t.Errorf("Expected the next trial to go through and close the breaker, got %v (%s)", err, b.State())
+	}
+}
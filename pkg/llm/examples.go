@@ -0,0 +1,87 @@
+package llm
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Example is a hand-written question and the query that answers it, used as
+// a few-shot example for GenerateSQL.
+type Example struct {
+	Question string `json:"question"`
+	Type     string `json:"type"` // "metric", "range", or "log"
+	Query    string `json:"query"`
+}
+
+// Prefixed returns the query with the METRIC:/RANGE:/LOG: prefix GenerateSQL
+// emits. A range example's query starts with its window, e.g.
+// `1h avg(cpu_usage_pct)`.
+func (e Example) Prefixed() string {
+	switch e.Type {
+	case "log":
+		return "LOG:" + e.Query
+	case "range":
+		return "RANGE:" + e.Query
+	}
+	return "METRIC:" + e.Query
+}
+
+// ParseExample builds an Example from a question and a query carrying the
+// METRIC:/RANGE:/LOG: prefix GenerateSQL emits, as stored in the RL database. It
+// reports false for a query without a recognized prefix.
+func ParseExample(question, prefixed string) (Example, bool) {
+	query := strings.TrimSpace(prefixed)
+	switch upper := strings.ToUpper(query); {
+	case strings.HasPrefix(upper, "METRIC:"):
+		return Example{Question: question, Type: "metric", Query: strings.TrimSpace(query[len("METRIC:"):])}, true
+	case strings.HasPrefix(upper, "RANGE:"):
+		return Example{Question: question, Type: "range", Query: strings.TrimSpace(query[len("RANGE:"):])}, true
+	case strings.HasPrefix(upper, "LOG:"):
+		return Example{Question: question, Type: "log", Query: strings.TrimSpace(query[len("LOG:"):])}, true
+	}
+	return Example{}, false
+}
+
+// LoadExamples reads a JSON or YAML list of examples from path and checks
+// that every entry is complete.
+func LoadExamples(path string) ([]Example, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// YAML is a superset of JSON, so one decoder handles both.
+	var examples []Example
+	if err := yaml.Unmarshal(data, &examples); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	for i, e := range examples {
+		if strings.TrimSpace(e.Question) == "" || strings.TrimSpace(e.Query) == "" {
+			return nil, fmt.Errorf("%s: example %d needs both a question and a query", path, i+1)
+		}
+		if e.Type != "metric" && e.Type != "range" && e.Type != "log" {
+			return nil, fmt.Errorf("%s: example %d has type %q, expected \"metric\", \"range\", or \"log\"", path, i+1, e.Type)
+		}
+	}
+	return examples, nil
+}
+
+// WithExamples appends examples to userQuery as few-shot guidance for
+// GenerateSQL, in the order given, so callers put the most relevant first.
+func WithExamples(userQuery string, examples []Example) string {
+	if len(examples) == 0 {
+		return userQuery
+	}
+	var b strings.Builder
+	b.WriteString(userQuery)
+	b.WriteString("\n(Examples of good queries for similar questions:")
+	for _, e := range examples {
+		fmt.Fprintf(&b, "\n- %q: `%s`", e.Question, e.Prefixed())
+	}
+	b.WriteString(")")
+	return b.String()
+}
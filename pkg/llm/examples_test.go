@@ -0,0 +1,69 @@
+package llm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadExamples(t *testing.T) {
+	dir := t.TempDir()
+
+	yamlPath := filepath.Join(dir, "examples.yaml")
+	os.WriteFile(yamlPath, []byte(`
+- question: Is Docker using too much memory?
+  type: metric
+  query: sum(process_memory_mb{process_name=~"(?i)docker"})
+- question: Wi-Fi errors
+  type: log
+  query: processName:~"(?i)wifi" AND messageType:error
+`), 0644)
+	examples, err := LoadExamples(yamlPath)
+	if err != nil {
+		t.Fatalf("Failed to load YAML examples: %v", err)
+	}
+	if len(examples) != 2 || examples[1].Prefixed() != `LOG:processName:~"(?i)wifi" AND messageType:error` {
+		t.Errorf("Unexpected examples: %+v", examples)
+	}
+
+	jsonPath := filepath.Join(dir, "examples.json")
+	os.WriteFile(jsonPath, []byte(`[{"question": "CPU", "type": "metric", "query": "avg(cpu_usage_pct)"}]`), 0644)
+	if examples, err := LoadExamples(jsonPath); err != nil || len(examples) != 1 {
+		t.Errorf("Expected one JSON example, got %+v (err %v)", examples, err)
+	}
+
+	badPath := filepath.Join(dir, "bad.json")
+	os.WriteFile(badPath, []byte(`[{"question": "CPU", "type": "sql", "query": "SELECT 1"}]`), 0644)
+	if _, err := LoadExamples(badPath); err == nil || !strings.Contains(err.Error(), "example 1") {
+		t.Errorf("Expected a validation error for example 1, got %v", err)
+	}
+}
+
+func TestWithExamples(t *testing.T) {
+	if got := WithExamples("cpu", nil); got != "cpu" {
+		t.Errorf("Expected no examples to leave the query unchanged, got %q", got)
+	}
+	got := WithExamples("cpu", []Example{{Question: "CPU now", Type: "metric", Query: "avg(cpu_usage_pct)"}})
+	if !strings.Contains(got, "`METRIC:avg(cpu_usage_pct)`") || !strings.HasPrefix(got, "cpu\n") {
+		t.Errorf("Unexpected prompt: %q", got)
+	}
+}
+
+func TestParseExample(t *testing.T) {
+	e, ok := ParseExample("cpu usage", "METRIC: avg(cpu_usage_pct)")
+	if !ok || e.Type != "metric" || e.Query != "avg(cpu_usage_pct)" || e.Prefixed() != "METRIC:avg(cpu_usage_pct)" {
+		t.Errorf("Unexpected metric example: %+v", e)
+	}
+	e, ok = ParseExample("chrome logs", "log:processName:chrome")
+	if !ok || e.Type != "log" || e.Query != "processName:chrome" {
+		t.Errorf("Unexpected log example: %+v", e)
+	}
+	e, ok = ParseExample("cpu trend", "RANGE:6h avg(cpu_usage_pct)")
+	if !ok || e.Type != "range" || e.Query != "6h avg(cpu_usage_pct)" || e.Prefixed() != "RANGE:6h avg(cpu_usage_pct)" {
+		t.Errorf("Unexpected range example: %+v", e)
+	}
+	if _, ok := ParseExample("cpu", "avg(cpu_usage_pct)"); ok {
+		t.Error("Expected a query without a prefix to be rejected")
+	}
+}
@@ -1,13 +1,105 @@
 package llm
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
 // Provider defines the interface for an LLM provider (e.g. Gemini, Ollama).
 type Provider interface {
 	// GenerateSQL translates a natural language query into a SQL query for the zenith.db.
 	GenerateSQL(userQuery string) (string, error)
 
 	// ExplainResults explains the results of a SQL query in natural language.
-	ExplainResults(userQuery, sql, results string) (string, error)
+	// language selects the response language; empty means English.
+	ExplainResults(userQuery, sql, results, language string) (string, error)
 
 	// GenerateRecommendations analyzes recent system data and provides performance improvement recommendations.
-	GenerateRecommendations(systemData string) (string, error)
+	// language selects the response language; empty means English.
+	GenerateRecommendations(systemData, language string) (string, error)
+}
+
+// StreamExplainer is implemented by providers that can stream
+// ExplainResults output token by token.
+type StreamExplainer interface {
+	ExplainResultsStream(userQuery, sql, results, language string, onToken func(string)) (string, error)
+}
+
+// ExplainStream calls p's ExplainResultsStream if it has one. Otherwise it
+// falls back to ExplainResults and passes the whole answer to onToken at
+// once.
+func ExplainStream(p Provider, userQuery, sql, results, language string, onToken func(string)) (string, error) {
+	if s, ok := p.(StreamExplainer); ok {
+		return s.ExplainResultsStream(userQuery, sql, results, language, onToken)
+	}
+	res, err := p.ExplainResults(userQuery, sql, results, language)
+	if err == nil && res != "" && onToken != nil {
+		onToken(res)
+	}
+	return res, err
+}
+
+// ErrProviderNotConfigured is returned when the server is running in
+// collection-only mode because the LLM provider failed to initialize.
+var ErrProviderNotConfigured = errors.New("LLM provider not configured")
+
+// Pinger is implemented by providers that can cheaply check that their
+// backend is reachable before the server starts taking queries.
+type Pinger interface {
+	Ping() error
+}
+
+// DefaultLanguage is the language answers are written in when none is requested.
+const DefaultLanguage = "English"
+
+// LanguageDirective returns the prompt line that asks the model to answer in
+// language. Query text, metric names, and process names are left untouched so
+// the answer still matches what was executed.
+func LanguageDirective(language string) string {
+	language = strings.TrimSpace(language)
+	if language == "" {
+		language = DefaultLanguage
+	}
+	return fmt.Sprintf("Respond in %s. Keep metric names, process names, and query syntax exactly as written.", language)
+}
+
+// Temperatures sets the sampling temperature for each kind of call. Query
+// generation should be near-deterministic so retries converge, while
+// recommendations benefit from some variety.
+type Temperatures struct {
+	GenerateSQL float32
+	Explain     float32
+	Recommend   float32
+}
+
+// DefaultTemperatures are used by providers unless configured otherwise.
+var DefaultTemperatures = Temperatures{GenerateSQL: 0.1, Explain: 0.3, Recommend: 0.7}
+
+// QueryHint pins query generation to a metric and/or label the user already
+// knows they want, for questions the model would otherwise have to guess at
+// (e.g. "memory usage": system-wide or per-process).
+type QueryHint struct {
+	Metric string `json:"metric,omitempty"`
+	Label  string `json:"label,omitempty"`
+}
+
+// WithHint appends hint to userQuery as a constraint for GenerateSQL. A nil
+// or empty hint leaves the query unchanged.
+func WithHint(userQuery string, hint *QueryHint) string {
+	if hint == nil {
+		return userQuery
+	}
+	metric, label := strings.TrimSpace(hint.Metric), strings.TrimSpace(hint.Label)
+	var parts []string
+	if metric != "" {
+		parts = append(parts, fmt.Sprintf("you MUST use the metric `%s` and no other", metric))
+	}
+	if label != "" {
+		parts = append(parts, fmt.Sprintf("filter or group by the label `%s`", label))
+	}
+	if len(parts) == 0 {
+		return userQuery
+	}
+	return fmt.Sprintf("%s\n(Hint from the user: %s.)", userQuery, strings.Join(parts, "; "))
 }
@@ -0,0 +1,46 @@
+package llm
+
+import "testing"
+
+func TestWithHint(t *testing.T) {
+	if got := WithHint("memory usage", nil); got != "memory usage" {
+		t.Errorf("Expected nil hint to leave the query unchanged, got %q", got)
+	}
+	if got := WithHint("memory usage", &QueryHint{Metric: "  "}); got != "memory usage" {
+		t.Errorf("Expected blank hint to leave the query unchanged, got %q", got)
+	}
+
+	got := WithHint("memory usage", &QueryHint{Metric: "process_memory_mb", Label: "process_name"})
+	want := "memory usage\n(Hint from the user: you MUST use the metric `process_memory_mb` and no other; filter or group by the label `process_name`.)"
+	if got != want {
+		t.Errorf("WithHint() = %q, want %q", got, want)
+	}
+}
+
+type streamingProvider struct {
+	fakeProvider
+}
+
+func (s *streamingProvider) ExplainResultsStream(userQuery, sql, results, language string, onToken func(string)) (string, error) {
+	for _, tok := range []string{"CPU ", "is ", "fine"} {
+		onToken(tok)
+	}
+	return "CPU is fine", nil
+}
+
+func TestExplainStream(t *testing.T) {
+	var tokens []string
+	collect := func(tok string) { tokens = append(tokens, tok) }
+
+	res, err := ExplainStream(&streamingProvider{}, "q", "sql", "res", "", collect)
+	if err != nil || res != "CPU is fine" || len(tokens) != 3 {
+		t.Errorf("Expected three streamed tokens, got %q (%v, err %v)", res, tokens, err)
+	}
+
+	// Providers that can't stream deliver the whole answer as one token.
+	tokens = nil
+	res, err = ExplainStream(&fakeProvider{}, "q", "sql", "res", "", collect)
+	if err != nil || res != "explanation" || len(tokens) != 1 || tokens[0] != "explanation" {
+		t.Errorf("Expected the fallback to send one token, got %q (%v, err %v)", res, tokens, err)
+	}
+}
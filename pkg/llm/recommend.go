@@ -0,0 +1,67 @@
+package llm
+
+import (
+	"regexp"
+	"strings"
+)
+
+// minRecommendationLength is the shortest response that can carry a
+// concrete recommendation; anything shorter is a refusal or a placeholder.
+const minRecommendationLength = 60
+
+// maxBoilerplateLength is the longest reply without a list that is still
+// treated as a refusal. Longer prose may well carry advice, and a reply
+// that mentions "insufficient data" while recommending things is kept.
+const maxBoilerplateLength = 300
+
+// boilerplatePhrases mark a short reply that declines to recommend anything.
+var boilerplatePhrases = []string{
+	"no recommendations",
+	"insufficient data",
+	"not enough data",
+	"not enough information",
+	"unable to provide",
+	"cannot provide",
+	"can't provide",
+	"no specific recommendations",
+	"as an ai",
+}
+
+// recommendationItemPattern matches a bulleted or numbered list item,
+// including Markdown variants such as `**1.** Restart`, `**1. Restart**`
+// and `### 1. Restart`.
+var recommendationItemPattern = regexp.MustCompile(`(?m)^\s*(?:#{1,6}\s+)?(?:\*\*|__)?(?:[-*•+]|\d+[.)])(?:\*\*|__)?\s+\S`)
+
+// LowInformation reports whether a GenerateRecommendations response carries
+// no usable advice, and why: it is empty or very short, or it is a short
+// reply with no list of recommendations, such as a refusal. A reply with
+// list items is never low-information, whatever else it says. The reason
+// is a short phrase for logs and the RL database.
+func LowInformation(recommendations string) (bool, string) {
+	text := strings.TrimSpace(recommendations)
+	if text == "" {
+		return true, "empty response"
+	}
+	if len(text) < minRecommendationLength {
+		return true, "response too short"
+	}
+	if recommendationItemPattern.MatchString(text) || len(text) > maxBoilerplateLength {
+		return false, ""
+	}
+	lower := strings.ToLower(text)
+	for _, phrase := range boilerplatePhrases {
+		if strings.Contains(lower, phrase) {
+			return true, "declined: " + phrase
+		}
+	}
+	return true, "no list of recommendations"
+}
+
+// StrongerRecommendPrompt wraps systemData for a second GenerateRecommendations
+// attempt after a low-information response, insisting on specific advice
+// or an explicit admission that the data is too sparse.
+func StrongerRecommendPrompt(systemData string) string {
+	return "Your previous answer gave no concrete advice. Give at least 3 bulleted recommendations, " +
+		"each naming a specific process, metric value, or log message from the data below and the action to take. " +
+		"If the data really supports no recommendation, reply with exactly: insufficient data\n\n" + systemData
+}
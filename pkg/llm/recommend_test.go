@@ -0,0 +1,36 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLowInformation(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		low  bool
+	}{
+		{"empty", "  \n", true},
+		{"short", "Looks fine.", true},
+		{"boilerplate", "There is not enough data to make any recommendations about this system right now.", true},
+		{"no list", "Your system appears to be running normally and nothing stands out in the collected data.", true},
+		{"useful", "- Quit Google Chrome Helper (Renderer), which used 2.1 GB.\n- WindowServer peaks at 85% CPU; reduce transparency effects.", false},
+		{"numbered", "1. Restart mds_stores, which is at 140% CPU while indexing.\n2) Free disk space: 92% used.", false},
+		{"bold numbers", "**1.** Restart mds_stores, which is at 140% CPU while indexing.\n**2.** Free disk space: 92% used.", false},
+		{"bold items", "**1. Restart mds_stores**, which is at 140% CPU while indexing.\n**2. Free disk space**: 92% used.", false},
+		{"headings", "### 1. Restart mds_stores\nIt is at 140% CPU while indexing.\n### 2. Free disk space\nThe disk is 92% used.", false},
+		{"mixed", "There is insufficient data on disk I/O, so I cannot provide advice there. However:\n- Quit Google Chrome Helper (Renderer), which used 2.1 GB of memory.", false},
+		{"long prose", strings.Repeat("WindowServer is at 85% CPU because of the external display; lower its resolution or reduce transparency. ", 4), false},
+		{"bold heading only", "**Summary**\nI cannot provide recommendations because there is insufficient data collected so far.", true},
+	}
+	for _, tt := range tests {
+		low, reason := LowInformation(tt.text)
+		if low != tt.low {
+			t.Errorf("%s: expected low=%v, got %v (%s)", tt.name, tt.low, low, reason)
+		}
+		if low && reason == "" {
+			t.Errorf("%s: expected a reason", tt.name)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package llm
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// builtinRedactions match data that commonly identifies a user or machine.
+var builtinRedactions = []redaction{
+	{regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), "[REDACTED_EMAIL]"},
+	{regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])\.){3}(?:25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])\b`), "[REDACTED_IP]"},
+	// Full or "::"-compressed IPv6. Times like 12:30:45 have too few groups,
+	// and C++ names like Foo::add don't start with a hex group.
+	{regexp.MustCompile(`(?i)\b(?:[0-9a-f]{1,4}:){7}[0-9a-f]{1,4}\b|\b(?:[0-9a-f]{1,4}:){1,6}:(?:[0-9a-f]{1,4}:){0,5}[0-9a-f]{1,4}\b`), "[REDACTED_IP]"},
+}
+
+type redaction struct {
+	re   *regexp.Regexp
+	repl string
+}
+
+// Redactor masks sensitive data in text before it is put into a prompt. A
+// nil *Redactor leaves text unchanged.
+type Redactor struct {
+	rules []redaction
+}
+
+// NewRedactor builds a Redactor from the built-in email and IP address
+// rules (when builtins is set) plus patterns, each a regular expression
+// whose matches are replaced with [REDACTED]. It returns nil when there is
+// nothing to redact.
+func NewRedactor(builtins bool, patterns []string) (*Redactor, error) {
+	var rules []redaction
+	if builtins {
+		rules = append(rules, builtinRedactions...)
+	}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %v", p, err)
+		}
+		rules = append(rules, redaction{re, "[REDACTED]"})
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	return &Redactor{rules: rules}, nil
+}
+
+// Redact returns s with every match replaced, and the number of matches.
+func (r *Redactor) Redact(s string) (string, int) {
+	if r == nil {
+		return s, 0
+	}
+	n := 0
+	for _, rule := range r.rules {
+		s = rule.re.ReplaceAllStringFunc(s, func(string) string {
+			n++
+			return rule.repl
+		})
+	}
+	return s, n
+}
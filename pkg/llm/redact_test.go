@@ -0,0 +1,35 @@
+package llm
+
+import "testing"
+
+func TestRedactor(t *testing.T) {
+	r, err := NewRedactor(true, []string{`token=[A-Za-z0-9]+`, `(?i)host-[a-z0-9]+`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := "sshd: accepted key for alice@example.com from 192.168.1.20 port 22 at 12:30:45\n" +
+		"curl https://api.example.com?token=abc123 from fe80::1c2b:3aff:fe4d:5e6f on HOST-build7\n" +
+		"Foo::add() in memory_used_mb 2048.5"
+	want := "sshd: accepted key for [REDACTED_EMAIL] from [REDACTED_IP] port 22 at 12:30:45\n" +
+		"curl https://api.example.com?[REDACTED] from [REDACTED_IP] on [REDACTED]\n" +
+		"Foo::add() in memory_used_mb 2048.5"
+	got, n := r.Redact(in)
+	if got != want {
+		t.Errorf("Unexpected redaction\n got: %q\nwant: %q", got, want)
+	}
+	if n != 5 {
+		t.Errorf("Expected 5 matches, got %d", n)
+	}
+
+	if _, err := NewRedactor(false, []string{"("}); err == nil {
+		t.Error("Expected an invalid pattern to be rejected")
+	}
+	none, err := NewRedactor(false, nil)
+	if err != nil || none != nil {
+		t.Errorf("Expected no redactor without rules, got %v, %v", none, err)
+	}
+	if s, n := none.Redact("alice@example.com"); s != "alice@example.com" || n != 0 {
+		t.Errorf("Expected a nil redactor to leave text alone, got %q (%d)", s, n)
+	}
+}
@@ -0,0 +1,72 @@
+package llm
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SchemaDescriber is implemented by providers whose query-generation prompt
+// can be inspected for the metric names it advertises.
+type SchemaDescriber interface {
+	SchemaPrompt() string
+}
+
+// SchemaDrift compares the metric names mentioned in prompt against the
+// names the collectors emit. missing are emitted metrics the prompt never
+// mentions; unknown are metric-looking names in the prompt that no collector
+// emits. A name counts as metric-looking when it shares its first
+// underscore-separated segment with an emitted metric (e.g. "process_").
+func SchemaDrift(prompt string, emitted []string) (missing, unknown []string) {
+	known := make(map[string]bool, len(emitted))
+	prefixes := make(map[string]bool)
+	for _, name := range emitted {
+		known[name] = true
+		if i := strings.Index(name, "_"); i > 0 {
+			prefixes[regexp.QuoteMeta(name[:i])] = true
+		}
+	}
+	if len(known) == 0 {
+		return nil, nil
+	}
+
+	alts := make([]string, 0, len(prefixes))
+	for p := range prefixes {
+		alts = append(alts, p)
+	}
+	sort.Strings(alts)
+	re := regexp.MustCompile(`\b(?:` + strings.Join(alts, "|") + `)_[a-z0-9_]+\b`)
+
+	mentioned := make(map[string]bool)
+	for _, tok := range re.FindAllString(prompt, -1) {
+		mentioned[tok] = true
+	}
+
+	for _, name := range emitted {
+		if !mentioned[name] {
+			missing = append(missing, name)
+		}
+	}
+	for tok := range mentioned {
+		// Label names like process_name share a metric prefix but aren't metrics.
+		if !known[tok] && !strings.HasSuffix(tok, "_name") {
+			unknown = append(unknown, tok)
+		}
+	}
+	sort.Strings(unknown)
+	return missing, unknown
+}
+
+// CounterGuidance renders a prompt rule naming the cumulative counter
+// metrics and telling the model to wrap them in increase() for questions
+// about a time window. It returns "" when there are no counters.
+func CounterGuidance(counters []string) string {
+	if len(counters) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Counters are cumulative totals that only grow: %s. "+
+		"For questions about a time window (\"in the last hour\", \"today\", \"recently\") use `increase(metric[window])`, "+
+		"e.g. `sum(increase(%s[1h]))`; the raw value is the total since the source started counting.",
+		strings.Join(counters, ", "), counters[0])
+}
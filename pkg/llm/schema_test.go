@@ -0,0 +1,44 @@
+package llm_test
+
+import (
+	"context"
+	"testing"
+
+	"zenith/pkg/collector"
+	"zenith/pkg/gemini"
+	"zenith/pkg/llamacpp"
+	"zenith/pkg/llm"
+	"zenith/pkg/ollama"
+)
+
+func TestSchemaDrift(t *testing.T) {
+	emitted := []string{"cpu_usage_pct", "process_memory_mb", "process_cpu_pct"}
+	prompt := "Metrics: cpu_usage_pct, process_memory_mb, process_disk_mb (label `process_name`)"
+
+	missing, unknown := llm.SchemaDrift(prompt, emitted)
+	if len(missing) != 1 || missing[0] != "process_cpu_pct" {
+		t.Errorf("Expected missing [process_cpu_pct], got %v", missing)
+	}
+	if len(unknown) != 1 || unknown[0] != "process_disk_mb" {
+		t.Errorf("Expected unknown [process_disk_mb], got %v", unknown)
+	}
+}
+
+// TestProviderPrompts_NoSchemaDrift guards against adding a collector metric
+// without telling the LLMs about it, or advertising one that isn't collected.
+func TestProviderPrompts_NoSchemaDrift(t *testing.T) {
+	providers := map[string]llm.SchemaDescriber{
+		"gemini":   &gemini.Client{Ctx: context.Background()},
+		"ollama":   ollama.NewClient("http://localhost:11434", "test"),
+		"llamacpp": llamacpp.NewClient("http://localhost:8081"),
+	}
+	for name, p := range providers {
+		missing, unknown := llm.SchemaDrift(p.SchemaPrompt(), collector.MetricNames)
+		if len(missing) > 0 {
+			t.Errorf("%s prompt is missing collected metrics: %v", name, missing)
+		}
+		if len(unknown) > 0 {
+			t.Errorf("%s prompt advertises metrics no collector emits: %v", name, unknown)
+		}
+	}
+}
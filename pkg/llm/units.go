@@ -0,0 +1,133 @@
+package llm
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Binary multiples, matching how the collectors derive MB from bytes.
+var sizeFactors = map[string]float64{
+	"b": 1,
+	"k": 1 << 10, "kb": 1 << 10, "ki": 1 << 10, "kib": 1 << 10,
+	"m": 1 << 20, "mb": 1 << 20, "mi": 1 << 20, "mib": 1 << 20,
+	"g": 1 << 30, "gb": 1 << 30, "gi": 1 << 30, "gib": 1 << 30,
+	"t": 1 << 40, "tb": 1 << 40, "ti": 1 << 40, "tib": 1 << 40,
+}
+
+var durationFactors = map[string]float64{
+	"ms": 1, "s": 1000, "sec": 1000, "min": 60 * 1000, "h": 60 * 60 * 1000,
+}
+
+// unitFactors gives, for each stored unit whose thresholds can be
+// converted, the size of one stored unit and the suffixes it converts from.
+var unitFactors = map[string]struct {
+	base     float64
+	suffixes map[string]float64
+}{
+	"bytes": {1, sizeFactors},
+	"MB":    {1 << 20, sizeFactors},
+	"ms":    {1, durationFactors},
+}
+
+// thresholdPattern matches a comparison against a number with a unit suffix,
+// e.g. "> 2GB" or ">= 1.5 GiB".
+var thresholdPattern = regexp.MustCompile(`(?i)(>=|<=|==|!=|>|<)(\s*)([0-9]+(?:\.[0-9]+)?)\s*(kib|mib|gib|tib|kb|mb|gb|tb|ki|mi|gi|ti|ms|min|sec|[kmgtbsh])\b`)
+
+// UnitGuidance renders a prompt rule telling the model to write thresholds
+// in each metric's stored unit. units maps metric names to units; only
+// metrics measured in MB, bytes, or ms are mentioned. It returns "" when
+// there are none.
+func UnitGuidance(units map[string]string) string {
+	byUnit := make(map[string][]string)
+	for name, unit := range units {
+		if _, ok := unitFactors[unit]; ok {
+			byUnit[unit] = append(byUnit[unit], name)
+		}
+	}
+	if len(byUnit) == 0 {
+		return ""
+	}
+
+	examples := map[string]string{
+		"MB":    "MB (2GB = 2048, 512KB = 0.5)",
+		"bytes": "bytes (1GB = 1073741824, 1MB = 1048576)",
+		"ms":    "milliseconds (2s = 2000)",
+	}
+	var parts []string
+	for _, unit := range []string{"MB", "bytes", "ms"} {
+		names := byUnit[unit]
+		if len(names) == 0 {
+			continue
+		}
+		sort.Strings(names)
+		parts = append(parts, fmt.Sprintf("%s %s in %s", strings.Join(names, ", "), plural(len(names), "is", "are"), examples[unit]))
+	}
+	return "Compare thresholds in the metric's stored unit, as a plain number with no unit suffix: " +
+		strings.Join(parts, "; ") + ". For example \"processes using more than 2GB\" is `process_memory_mb > 2048`."
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+// NormalizeUnits rewrites thresholds in a METRIC: or RANGE: query that carry
+// a unit suffix into the stored unit of the metric being compared, so
+// `process_memory_mb > 2GB` becomes `process_memory_mb > 2048`. The metric is
+// the last known name before the comparison. Log queries, bare numbers, and
+// suffixes that don't fit the metric's unit are left alone.
+func NormalizeUnits(query string, units map[string]string) string {
+	upper := strings.ToUpper(strings.TrimSpace(query))
+	if !strings.HasPrefix(upper, "METRIC:") && !strings.HasPrefix(upper, "RANGE:") {
+		return query
+	}
+
+	matches := thresholdPattern.FindAllStringSubmatchIndex(query, -1)
+	if len(matches) == 0 {
+		return query
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		metric := lastMetricBefore(query[:m[0]], units)
+		conv, ok := unitFactors[units[metric]]
+		if !ok {
+			continue
+		}
+		factor, ok := conv.suffixes[strings.ToLower(query[m[8]:m[9]])]
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseFloat(query[m[6]:m[7]], 64)
+		if err != nil {
+			continue
+		}
+		b.WriteString(query[last:m[0]])
+		b.WriteString(query[m[2]:m[3]])
+		b.WriteString(query[m[4]:m[5]])
+		b.WriteString(strconv.FormatFloat(n*factor/conv.base, 'f', -1, 64))
+		last = m[1]
+	}
+	b.WriteString(query[last:])
+	return b.String()
+}
+
+// metricNamePattern matches identifiers that could be metric names.
+var metricNamePattern = regexp.MustCompile(`[a-zA-Z_][a-zA-Z0-9_]*`)
+
+// lastMetricBefore returns the last name in s that units knows, or "".
+func lastMetricBefore(s string, units map[string]string) string {
+	names := metricNamePattern.FindAllString(s, -1)
+	for i := len(names) - 1; i >= 0; i-- {
+		if _, ok := units[names[i]]; ok {
+			return names[i]
+		}
+	}
+	return ""
+}
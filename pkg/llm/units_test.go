@@ -0,0 +1,61 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+)
+
+var testUnits = map[string]string{
+	"process_memory_mb":            "MB",
+	"memory_used_mb":               "MB",
+	"srum_app_bytes_written_total": "bytes",
+	"srum_app_duration_ms":         "ms",
+	"process_cpu_pct":              "percent",
+}
+
+func TestNormalizeUnits(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`METRIC:process_memory_mb > 2GB`, `METRIC:process_memory_mb > 2048`},
+		{`METRIC:process_memory_mb{process_name=~"(?i)chrome"} >= 1.5 GiB`, `METRIC:process_memory_mb{process_name=~"(?i)chrome"} >= 1536`},
+		{`METRIC:process_memory_mb > 500MB`, `METRIC:process_memory_mb > 500`},
+		{`METRIC:process_memory_mb < 512KB`, `METRIC:process_memory_mb < 0.5`},
+		{`METRIC:sum(memory_used_mb) > 8G`, `METRIC:sum(memory_used_mb) > 8192`},
+		{`METRIC:topk(5, srum_app_bytes_written_total) > 1MB`, `METRIC:topk(5, srum_app_bytes_written_total) > 1048576`},
+		{`METRIC:srum_app_bytes_written_total > 64kb`, `METRIC:srum_app_bytes_written_total > 65536`},
+		{`METRIC:srum_app_duration_ms > 2s`, `METRIC:srum_app_duration_ms > 2000`},
+		{`METRIC:process_memory_mb > 2048 and process_cpu_pct > 50`, `METRIC:process_memory_mb > 2048 and process_cpu_pct > 50`},
+		{`METRIC:process_memory_mb > 1GB or process_cpu_pct > 90`, `METRIC:process_memory_mb > 1024 or process_cpu_pct > 90`},
+		{`RANGE:6h process_memory_mb > 2GB`, `RANGE:6h process_memory_mb > 2048`},
+		// A size suffix on a percentage is not something we can convert.
+		{`METRIC:process_cpu_pct > 2G`, `METRIC:process_cpu_pct > 2G`},
+		{`LOG:eventMessage:"more than 2GB"`, `LOG:eventMessage:"more than 2GB"`},
+	}
+	for _, tt := range tests {
+		if got := NormalizeUnits(tt.query, testUnits); got != tt.want {
+			t.Errorf("NormalizeUnits(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestUnitGuidance(t *testing.T) {
+	g := UnitGuidance(testUnits)
+	for _, want := range []string{
+		"memory_used_mb, process_memory_mb are in MB (2GB = 2048",
+		"srum_app_bytes_written_total is in bytes",
+		"srum_app_duration_ms is in milliseconds",
+		"`process_memory_mb > 2048`",
+	} {
+		if !strings.Contains(g, want) {
+			t.Errorf("Expected guidance to contain %q, got %q", want, g)
+		}
+	}
+	if strings.Contains(g, "process_cpu_pct") {
+		t.Errorf("Expected percentages to be left out, got %q", g)
+	}
+	if g := UnitGuidance(map[string]string{"process_cpu_pct": "percent"}); g != "" {
+		t.Errorf("Expected no guidance without convertible units, got %q", g)
+	}
+}
@@ -8,18 +8,29 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"zenith/pkg/collector"
+	"zenith/pkg/llm"
 )
 
 type Client struct {
-	BaseURL string
-	Model   string
-	Client  *http.Client
+	BaseURL      string
+	Model        string
+	Client       *http.Client
+	Temperatures llm.Temperatures
 }
 
 type GenerateRequest struct {
 	Model  string `json:"model"`
 	Prompt string `json:"prompt"`
 	Stream bool   `json:"stream"`
+
+	Options *GenerateOptions `json:"options,omitempty"`
+}
+
+// GenerateOptions are the model parameters Zenith sets per request.
+type GenerateOptions struct {
+	Temperature float32 `json:"temperature"`
 }
 
 type GenerateResponse struct {
@@ -34,17 +45,49 @@ func NewClient(baseURL, model string) *Client {
 	}
 
 	return &Client{
-		BaseURL: baseURL,
-		Model:   model,
-		Client:  &http.Client{Timeout: 300 * time.Second},
+		BaseURL:      baseURL,
+		Model:        model,
+		Client:       &http.Client{Timeout: 300 * time.Second},
+		Temperatures: llm.DefaultTemperatures,
+	}
+}
+
+// Ping checks that the Ollama server is up and has the configured model pulled.
+func (c *Client) Ping() error {
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(c.BaseURL + "/api/tags")
+	if err != nil {
+		return fmt.Errorf("failed to connect to Ollama at %s: %v", c.BaseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("ollama API error: %s", string(body))
+	}
+
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return err
+	}
+	for _, m := range tags.Models {
+		if m.Name == c.Model || strings.TrimSuffix(m.Name, ":latest") == c.Model {
+			return nil
+		}
 	}
+	return fmt.Errorf("ollama model %q is not pulled (run: ollama pull %s)", c.Model, c.Model)
 }
 
-func (c *Client) generate(prompt string) (string, error) {
+func (c *Client) generate(prompt string, temperature float32) (string, error) {
 	reqBody := GenerateRequest{
-		Model:  c.Model,
-		Prompt: prompt,
-		Stream: false,
+		Model:   c.Model,
+		Prompt:  prompt,
+		Stream:  false,
+		Options: &GenerateOptions{Temperature: temperature},
 	}
 
 	data, err := json.Marshal(reqBody)
@@ -75,41 +118,120 @@ func (c *Client) generate(prompt string) (string, error) {
 	return genResp.Response, nil
 }
 
-func (c *Client) GenerateSQL(userQuery string) (string, error) {
-	prompt := fmt.Sprintf("You are Zenith, an AI expert in system performance. "+
+// generateStream is generate with Stream set: Ollama sends one
+// GenerateResponse per line as tokens are produced. onToken is called with
+// each token as it arrives, and the full text is returned at the end.
+func (c *Client) generateStream(prompt string, temperature float32, onToken func(string)) (string, error) {
+	reqBody := GenerateRequest{
+		Model:   c.Model,
+		Prompt:  prompt,
+		Stream:  true,
+		Options: &GenerateOptions{Temperature: temperature},
+	}
+
+	data, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.Client.Post(c.BaseURL+"/api/generate", "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to Ollama: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("ollama API error: %s", string(body))
+	}
+
+	var full strings.Builder
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var chunk GenerateResponse
+		if err := decoder.Decode(&chunk); err == io.EOF {
+			break
+		} else if err != nil {
+			return "", err
+		}
+		if chunk.Error != "" {
+			return "", fmt.Errorf("ollama error: %s", chunk.Error)
+		}
+		if chunk.Response != "" {
+			full.WriteString(chunk.Response)
+			if onToken != nil {
+				onToken(chunk.Response)
+			}
+		}
+		if chunk.Done {
+			break
+		}
+	}
+	return full.String(), nil
+}
+
+// SchemaPrompt returns the query-generation prompt without a user query,
+// so the metric names it advertises can be checked against the collectors.
+func (c *Client) SchemaPrompt() string {
+	return sqlPrompt("")
+}
+
+func sqlPrompt(userQuery string) string {
+	return fmt.Sprintf("You are Zenith, an AI expert in system performance. "+
 		"You have access to two databases:\n"+
 		"1. VictoriaMetrics (Metrics): Query using MetricsQL (PromQL-compatible).\n"+
-		"   System-wide (NO label filter needed): cpu_usage_pct, memory_used_mb\n"+
-		"   Per-process (use label `process_name`): process_cpu_pct, process_memory_mb\n"+
+		"   System-wide (NO label filter needed): cpu_usage_pct, memory_used_mb, memory_free_mb, system_open_fds\n"+
+		"   Disk I/O (macOS only; label `disk`, e.g. `disk0`): disk_read_kb, disk_write_kb, the KB per second read and written per disk, averaged over the collection interval. Sum over `disk` for the machine total\n"+
+		"   Thermal (macOS only, when enabled; NO label filter needed): cpu_temperature_c (CPU die temperature in Celsius), fan_rpm (label `fan` per fan). High temperature with high fan_rpm under load suggests thermal throttling\n"+
+		"   Services (Linux with systemd): service_failed_count (NO label filter needed; number of failed services), service_active (label `unit`, e.g. `nginx.service`; 1 if running, 0 otherwise). For \"are my services healthy?\" check service_failed_count, then service_active == 0 to name them\n"+
+		"   Log volume (label `level`, the log messageType): log_event_count is how many log entries arrived per collection cycle. For trends such as \"is my error rate climbing?\" query log_event_count{level=~\"(?i)error|fault|critical\"} over time rather than counting logs\n"+
+		"   CPU spread within a collection cycle (only when sub-sampling is enabled): cpu_usage_pct_min, cpu_usage_pct_max, cpu_usage_pct_p95. Use cpu_usage_pct_max for questions about spikes\n"+
+		"   Per-process (use label `process_name`): process_cpu_pct, process_cpu_pct_normalized, process_memory_mb, process_open_fds\n"+
+		"   process_open_fds is open file descriptors (handles on Windows); a steadily rising value suggests a leak\n"+
+		"   process_cpu_pct is percent of ONE core and can exceed 100 on multi-core systems; process_cpu_pct_normalized is the 0-100 share of total machine CPU\n"+
 		"   SRUM app (use labels `app_name`, `user_name`): srum_app_cycle_time_total, srum_app_bytes_read_total, srum_app_bytes_written_total, srum_app_duration_ms, srum_app_foreground_cycle_time_total, srum_app_background_cycle_time_total\n"+
 		"   SRUM network (NO label needed): srum_network_bytes_sent_total, srum_network_bytes_received_total\n"+
+		"   %s\n"+
+		"   %s\n"+
 		"2. VictoriaLogs (Logs): Query using LogsQL (Syntax: `field:value`). Fields: processName, subsystem, category, messageType, eventMessage.\n\n"+
-		"Based on the user query, provide EXACTLY ONE database query prefixed with 'METRIC:' or 'LOG:'. Do NOT include explanation or markdown.\n\n"+
+		"Based on the user query, provide EXACTLY ONE database query prefixed with 'METRIC:', 'RANGE:', or 'LOG:'. Do NOT include explanation or markdown.\n\n"+
 		"Rules for Queries:\n"+
 		"- Return ONLY ONE line. Do NOT truncate the query or cut off metric names.\n"+
 		"- NEVER add a label filter unless the user asks about a specific app or process.\n"+
 		"- NEVER use placeholder label values like 'your_process_name'. Omit the label entirely.\n"+
 		"- NEVER combine metrics and logs in the same query. Choose ONE.\n"+
+		"- For trends or history over a period (\"CPU over the last hour\", \"how has memory changed today\"), use a RANGE: query: the window, a space, then MetricsQL, e.g. `RANGE:1h avg(cpu_usage_pct)`. It returns the values across the window rather than one current value. Use METRIC: for current values and rankings.\n"+
 		"- SRUM data is exclusively stored as METRICS, never LOGS.\n"+
 		"- NEVER compare metrics to strings. To check for existence, just use `metric_name > 0`.\n"+
 		"- MetricsQL regex uses `=~`, e.g., `process_cpu_pct{process_name=~\"(?i)ollama\"}`.\n"+
 		"- MetricsQL uses lowercase logical operators: `and`, `or`, `unless`.\n"+
 		"- MetricsQL NEVER uses SQL syntax like `ORDER BY` or `LIMIT`. To rank results, use `topk(n, metric)`.\n"+
+		"- Metrics carry a `host` label naming the machine that reported them. Only group by it when the user asks about hosts, machines, or the fleet: compare with `sort_desc(avg by (host) (avg_over_time(cpu_usage_pct[1h])))`, and for \"which host is busiest\" use `topk(1, avg by (host) (avg_over_time(cpu_usage_pct[1h])))`.\n"+
 		"- LogsQL uses `:` for equality, NEVER `=` or `==`.\n"+
 		"- LogsQL NEVER uses comparison operators like `>`, `<`, `>=`, `<=`. Use `:` for all filters.\n"+
 		"- LogsQL NEVER uses time-related keywords in the query string (e.g., `timestamp`, `@timestamp`, `now`, `24h`, `1d`).\n"+
-		"- NEVER use square brackets `[]` for filters or grouping in LogsQL.\n\n"+
+		"- NEVER use square brackets `[]` for filters or grouping in LogsQL.\n"+
+		"- To find logs by process or app name, use a case-insensitive regex substring match `processName:~\"(?i)name\"`. An exact `processName:\"chrome\"` will NOT match `Google Chrome`.\n\n"+
 		"Example 'System performance': `METRIC:avg(cpu_usage_pct)`\n"+
 		"Example 'Memory': `METRIC:avg(memory_used_mb)`\n"+
 		"Example 'Process CPU': `METRIC:topk(5, process_cpu_pct)`\n"+
+		"Example 'Which process is leaking file descriptors': `METRIC:topk(5, delta(process_open_fds[1h]))`\n"+
 		"Example 'Any SRUM data': `METRIC:srum_app_bytes_read_total > 0`\n"+
 		"Example 'Most disk IO apps': `METRIC:topk(10, srum_app_bytes_written_total)`\n"+
 		"Example 'Most CPU apps (SRUM)': `METRIC:topk(10, srum_app_cycle_time_total)`\n"+
-		"Example LogsQL: `LOG:eventMessage:\"error\" AND processName:\"wifid\"`\n\n"+
+		"Example 'Network bytes sent in the last hour': `METRIC:sum(increase(srum_network_bytes_sent_total[1h]))`\n"+
+		"Example 'Compare memory across all hosts': `METRIC:sort_desc(avg by (host) (avg_over_time(memory_used_mb[1h])))`\n"+
+		"Example 'CPU over the last hour': `RANGE:1h avg(cpu_usage_pct)`\n"+
+		"Example LogsQL: `LOG:eventMessage:\"error\" AND processName:\"wifid\"`\n"+
+		"Example 'Logs from chrome': `LOG:processName:~\"(?i)chrome\"`\n\n"+
 		"Query: %s\n\n"+
-		"Response:", userQuery)
+		"Response:", llm.CounterGuidance(collector.CounterNames), llm.UnitGuidance(collector.MetricUnits), userQuery)
+}
+
+func (c *Client) GenerateSQL(userQuery string) (string, error) {
+	prompt := sqlPrompt(userQuery)
 
-	resp, err := c.generate(prompt)
+	resp, err := c.generate(prompt, c.Temperatures.GenerateSQL)
 	if err != nil {
 		return "", err
 	}
@@ -117,36 +239,55 @@ func (c *Client) GenerateSQL(userQuery string) (string, error) {
 	return cleanSQL(resp), nil
 }
 
-func (c *Client) ExplainResults(userQuery, sql, results string) (string, error) {
-	prompt := fmt.Sprintf("System: You are Zenith, an AI expert in system performance. "+
+func (c *Client) ExplainResults(userQuery, sql, results, language string) (string, error) {
+	return c.generate(explainPrompt(userQuery, sql, results, language), c.Temperatures.Explain)
+}
+
+// ExplainResultsStream is ExplainResults, passing each token to onToken as
+// the model produces it.
+func (c *Client) ExplainResultsStream(userQuery, sql, results, language string, onToken func(string)) (string, error) {
+	return c.generateStream(explainPrompt(userQuery, sql, results, language), c.Temperatures.Explain, onToken)
+}
+
+func explainPrompt(userQuery, sql, results, language string) string {
+	return fmt.Sprintf("System: You are Zenith, an AI expert in system performance. "+
 		"Analyze the database results below to answer the user's question. "+
 		"Rules:\n"+
 		"1. If the results are 'NO_DATA_FOUND' or empty, say 'No data found for this query'.\n"+
 		"2. If results contain metrics with value 0, explain that those apps/processes showed no activity for that metric - do NOT say 'no data found'.\n"+
 		"3. Do NOT invent names, PIDs, or values.\n"+
 		"4. Do NOT use placeholders like 'Application X'.\n"+
-		"5. Be extremely concise. If all values are 0, say so clearly.\n\n"+
+		"5. Be extremely concise. If all values are 0, say so clearly.\n"+
+		"6. process_cpu_pct is per-core and may exceed 100%% on multi-core systems (e.g. 250%% = 2.5 cores busy). This is normal; do NOT call it an error.\n"+
+		"7. %s\n\n"+
 		"User Query: %s\n"+
 		"SQL Executed: %s\n"+
 		"Database Results: %s\n\n"+
-		"Analysis:", userQuery, sql, results)
-
-	return c.generate(prompt)
+		"Analysis:", llm.LanguageDirective(language), userQuery, sql, results)
 }
 
-func (c *Client) GenerateRecommendations(systemData string) (string, error) {
-	prompt := fmt.Sprintf("System: You are Zenith, an AI expert in system performance. "+
+func (c *Client) GenerateRecommendations(systemData, language string) (string, error) {
+	prompt := fmt.Sprintf("System: You are Zenith, an AI expert in system performance on macOS and Windows. "+
 		"Based on the following recent system data, provide 3-5 concrete recommendations for performance improvement. "+
-		"Be extremely concise, focus on actionable advice, and avoid conversational filler.\n\n"+
-		"System Data:\n%s\n\nRecommendations:", systemData)
+		"Format them as a short bulleted list ordered by priority, most impactful first. "+
+		"Be extremely concise, focus on actionable advice, and avoid conversational filler. "+
+		"%s\n\n"+
+		"System Data:\n%s\n\nRecommendations:", llm.LanguageDirective(language), systemData)
+
+	resp, err := c.generate(prompt, c.Temperatures.Recommend)
+	if err != nil {
+		return "", err
+	}
 
-	return c.generate(prompt)
+	// Reasoning models (e.g. deepseek-r1) emit their chain-of-thought in
+	// <think> blocks; keep it out of what the user sees.
+	return stripThink(resp), nil
 }
 
-func cleanSQL(s string) string {
+// stripThink removes <think>...</think> blocks, and an unterminated
+// trailing <think>, from a model response.
+func stripThink(s string) string {
 	s = strings.TrimSpace(s)
-
-	// 1. Remove <think>...</think> blocks if present
 	for {
 		start := strings.Index(s, "<think>")
 		if start == -1 {
@@ -160,6 +301,12 @@ func cleanSQL(s string) string {
 		s = s[:start] + s[start+end+8:]
 		s = strings.TrimSpace(s)
 	}
+	return strings.TrimSpace(s)
+}
+
+func cleanSQL(s string) string {
+	// 1. Remove <think>...</think> blocks if present
+	s = stripThink(s)
 
 	// 2. Strip SQL line comments (-- ...)
 	lines := strings.Split(s, "\n")
@@ -180,7 +327,7 @@ func cleanSQL(s string) string {
 			continue
 		}
 		upper := strings.ToUpper(trimmed)
-		if strings.HasPrefix(upper, "METRIC:") || strings.HasPrefix(upper, "LOG:") {
+		if strings.HasPrefix(upper, "METRIC:") || strings.HasPrefix(upper, "RANGE:") || strings.HasPrefix(upper, "LOG:") {
 			selected = trimmed
 			break
 		}
@@ -201,14 +348,17 @@ func cleanSQL(s string) string {
 		return s
 	}
 
-	// Globally remove all instances of METRIC: and LOG: from the selected line
+	// Globally remove all instances of METRIC:, RANGE: and LOG: from the selected line
 	upperSelected := strings.ToUpper(selected)
 	hasLog := strings.HasPrefix(upperSelected, "LOG:")
+	hasRange := strings.HasPrefix(upperSelected, "RANGE:")
 
 	res := selected
 	reMetric := strings.NewReplacer("METRIC:", "", "metric:", "", "Metric:", "")
+	reRange := strings.NewReplacer("RANGE:", "", "range:", "", "Range:", "")
 	reLog := strings.NewReplacer("LOG:", "", "log:", "", "Log:", "")
 	res = reMetric.Replace(res)
+	res = reRange.Replace(res)
 	res = reLog.Replace(res)
 	res = strings.TrimSpace(res)
 
@@ -241,5 +391,8 @@ func cleanSQL(s string) string {
 	if hasLog {
 		return "LOG:" + res
 	}
+	if hasRange {
+		return "RANGE:" + res
+	}
 	return "METRIC:" + res
 }
@@ -1,7 +1,9 @@
 package rl
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -13,15 +15,40 @@ import (
 
 // Experience represents a single interaction with the LLM and its outcome.
 type Experience struct {
-	ID              int64
-	Timestamp       time.Time
-	Source          string // "query" or "recommend"
-	Prompt          string
-	GeneratedQuery  string
-	ExecutionResult string // Details of success or failure
-	UserFeedback    int    // 0 = none, 1 = good, -1 = bad
+	ID              int64     `json:"id"`
+	Timestamp       time.Time `json:"timestamp"`
+	Source          string    `json:"source"`           // "query", "recommend" or "report"
+	Origin          string    `json:"origin,omitempty"` // where a /query interaction's query came from; see OriginLLM
+	Prompt          string    `json:"prompt"`
+	GeneratedQuery  string    `json:"generated_query"`
+	ExecutionResult string    `json:"execution_result"` // Details of success or failure
+	UserFeedback    int       `json:"user_feedback"`    // 0 = none, 1 = good, -1 = bad, 2/-2 = automatic
 }
 
+// Automatic feedback values, kept apart from a user's 1 and -1 so the
+// dataset can tell a click from an inferred outcome.
+const (
+	// FeedbackAutoGood marks a query that executed and was explained on the
+	// first attempt.
+	FeedbackAutoGood = 2
+	// FeedbackAutoBad marks a query that needed retries or failed.
+	FeedbackAutoBad = -2
+)
+
+// Origins of a /query interaction's query, as in the server's trace:
+// "confirmed" (sent by the client), "canned" and "fallback" are the others.
+// Only queries the model wrote are used as few-shot examples, so a client
+// can't teach it arbitrary MetricsQL or LogsQL.
+const (
+	// OriginLLM marks a query the LLM generated for this request.
+	OriginLLM = "llm"
+	// OriginCache marks an earlier LLM translation reused from the cache.
+	OriginCache = "cache"
+)
+
+// ErrNotFound is returned by UpdateFeedback when no experience has the ID.
+var ErrNotFound = errors.New("experience not found")
+
 // DB handles the connection to the experience replay SQLite database.
 type DB struct {
 	sqlDB *sql.DB
@@ -47,7 +74,8 @@ func InitDB(dbPath string) (*DB, error) {
 		prompt TEXT NOT NULL,
 		generated_query TEXT,
 		execution_result TEXT,
-		user_feedback INTEGER DEFAULT 0
+		user_feedback INTEGER DEFAULT 0,
+		origin TEXT NOT NULL DEFAULT ''
 	);`
 
 	_, err = db.Exec(createTableSQL)
@@ -55,15 +83,42 @@ func InitDB(dbPath string) (*DB, error) {
 		return nil, fmt.Errorf("failed to create table: %v", err)
 	}
 
+	// Databases from before origin was recorded get the column empty, which
+	// keeps their rows out of GetSuccessfulExamples: nothing says who wrote
+	// those queries.
+	var hasOrigin int
+	err = db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('experiences') WHERE name = 'origin'`).Scan(&hasOrigin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read table schema: %v", err)
+	}
+	if hasOrigin == 0 {
+		if _, err := db.Exec(`ALTER TABLE experiences ADD COLUMN origin TEXT NOT NULL DEFAULT ''`); err != nil {
+			return nil, fmt.Errorf("failed to add origin column: %v", err)
+		}
+	}
+
+	// GetSuccessfulExamples runs on every /query; this keeps it from
+	// scanning the whole table.
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS experiences_examples ON experiences (source, user_feedback, timestamp)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create index: %v", err)
+	}
+
 	return &DB{sqlDB: db}, nil
 }
 
 // LogExperience records an LLM interaction and its immediate execution result.
 // It returns the ID of the inserted record, which can be used later for user feedback.
 func (db *DB) LogExperience(source, prompt, generatedQuery, executionResult string) (int64, error) {
+	return db.LogExperienceFrom(source, "", prompt, generatedQuery, executionResult)
+}
+
+// LogExperienceFrom is LogExperience for a query whose origin is known,
+// such as OriginLLM.
+func (db *DB) LogExperienceFrom(source, origin, prompt, generatedQuery, executionResult string) (int64, error) {
 	insertSQL := `
-	INSERT INTO experiences (source, prompt, generated_query, execution_result)
-	VALUES (?, ?, ?, ?)`
+	INSERT INTO experiences (source, origin, prompt, generated_query, execution_result)
+	VALUES (?, ?, ?, ?, ?)`
 
 	stmt, err := db.sqlDB.Prepare(insertSQL)
 	if err != nil {
@@ -71,7 +126,7 @@ func (db *DB) LogExperience(source, prompt, generatedQuery, executionResult stri
 	}
 	defer stmt.Close()
 
-	res, err := stmt.Exec(source, prompt, generatedQuery, executionResult)
+	res, err := stmt.Exec(source, origin, prompt, generatedQuery, executionResult)
 	if err != nil {
 		return 0, err
 	}
@@ -85,8 +140,13 @@ func (db *DB) LogExperience(source, prompt, generatedQuery, executionResult stri
 	return id, nil
 }
 
-// UpdateFeedback updates the user_feedback field for a specific experience ID.
+// UpdateFeedback records a user's rating, 1 (good) or -1 (bad), for a
+// specific experience ID. Any other value is rejected, so a client can't
+// pass its rating off as an automatic one.
 func (db *DB) UpdateFeedback(id int64, feedback int) error {
+	if feedback != 1 && feedback != -1 {
+		return fmt.Errorf("feedback must be 1 or -1, got %d", feedback)
+	}
 	updateSQL := `UPDATE experiences SET user_feedback = ? WHERE id = ?`
 
 	stmt, err := db.sqlDB.Prepare(updateSQL)
@@ -106,13 +166,201 @@ func (db *DB) UpdateFeedback(id int64, feedback int) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("experience ID %d not found", id)
+		return fmt.Errorf("%w: ID %d", ErrNotFound, id)
 	}
 
 	log.Printf("RL Experience Feedback Updated [ID: %d] Feedback: %d", id, feedback)
 	return nil
 }
 
+// SetAutoFeedback records an automatic feedback value (FeedbackAutoGood or
+// FeedbackAutoBad) for an experience the user hasn't rated. A later
+// UpdateFeedback replaces it.
+func (db *DB) SetAutoFeedback(id int64, feedback int) error {
+	_, err := db.sqlDB.Exec(`UPDATE experiences SET user_feedback = ? WHERE id = ? AND user_feedback = 0`, feedback, id)
+	if err != nil {
+		return fmt.Errorf("failed to set automatic feedback: %v", err)
+	}
+	return nil
+}
+
+// ListExperiences returns up to limit experiences, newest first, skipping
+// the first offset.
+func (db *DB) ListExperiences(limit, offset int) ([]Experience, error) {
+	rows, err := db.sqlDB.Query(`
+	SELECT id, timestamp, source, origin, prompt, generated_query, execution_result, user_feedback
+	FROM experiences
+	ORDER BY id DESC
+	LIMIT ? OFFSET ?`, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query experiences: %v", err)
+	}
+	defer rows.Close()
+
+	out := []Experience{}
+	for rows.Next() {
+		e, err := scanExperience(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// scanExperience reads a row selected as id, timestamp, source, origin,
+// prompt, generated_query, execution_result, user_feedback.
+func scanExperience(rows *sql.Rows) (Experience, error) {
+	var e Experience
+	var generated, result sql.NullString
+	if err := rows.Scan(&e.ID, &e.Timestamp, &e.Source, &e.Origin, &e.Prompt, &generated, &result, &e.UserFeedback); err != nil {
+		return Experience{}, fmt.Errorf("failed to read experience: %v", err)
+	}
+	e.GeneratedQuery, e.ExecutionResult = generated.String, result.String
+	return e, nil
+}
+
+// GetSuccessfulExamples returns up to limit experiences from source whose
+// query the LLM wrote (OriginLLM or OriginCache) and that executed
+// successfully and were not rated bad, rated good by a user first,
+// then automatically rated good, then most recent first. Only the best row
+// for each distinct prompt is kept, so a question asked many times doesn't
+// crowd out the others. The ranking, deduplication and limit all happen in
+// SQLite, over the experiences_examples index, so a long history isn't
+// read into memory on every call.
+func (db *DB) GetSuccessfulExamples(source string, limit int) ([]Experience, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	rows, err := db.sqlDB.Query(`
+	SELECT id, timestamp, source, origin, prompt, generated_query, execution_result, user_feedback
+	FROM (
+		SELECT *, ROW_NUMBER() OVER (PARTITION BY prompt ORDER BY rank DESC, timestamp DESC, id DESC) AS n
+		FROM (
+			SELECT *, CASE user_feedback WHEN 1 THEN 2 WHEN ? THEN 1 ELSE 0 END AS rank
+			FROM experiences
+			WHERE source = ? AND origin IN (?, ?) AND user_feedback >= 0 AND execution_result = 'Success' AND generated_query != ''
+		)
+	)
+	WHERE n = 1
+	ORDER BY rank DESC, timestamp DESC, id DESC
+	LIMIT ?`, FeedbackAutoGood, source, OriginLLM, OriginCache, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query experiences: %v", err)
+	}
+	defer rows.Close()
+
+	var out []Experience
+	for rows.Next() {
+		e, err := scanExperience(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// SourceStats counts the interactions of one kind.
+type SourceStats struct {
+	Total        int64 `json:"total"`
+	Successes    int64 `json:"successes"`
+	Failures     int64 `json:"failures"`
+	GoodFeedback int64 `json:"good_feedback"`
+	BadFeedback  int64 `json:"bad_feedback"`
+	AutoGood     int64 `json:"auto_good_feedback"`
+	AutoBad      int64 `json:"auto_bad_feedback"`
+}
+
+// ExperienceStats summarizes every recorded interaction, overall and per
+// source.
+type ExperienceStats struct {
+	SourceStats
+	BySource map[string]SourceStats `json:"by_source"`
+}
+
+// Stats counts interactions, successes, failures, and user and automatic
+// good and bad feedback, overall and per source.
+func (db *DB) Stats() (ExperienceStats, error) {
+	rows, err := db.sqlDB.Query(`
+	SELECT source,
+		COUNT(*),
+		COALESCE(SUM(execution_result = 'Success'), 0),
+		COALESCE(SUM(user_feedback = 1), 0),
+		COALESCE(SUM(user_feedback = -1), 0),
+		COALESCE(SUM(user_feedback = ?), 0),
+		COALESCE(SUM(user_feedback = ?), 0)
+	FROM experiences
+	GROUP BY source`, FeedbackAutoGood, FeedbackAutoBad)
+	if err != nil {
+		return ExperienceStats{}, fmt.Errorf("failed to query experience stats: %v", err)
+	}
+	defer rows.Close()
+
+	stats := ExperienceStats{BySource: make(map[string]SourceStats)}
+	for rows.Next() {
+		var source string
+		var s SourceStats
+		if err := rows.Scan(&source, &s.Total, &s.Successes, &s.GoodFeedback, &s.BadFeedback, &s.AutoGood, &s.AutoBad); err != nil {
+			return ExperienceStats{}, fmt.Errorf("failed to read experience stats: %v", err)
+		}
+		s.Failures = s.Total - s.Successes
+		stats.BySource[source] = s
+
+		stats.Total += s.Total
+		stats.Successes += s.Successes
+		stats.Failures += s.Failures
+		stats.GoodFeedback += s.GoodFeedback
+		stats.BadFeedback += s.BadFeedback
+		stats.AutoGood += s.AutoGood
+		stats.AutoBad += s.AutoBad
+	}
+	return stats, rows.Err()
+}
+
+// sqliteTime is how CURRENT_TIMESTAMP formats the timestamp column (UTC).
+const sqliteTime = "2006-01-02 15:04:05"
+
+// PruneOlderThan deletes experiences recorded more than d ago and returns
+// how many were removed. Rows a user rated good or bad are kept regardless
+// of age; automatic feedback doesn't protect a row.
+func (db *DB) PruneOlderThan(d time.Duration) (int64, error) {
+	cutoff := time.Now().UTC().Add(-d).Format(sqliteTime)
+	res, err := db.sqlDB.Exec(`DELETE FROM experiences WHERE user_feedback NOT IN (1, -1) AND timestamp < ?`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune experiences: %v", err)
+	}
+	return res.RowsAffected()
+}
+
+// PruneToMaxRows deletes all but the n most recent experiences and returns
+// how many were removed. Rows a user rated good or bad are kept even when
+// they fall outside the n.
+func (db *DB) PruneToMaxRows(n int) (int64, error) {
+	res, err := db.sqlDB.Exec(`
+	DELETE FROM experiences
+	WHERE user_feedback NOT IN (1, -1) AND id NOT IN (SELECT id FROM experiences ORDER BY id DESC LIMIT ?)`, n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune experiences: %v", err)
+	}
+	return res.RowsAffected()
+}
+
+// Snapshot writes a transactionally consistent copy of the database to
+// path using VACUUM INTO, so it is safe to run while the server is writing.
+// path must not already exist.
+func (db *DB) Snapshot(path string) error {
+	if _, err := db.sqlDB.Exec(`VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("failed to snapshot database: %v", err)
+	}
+	return nil
+}
+
+// Ping checks that the database is still reachable.
+func (db *DB) Ping(ctx context.Context) error {
+	return db.sqlDB.PingContext(ctx)
+}
+
 // Close closes the database connection.
 func (db *DB) Close() error {
 	if db.sqlDB != nil {
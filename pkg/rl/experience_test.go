@@ -0,0 +1,309 @@
+package rl
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDB_Snapshot(t *testing.T) {
+	dir := t.TempDir()
+	db, err := InitDB(filepath.Join(dir, "zenith_rl.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.LogExperience("query", "cpu usage", "METRIC:avg(cpu_usage_pct)", "Success"); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := filepath.Join(dir, "snapshot.db")
+	if err := db.Snapshot(snapshot); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+
+	copyDB, err := sql.Open("sqlite", snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer copyDB.Close()
+
+	var count int
+	if err := copyDB.QueryRow(`SELECT COUNT(*) FROM experiences`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 experience in the snapshot, got %d", count)
+	}
+
+	if err := db.Snapshot(snapshot); err == nil {
+		t.Error("Expected snapshotting over an existing file to fail")
+	}
+}
+
+func TestDB_GetSuccessfulExamples(t *testing.T) {
+	db, err := InitDB(filepath.Join(t.TempDir(), "zenith_rl.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	db.LogExperienceFrom("query", OriginLLM, "cpu usage", "METRIC:avg(cpu_usage_pct)", "Success")
+	db.LogExperienceFrom("query", OriginLLM, "memory", "METRIC:avg(memory_used_mb)", "Success")
+	bad, _ := db.LogExperienceFrom("query", OriginLLM, "disk", "METRIC:disk_used_pct", "Success")
+	db.UpdateFeedback(bad, -1)
+	db.LogExperienceFrom("query", OriginLLM, "wifi errors", "LOG:processName:wifi", "Execution Error: bad query")
+	good, _ := db.LogExperienceFrom("query", OriginLLM, "chrome logs", "LOG:processName:chrome", "Success")
+	db.UpdateFeedback(good, 1)
+	db.LogExperienceFrom("query", OriginCache, "memory", "METRIC:sum(memory_used_mb)", "Success")
+	db.LogExperience("recommend", "Generate system recommendations", "", "Success")
+
+	// Queries the model didn't write never become examples, even rated good.
+	for _, origin := range []string{"confirmed", "canned", "fallback", ""} {
+		id, _ := db.LogExperienceFrom("query", origin, "network "+origin, "METRIC:vector(1)", "Success")
+		db.UpdateFeedback(id, 1)
+	}
+
+	examples, err := db.GetSuccessfulExamples("query", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range examples {
+		got = append(got, e.Prompt+" => "+e.GeneratedQuery)
+	}
+	want := []string{
+		"chrome logs => LOG:processName:chrome",
+		"memory => METRIC:sum(memory_used_mb)",
+		"cpu usage => METRIC:avg(cpu_usage_pct)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected examples\n got: %q\nwant: %q", got, want)
+	}
+
+	if examples, _ := db.GetSuccessfulExamples("query", 1); len(examples) != 1 || examples[0].Prompt != "chrome logs" {
+		t.Errorf("Expected only the best-rated example with limit 1, got %+v", examples)
+	}
+}
+
+func TestDB_ListExperiences(t *testing.T) {
+	db, err := InitDB(filepath.Join(t.TempDir(), "zenith_rl.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, prompt := range []string{"first", "second", "third"} {
+		db.LogExperience("query", prompt, "METRIC:up", "Success")
+	}
+	id, _ := db.LogExperience("recommend", "Generate system recommendations", "", "Success")
+	db.UpdateFeedback(id, 1)
+
+	page, err := db.ListExperiences(2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 2 || page[0].ID != id || page[0].UserFeedback != 1 || page[1].Prompt != "third" {
+		t.Errorf("Unexpected first page: %+v", page)
+	}
+	if page[0].Timestamp.IsZero() {
+		t.Error("Expected the timestamp to be read back")
+	}
+
+	page, err = db.ListExperiences(2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 2 || page[0].Prompt != "second" || page[1].Prompt != "first" {
+		t.Errorf("Unexpected second page: %+v", page)
+	}
+
+	if page, _ := db.ListExperiences(2, 10); page == nil || len(page) != 0 {
+		t.Errorf("Expected an empty page past the end, got %#v", page)
+	}
+}
+
+func TestDB_Prune(t *testing.T) {
+	db, err := InitDB(filepath.Join(t.TempDir(), "zenith_rl.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, prompt := range []string{"old", "old rated", "old rated bad"} {
+		db.LogExperience("query", prompt, "METRIC:up", "Success")
+	}
+	db.UpdateFeedback(2, 1)
+	db.UpdateFeedback(3, -1)
+	if _, err := db.sqlDB.Exec(`UPDATE experiences SET timestamp = datetime('now', '-100 days')`); err != nil {
+		t.Fatal(err)
+	}
+	for _, prompt := range []string{"new 1", "new 2", "new 3"} {
+		db.LogExperience("query", prompt, "METRIC:up", "Success")
+	}
+
+	n, err := db.PruneOlderThan(90 * 24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("Expected 1 old unrated experience pruned, got %d", n)
+	}
+
+	n, err = db.PruneToMaxRows(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("Expected 1 experience beyond the newest 2 pruned, got %d", n)
+	}
+
+	left, _ := db.ListExperiences(10, 0)
+	var prompts []string
+	for _, e := range left {
+		prompts = append(prompts, e.Prompt)
+	}
+	if got := strings.Join(prompts, ","); got != "new 3,new 2,old rated bad,old rated" {
+		t.Errorf("Unexpected experiences after pruning: %s", got)
+	}
+}
+
+func TestDB_Stats(t *testing.T) {
+	db, err := InitDB(filepath.Join(t.TempDir(), "zenith_rl.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	empty, err := db.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if empty.Total != 0 || len(empty.BySource) != 0 {
+		t.Errorf("Expected no stats for an empty database, got %+v", empty)
+	}
+
+	good, _ := db.LogExperience("query", "cpu", "METRIC:avg(cpu_usage_pct)", "Success")
+	db.UpdateFeedback(good, 1)
+	bad, _ := db.LogExperience("query", "disk", "METRIC:disk", "Success")
+	db.UpdateFeedback(bad, -1)
+	db.LogExperience("query", "wifi", "LOG:wifi", "Final Execution Error: bad query")
+	db.LogExperience("recommend", "Generate system recommendations", "", "Success")
+
+	stats, err := db.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := SourceStats{Total: 4, Successes: 3, Failures: 1, GoodFeedback: 1, BadFeedback: 1}
+	if stats.SourceStats != want {
+		t.Errorf("Unexpected totals: %+v", stats.SourceStats)
+	}
+	if q := stats.BySource["query"]; q != (SourceStats{Total: 3, Successes: 2, Failures: 1, GoodFeedback: 1, BadFeedback: 1}) {
+		t.Errorf("Unexpected query stats: %+v", q)
+	}
+	if r := stats.BySource["recommend"]; r != (SourceStats{Total: 1, Successes: 1}) {
+		t.Errorf("Unexpected recommend stats: %+v", r)
+	}
+}
+
+func TestDB_AutoFeedback(t *testing.T) {
+	db, err := InitDB(filepath.Join(t.TempDir(), "zenith_rl.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	unrated, _ := db.LogExperienceFrom("query", OriginLLM, "memory", "METRIC:avg(memory_used_mb)", "Success")
+	auto, _ := db.LogExperienceFrom("query", OriginLLM, "cpu usage", "METRIC:avg(cpu_usage_pct)", "Success")
+	db.SetAutoFeedback(auto, FeedbackAutoGood)
+	retried, _ := db.LogExperienceFrom("query", OriginLLM, "disk", "METRIC:disk_used_pct", "Success")
+	db.SetAutoFeedback(retried, FeedbackAutoBad)
+	good, _ := db.LogExperienceFrom("query", OriginLLM, "chrome logs", "LOG:processName:chrome", "Success")
+	db.UpdateFeedback(good, 1)
+	db.SetAutoFeedback(good, FeedbackAutoBad)
+
+	examples, err := db.GetSuccessfulExamples("query", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range examples {
+		got = append(got, e.Prompt)
+	}
+	if strings.Join(got, ",") != "chrome logs,cpu usage,memory" {
+		t.Errorf("Expected user-rated, then auto-rated, then unrated examples without the auto-bad one, got %v", got)
+	}
+
+	stats, _ := db.Stats()
+	if stats.GoodFeedback != 1 || stats.AutoGood != 1 || stats.AutoBad != 1 || stats.BadFeedback != 0 {
+		t.Errorf("Unexpected feedback counts: %+v", stats.SourceStats)
+	}
+
+	// Users can only rate 1 or -1; the automatic values are the server's.
+	for _, v := range []int{FeedbackAutoGood, FeedbackAutoBad, 0, 5} {
+		if err := db.UpdateFeedback(unrated, v); err == nil {
+			t.Errorf("Expected UpdateFeedback to reject %d", v)
+		}
+	}
+
+	if err := db.UpdateFeedback(9999, 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown ID, got %v", err)
+	}
+
+	// A user's rating replaces the automatic one.
+	if err := db.UpdateFeedback(retried, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetAutoFeedback(unrated, FeedbackAutoGood); err != nil {
+		t.Fatal(err)
+	}
+	page, _ := db.ListExperiences(10, 0)
+	feedback := make(map[string]int)
+	for _, e := range page {
+		feedback[e.Prompt] = e.UserFeedback
+	}
+	if feedback["disk"] != 1 || feedback["chrome logs"] != 1 || feedback["memory"] != FeedbackAutoGood {
+		t.Errorf("Unexpected feedback after override: %v", feedback)
+	}
+}
+
+func TestInitDB_AddsOriginColumn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "zenith_rl.db")
+	old, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = old.Exec(`
+	CREATE TABLE experiences (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+		source TEXT NOT NULL,
+		prompt TEXT NOT NULL,
+		generated_query TEXT,
+		execution_result TEXT,
+		user_feedback INTEGER DEFAULT 0
+	);
+	INSERT INTO experiences (source, prompt, generated_query, execution_result, user_feedback)
+	VALUES ('query', 'cpu usage', 'METRIC:vector(1)', 'Success', 1)`)
+	old.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := InitDB(path)
+	if err != nil {
+		t.Fatalf("Failed to open a database without the origin column: %v", err)
+	}
+	defer db.Close()
+	if examples, _ := db.GetSuccessfulExamples("query", 10); len(examples) != 0 {
+		t.Errorf("Expected rows of unknown origin not to be used as examples, got %+v", examples)
+	}
+	db.LogExperienceFrom("query", OriginLLM, "memory", "METRIC:avg(memory_used_mb)", "Success")
+	if page, _ := db.ListExperiences(10, 0); len(page) != 2 || page[0].Origin != OriginLLM || page[1].Origin != "" {
+		t.Errorf("Unexpected experiences after migration: %+v", page)
+	}
+}
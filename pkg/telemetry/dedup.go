@@ -0,0 +1,79 @@
+package telemetry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Deduper logs a message the first time it is seen and then suppresses
+// identical messages for Window. The next occurrence after the window is
+// logged with a "(repeated N times)" count of what was suppressed, so a
+// chronically failing collector reports once an hour instead of every cycle.
+type Deduper struct {
+	window time.Duration
+	output func(string)
+	now    func() time.Time
+
+	mu   sync.Mutex
+	seen map[string]*dedupEntry
+}
+
+type dedupEntry struct {
+	since   time.Time
+	repeats int
+}
+
+// NewDeduper returns a Deduper that writes through output, e.g. log.Print.
+func NewDeduper(window time.Duration, output func(string)) *Deduper {
+	return &Deduper{
+		window: window,
+		output: output,
+		now:    time.Now,
+		seen:   make(map[string]*dedupEntry),
+	}
+}
+
+// Printf formats a message and logs it unless it is a repeat within the window.
+func (d *Deduper) Printf(format string, args ...interface{}) {
+	msg := strings.TrimRight(fmt.Sprintf(format, args...), "\n")
+
+	d.mu.Lock()
+	now := d.now()
+	e, ok := d.seen[msg]
+	if ok && now.Sub(e.since) < d.window {
+		e.repeats++
+		d.mu.Unlock()
+		return
+	}
+
+	// Forget messages that have gone quiet so the map doesn't grow forever,
+	// reporting any repeats that were suppressed for them.
+	var summaries []string
+	for k, old := range d.seen {
+		if k != msg && now.Sub(old.since) >= d.window {
+			if old.repeats > 0 {
+				summaries = append(summaries, fmt.Sprintf("%s (repeated %d times)", k, old.repeats))
+			}
+			delete(d.seen, k)
+		}
+	}
+
+	repeats := 0
+	if ok {
+		repeats = e.repeats
+	}
+	d.seen[msg] = &dedupEntry{since: now}
+	d.mu.Unlock()
+
+	sort.Strings(summaries)
+	for _, s := range summaries {
+		d.output(s)
+	}
+	if repeats > 0 {
+		msg = fmt.Sprintf("%s (repeated %d times)", msg, repeats)
+	}
+	d.output(msg)
+}
@@ -0,0 +1,38 @@
+package telemetry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeduper_SuppressesRepeats(t *testing.T) {
+	var got []string
+	d := NewDeduper(time.Hour, func(s string) { got = append(got, s) })
+	now := time.Now()
+	d.now = func() time.Time { return now }
+
+	for i := 0; i < 4; i++ {
+		d.Printf("failed to collect logs: %v\n", "exec: not found")
+	}
+	d.Printf("failed to collect CPU metrics")
+	d.Printf("failed to collect CPU metrics")
+
+	now = now.Add(2 * time.Hour)
+	d.Printf("failed to collect logs: %v\n", "exec: not found")
+
+	want := []string{
+		"failed to collect logs: exec: not found",
+		"failed to collect CPU metrics",
+		// The quiet CPU message is summarized when it is forgotten.
+		"failed to collect CPU metrics (repeated 1 times)",
+		"failed to collect logs: exec: not found (repeated 3 times)",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %q, got %q", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Line %d: expected %q, got %q", i, want[i], got[i])
+		}
+	}
+}
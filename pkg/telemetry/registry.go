@@ -0,0 +1,226 @@
+package telemetry
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Registry holds Zenith's self-observability metrics and renders them in the
+// Prometheus text exposition format for the server's /metrics endpoint.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+// Default is the process-wide registry used by the server.
+var Default = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+type family struct {
+	name    string
+	help    string
+	kind    string // "counter", "gauge", or "histogram"
+	values  map[string]float64
+	fn      func() float64
+	buckets []float64
+	hists   map[string]*histogram
+}
+
+type histogram struct {
+	counts []float64 // per bucket, non-cumulative
+	sum    float64
+	count  float64
+}
+
+// Counter is a monotonically increasing metric, optionally split by labels.
+type Counter struct {
+	r *Registry
+	f *family
+}
+
+// Gauge is a metric that can go up and down, optionally split by labels.
+type Gauge struct {
+	r *Registry
+	f *family
+}
+
+func (r *Registry) family(name, help, kind string) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if f, ok := r.families[name]; ok {
+		return f
+	}
+	f := &family{name: name, help: help, kind: kind, values: make(map[string]float64)}
+	r.families[name] = f
+	return f
+}
+
+// Histogram is a metric that counts observations into fixed buckets,
+// optionally split by labels.
+type Histogram struct {
+	r *Registry
+	f *family
+}
+
+// Counter returns the counter registered under name, creating it if needed.
+func (r *Registry) Counter(name, help string) *Counter {
+	return &Counter{r: r, f: r.family(name, help, "counter")}
+}
+
+// Gauge returns the gauge registered under name, creating it if needed.
+func (r *Registry) Gauge(name, help string) *Gauge {
+	return &Gauge{r: r, f: r.family(name, help, "gauge")}
+}
+
+// Histogram returns the histogram registered under name, creating it with
+// the given upper bucket bounds (sorted ascending) if needed.
+func (r *Registry) Histogram(name, help string, buckets []float64) *Histogram {
+	f := r.family(name, help, "histogram")
+	r.mu.Lock()
+	if f.hists == nil {
+		f.buckets = append([]float64(nil), buckets...)
+		sort.Float64s(f.buckets)
+		f.hists = make(map[string]*histogram)
+	}
+	r.mu.Unlock()
+	return &Histogram{r: r, f: f}
+}
+
+// GaugeFunc registers a gauge whose value is computed by fn at scrape time.
+func (r *Registry) GaugeFunc(name, help string, fn func() float64) {
+	f := r.family(name, help, "gauge")
+	r.mu.Lock()
+	f.fn = fn
+	r.mu.Unlock()
+}
+
+// Add increments the counter series identified by labels by delta.
+func (c *Counter) Add(labels map[string]string, delta float64) {
+	c.r.mu.Lock()
+	c.f.values[labelKey(labels)] += delta
+	c.r.mu.Unlock()
+}
+
+// Inc increments the counter series identified by labels by one.
+func (c *Counter) Inc(labels map[string]string) {
+	c.Add(labels, 1)
+}
+
+// Set replaces the gauge series identified by labels with v.
+func (g *Gauge) Set(labels map[string]string, v float64) {
+	g.r.mu.Lock()
+	g.f.values[labelKey(labels)] = v
+	g.r.mu.Unlock()
+}
+
+// Observe records v in the histogram series identified by labels.
+func (h *Histogram) Observe(labels map[string]string, v float64) {
+	h.r.mu.Lock()
+	defer h.r.mu.Unlock()
+	key := labelKey(labels)
+	hd, ok := h.f.hists[key]
+	if !ok {
+		hd = &histogram{counts: make([]float64, len(h.f.buckets))}
+		h.f.hists[key] = hd
+	}
+	for i, le := range h.f.buckets {
+		if v <= le {
+			hd.counts[i]++
+			break
+		}
+	}
+	hd.sum += v
+	hd.count++
+}
+
+// WritePrometheus renders every registered metric in text exposition format.
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		f := r.families[name]
+		fmt.Fprintf(&b, "# HELP %s %s\n", f.name, f.help)
+		fmt.Fprintf(&b, "# TYPE %s %s\n", f.name, f.kind)
+		if f.fn != nil {
+			fmt.Fprintf(&b, "%s %g\n", f.name, f.fn())
+			continue
+		}
+		if f.hists != nil {
+			writeHistogram(&b, f)
+			continue
+		}
+		keys := make([]string, 0, len(f.values))
+		for k := range f.values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(&b, "%s%s %g\n", f.name, k, f.values[k])
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeHistogram(b *strings.Builder, f *family) {
+	keys := make([]string, 0, len(f.hists))
+	for k := range f.hists {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		hd := f.hists[k]
+		var cumulative float64
+		for i, le := range f.buckets {
+			cumulative += hd.counts[i]
+			fmt.Fprintf(b, "%s_bucket%s %g\n", f.name, withLabel(k, "le", fmt.Sprintf("%g", le)), cumulative)
+		}
+		fmt.Fprintf(b, "%s_bucket%s %g\n", f.name, withLabel(k, "le", "+Inf"), hd.count)
+		fmt.Fprintf(b, "%s_sum%s %g\n", f.name, k, hd.sum)
+		fmt.Fprintf(b, "%s_count%s %g\n", f.name, k, hd.count)
+	}
+}
+
+// withLabel appends name="value" to a rendered label key.
+func withLabel(key, name, value string) string {
+	pair := fmt.Sprintf(`%s="%s"`, name, value)
+	if key == "" {
+		return "{" + pair + "}"
+	}
+	return key[:len(key)-1] + "," + pair + "}"
+}
+
+// labelKey renders labels as a sorted `{k="v",...}` suffix so that the same
+// label set always maps to the same series.
+func labelKey(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, k, escaper.Replace(labels[k])))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
@@ -0,0 +1,33 @@
+package telemetry
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistry_Histogram(t *testing.T) {
+	r := NewRegistry()
+	h := r.Histogram("zenith_query_attempts", "Attempts per query.", []float64{1, 2, 3})
+	h.Observe(nil, 1)
+	h.Observe(nil, 1)
+	h.Observe(nil, 3)
+
+	var b strings.Builder
+	if err := r.WritePrometheus(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		"# TYPE zenith_query_attempts histogram",
+		`zenith_query_attempts_bucket{le="1"} 2`,
+		`zenith_query_attempts_bucket{le="2"} 2`,
+		`zenith_query_attempts_bucket{le="3"} 3`,
+		`zenith_query_attempts_bucket{le="+Inf"} 3`,
+		"zenith_query_attempts_sum 5",
+		"zenith_query_attempts_count 3",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}